@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// repoAllowed reports whether owner/repo may be queried under REPO_ALLOWLIST.
+// Every repository is allowed when no allowlist is configured.
+func repoAllowed(owner, repo string) bool {
+	if len(config.RepoAllowlist) == 0 {
+		return true
+	}
+
+	owner, repo = strings.ToLower(owner), strings.ToLower(repo)
+	for _, entry := range config.RepoAllowlist {
+		allowedOwner, allowedRepo, _ := strings.Cut(entry, "/")
+		if allowedOwner == owner && (allowedRepo == "*" || allowedRepo == repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// notAllowedMessage is the error reported for repositories outside REPO_ALLOWLIST
+func notAllowedMessage(owner, repo string) string {
+	return fmt.Sprintf("Repository '%s/%s' is not allowed on this service", owner, repo)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseRepoAllowlist(t *testing.T) {
+	allowlist, err := parseRepoAllowlist("MyOrg/*, partner/API")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if expected := []string{"myorg/*", "partner/api"}; !reflect.DeepEqual(allowlist, expected) {
+		t.Errorf("Expected %v, got %v", expected, allowlist)
+	}
+
+	for _, value := range []string{"myorg", "/repo", "myorg/", "a/b/c"} {
+		if _, err := parseRepoAllowlist(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestStatusHandler_RepoAllowlist(t *testing.T) {
+	originalConfig := config
+	config.RepoAllowlist = []string{"myorg/*", "partner/api"}
+	defer func() { config = originalConfig }()
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	tests := []struct {
+		name         string
+		owner        string
+		repo         string
+		expectedCode int
+	}{
+		{"allowed", "partner", "api", http.StatusOK},
+		{"allowed case-insensitively", "Partner", "API", http.StatusOK},
+		{"wildcard allowed", "myorg", "anything", http.StatusOK},
+		{"denied repo", "partner", "web", http.StatusForbidden},
+		{"denied owner", "other", "api", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status?owner="+tt.owner+"&repo="+tt.repo+"&branch=main", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if (tt.expectedCode == http.StatusForbidden) != (response.Error != "") {
+				t.Errorf("Expected an error only for denied repos, got %q", response.Error)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_NoAllowlistAllowsAll(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=anyone&repo=anything&branch=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 without an allowlist, got %d", rr.Code)
+	}
+}
+
+func TestResolveBatchEntry_RepoAllowlist(t *testing.T) {
+	originalConfig := config
+	config.RepoAllowlist = []string{"myorg/*"}
+	defer func() { config = originalConfig }()
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		t.Errorf("Expected no upstream call for a denied repo, got %s", req.URL.Path)
+		return createHTTPResponse(500, `{}`), nil
+	})
+
+	response, err := resolveBatchEntry(httptest.NewRequest("GET", "/", nil).Context(), BatchEntry{Owner: "other", Repo: "api", Branch: "main"})
+	if err == nil || response.Error != notAllowedMessage("other", "api") {
+		t.Errorf("Expected denied entry to fail with the allowlist error, got %+v", response)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// anonymousHeader marks responses built from data Gitea served without authentication
+const anonymousHeader = "X-Gitea-Anonymous"
+
+type anonymousRecorderKey struct{}
+
+// markAnonymous notes on the request's context that an upstream call fell back to anonymous access
+func markAnonymous(ctx context.Context) {
+	if recorder, ok := ctx.Value(anonymousRecorderKey{}).(*atomic.Bool); ok {
+		recorder.Store(true)
+	}
+}
+
+// getJSONAnonymously repeats a request rejected with 401 without the token
+// when ALLOW_ANONYMOUS_FALLBACK is set, so public repositories are still
+// served while the token is misconfigured. It returns the original error
+// when the fallback is off or fails too.
+func (g *GiteaService) getJSONAnonymously(endpoint, operation string, v any, err error) error {
+	if !config.AllowAnonymousFallback || !isUpstreamStatus(err, http.StatusUnauthorized) {
+		return err
+	}
+
+	anonymous := *g
+	anonymous.Token = ""
+	if anonErr := anonymous.retry(func(ctx context.Context) error {
+		return anonymous.withContext(ctx).getJSONWithFailover(endpoint, operation, v)
+	}); anonErr != nil {
+		return err
+	}
+
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	markAnonymous(ctx)
+	return nil
+}
+
+// anonymousWriter adds the anonymous header just before the response headers are sent
+type anonymousWriter struct {
+	http.ResponseWriter
+	recorder    *atomic.Bool
+	wroteHeader bool
+}
+
+func (a *anonymousWriter) WriteHeader(code int) {
+	if !a.wroteHeader {
+		a.wroteHeader = true
+		if a.recorder.Load() {
+			a.Header().Set(anonymousHeader, "true")
+		}
+	}
+	a.ResponseWriter.WriteHeader(code)
+}
+
+func (a *anonymousWriter) Write(b []byte) (int, error) {
+	if !a.wroteHeader {
+		a.WriteHeader(http.StatusOK)
+	}
+	return a.ResponseWriter.Write(b)
+}
+
+// Flush passes through to the underlying writer so streaming endpoints keep working
+func (a *anonymousWriter) Flush() {
+	if flusher, ok := a.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (a *anonymousWriter) Unwrap() http.ResponseWriter {
+	return a.ResponseWriter
+}
+
+// reportAnonymous sets the X-Gitea-Anonymous header on responses whose
+// upstream calls fell back to anonymous access
+func reportAnonymous(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &atomic.Bool{}
+		ctx := context.WithValue(r.Context(), anonymousRecorderKey{}, recorder)
+		next.ServeHTTP(&anonymousWriter{ResponseWriter: w, recorder: recorder}, r.WithContext(ctx))
+	})
+}
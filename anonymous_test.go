@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusHandler_AnonymousFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		allow         bool
+		publicRepo    bool
+		expectedCode  int
+		expectedState string
+		anonymous     bool
+	}{
+		{"fallback serves public repo", true, true, http.StatusOK, "success", true},
+		{"fallback fails for private repo", true, false, http.StatusInternalServerError, "", false},
+		{"fallback disabled", false, true, http.StatusInternalServerError, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.AllowAnonymousFallback = tt.allow
+			defer func() { config = originalConfig }()
+
+			var anonymousCalls int
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				if req.Header.Get("Authorization") != "" {
+					return createHTTPResponse(401, `{"message": "token is invalid"}`), nil
+				}
+				anonymousCalls++
+				if !tt.publicRepo {
+					return createHTTPResponse(404, `{"message": "not found"}`), nil
+				}
+				return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branches=main", nil)
+			rr := httptest.NewRecorder()
+			reportAnonymous(http.HandlerFunc(statusHandler)).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.State != tt.expectedState {
+				t.Errorf("Expected state %q, got %q", tt.expectedState, response.State)
+			}
+			if anonymous := rr.Header().Get(anonymousHeader) == "true"; anonymous != tt.anonymous {
+				t.Errorf("Expected %s header set=%v, got %q", anonymousHeader, tt.anonymous, rr.Header().Get(anonymousHeader))
+			}
+			if !tt.allow && anonymousCalls != 0 {
+				t.Errorf("Expected no anonymous request with the fallback disabled, got %d", anonymousCalls)
+			}
+		})
+	}
+}
+
+func TestNewRequest_OmitsAuthorizationWithoutToken(t *testing.T) {
+	g := &GiteaService{BaseURL: "https://git.example.com"}
+	req, err := g.newRequest("https://git.example.com/api/v1/version")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		t.Errorf("Expected no Authorization header, got %q", auth)
+	}
+}
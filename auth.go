@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requestAPIKey extracts the API key a client presented, from either the
+// X-API-Key header or a bearer Authorization header
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if key, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return key
+	}
+	return ""
+}
+
+// requireAPIKey checks the request against the configured API key, writing
+// a 401 response and returning false when it does not match. Requests are
+// allowed through when no API key is configured.
+func requireAPIKey(w http.ResponseWriter, r *http.Request) bool {
+	if config.APIKey == "" {
+		return true
+	}
+
+	if subtle.ConstantTimeCompare([]byte(requestAPIKey(r)), []byte(config.APIKey)) == 1 {
+		return true
+	}
+
+	w.Header().Set("WWW-Authenticate", `Bearer realm="gitea-check-service"`)
+	writeError(w, http.StatusUnauthorized, "A valid API key is required")
+	return false
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		headers    map[string]string
+		expected   bool
+	}{
+		{"no key configured", "", nil, true},
+		{"missing key", "secret", nil, false},
+		{"wrong key", "secret", map[string]string{"X-API-Key": "nope"}, false},
+		{"X-API-Key header", "secret", map[string]string{"X-API-Key": "secret"}, true},
+		{"bearer token", "secret", map[string]string{"Authorization": "Bearer secret"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.APIKey = tt.configured
+			defer func() { config = originalConfig }()
+
+			req := httptest.NewRequest("GET", "/config", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rr := httptest.NewRecorder()
+
+			if result := requireAPIKey(rr, req); result != tt.expected {
+				t.Errorf("requireAPIKey() = %v, want %v", result, tt.expected)
+			}
+			if !tt.expected && rr.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %v", rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+)
+
+// badgeColors maps the named state colors to the hex values used in badges
+var badgeColors = map[string]string{
+	"green":  "#4c1",
+	"red":    "#e05d44",
+	"yellow": "#dfb317",
+	"orange": "#fe7d37",
+	"grey":   "#9f9f9f",
+}
+
+// badgeCharWidth approximates the width in pixels of one 11px Verdana character
+const badgeCharWidth = 7
+
+// badgeTemplate is a flat two-part badge in the style of shields.io
+const badgeTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<rect width="%[2]d" height="20" fill="#555"/>
+<rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="14">%[4]s</text>
+<text x="%[8]d" y="14">%[5]s</text>
+</g>
+</svg>
+`
+
+// badgeColor resolves the fill for state, passing custom colors through as given
+func badgeColor(state string) string {
+	color := mapStateToColor(state)
+	if hex, ok := badgeColors[color]; ok {
+		return hex
+	}
+	return color
+}
+
+// renderBadge draws a badge with label on the left and message on the right
+func renderBadge(label, message, state string) string {
+	labelWidth := len([]rune(label))*badgeCharWidth + 10
+	messageWidth := len([]rune(message))*badgeCharWidth + 10
+	return fmt.Sprintf(badgeTemplate,
+		labelWidth+messageWidth, labelWidth, messageWidth,
+		html.EscapeString(label), html.EscapeString(message), badgeColor(state),
+		labelWidth/2, labelWidth+messageWidth/2)
+}
+
+// badgeEntries builds the repositories for a badge from repeated repo
+// parameters, each either a name under owner or a full owner/repo
+func badgeEntries(owner string, repos []string) []BatchEntry {
+	entries := make([]BatchEntry, 0, len(repos))
+	for _, repo := range repos {
+		entry := BatchEntry{Owner: owner, Repo: repo}
+		if repoOwner, name, found := strings.Cut(repo, "/"); found {
+			entry = BatchEntry{Owner: repoOwner, Repo: name}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// badgeMessage summarizes the results: the state for a single repository,
+// otherwise how many of them are passing
+func badgeMessage(results []BuildStatusResponse, state string) string {
+	if len(results) == 1 {
+		return state
+	}
+
+	passing := 0
+	for _, result := range results {
+		if result.Error == "" && result.State == "success" {
+			passing++
+		}
+	}
+	return fmt.Sprintf("%d/%d passing", passing, len(results))
+}
+
+// badgeHandler handles the /badge.svg endpoint, rendering the worst state
+// across one or more repositories
+func badgeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	owner := r.URL.Query().Get("owner")
+	if owner == "" {
+		owner = config.DefaultOwner
+	}
+	repos := r.URL.Query()["repo"]
+	if len(repos) == 0 {
+		writeError(w, http.StatusBadRequest, "At least one 'repo' query parameter is required")
+		return
+	}
+	if len(repos) > config.MaxBatchSize {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("At most %d repositories are allowed per badge", config.MaxBatchSize))
+		return
+	}
+
+	label := r.URL.Query().Get("label")
+	if label == "" {
+		label = "build"
+	}
+	if msg := validateParamLengths(label); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	batch := resolveBatch(r.Context(), badgeEntries(owner, repos))
+	states := make([]string, len(batch.Results))
+	for i, result := range batch.Results {
+		states[i] = result.State
+		if result.Error != "" {
+			states[i] = "error"
+		}
+	}
+	state := worstState(states...)
+
+	// Badges are embedded in pages that would otherwise cache a stale state
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, renderBadge(label, badgeMessage(batch.Results, state), state))
+}
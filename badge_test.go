@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBadgeHandler_WorstStateAcrossRepos(t *testing.T) {
+	states := map[string]string{"api": "success", "web": "failure", "sdk": "success", "docs": "pending"}
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		for repo, state := range states {
+			if strings.Contains(req.URL.Path, "/repos/myorg/"+repo+"/") {
+				return createHTTPResponse(200, `{"state": "`+state+`", "statuses": [], "total_count": 1}`), nil
+			}
+		}
+		return createHTTPResponse(404, `{"message": "not found"}`), nil
+	})
+
+	tests := []struct {
+		name            string
+		query           string
+		expectedMessage string
+		expectedColor   string
+	}{
+		{"single repo", "owner=myorg&repo=api", ">success<", "#4c1"},
+		{"all passing", "owner=myorg&repo=api&repo=sdk", ">2/2 passing<", "#4c1"},
+		{"worst is failure", "owner=myorg&repo=api&repo=web&repo=docs&repo=sdk", ">2/4 passing<", "#e05d44"},
+		{"worst is pending", "repo=myorg/api&repo=myorg/docs", ">1/2 passing<", "#dfb317"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/badge.svg?"+tt.query+"&label=ci", nil)
+			rr := httptest.NewRecorder()
+			badgeHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "image/svg+xml" {
+				t.Errorf("Expected SVG content type, got %q", ct)
+			}
+			body := rr.Body.String()
+			if !strings.Contains(body, tt.expectedMessage) {
+				t.Errorf("Expected message %q in badge, got %s", tt.expectedMessage, body)
+			}
+			if !strings.Contains(body, `fill="`+tt.expectedColor+`"`) {
+				t.Errorf("Expected color %s in badge, got %s", tt.expectedColor, body)
+			}
+			if !strings.Contains(body, ">ci<") {
+				t.Errorf("Expected label in badge, got %s", body)
+			}
+		})
+	}
+}
+
+func TestBadgeHandler_UnresolvableRepoCountsAsError(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/repos/myorg/api") {
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		if strings.Contains(req.URL.Path, "/repos/myorg/api/") {
+			return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+		}
+		return createHTTPResponse(500, `{"message": "boom"}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/badge.svg?owner=myorg&repo=api&repo=broken", nil)
+	rr := httptest.NewRecorder()
+	badgeHandler(rr, req)
+
+	body := rr.Body.String()
+	if !strings.Contains(body, ">1/2 passing<") || !strings.Contains(body, `fill="#e05d44"`) {
+		t.Errorf("Expected a red 1/2 passing badge, got %s", body)
+	}
+}
+
+func TestBadgeHandler_RequiresRepo(t *testing.T) {
+	req := httptest.NewRequest("GET", "/badge.svg?owner=myorg", nil)
+	rr := httptest.NewRecorder()
+	badgeHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+}
+
+func TestRenderBadge_EscapesText(t *testing.T) {
+	badge := renderBadge("<ci>", "a&b", "success")
+	if strings.Contains(badge, "<ci>") || !strings.Contains(badge, "&lt;ci&gt;") || !strings.Contains(badge, "a&amp;b") {
+		t.Errorf("Expected label and message to be escaped, got %s", badge)
+	}
+}
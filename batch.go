@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// BatchEntry identifies a single repository in a batch request
+type BatchEntry struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Branch string `json:"branch,omitempty"`
+}
+
+// BatchRequest represents the body accepted by the /status/batch endpoint
+type BatchRequest struct {
+	Repos []BatchEntry `json:"repos"`
+}
+
+// BatchResponse represents the per-entry results of a batch request
+type BatchResponse struct {
+	Results   []BuildStatusResponse `json:"results"`
+	Succeeded int                   `json:"succeeded"`
+	Failed    int                   `json:"failed"`
+	Error     string                `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the batch results using the configured field naming style
+func (b BatchResponse) MarshalJSON() ([]byte, error) {
+	type plain BatchResponse
+	return marshalWithNaming(plain(b))
+}
+
+// resolveBatchEntry fetches the build status of a single batch entry,
+// returning the error it failed with, if any
+func resolveBatchEntry(ctx context.Context, entry BatchEntry) (BuildStatusResponse, error) {
+	if entry.Owner == "" {
+		entry.Owner = config.DefaultOwner
+	}
+	if entry.Owner == "" || entry.Repo == "" {
+		msg := "Both 'owner' and 'repo' are required"
+		return BuildStatusResponse{
+			Owner:      entry.Owner,
+			Repository: entry.Repo,
+			Error:      msg,
+			Retryable:  retryableFlag(nil),
+		}, errors.New(msg)
+	}
+
+	if msg := validateParamLengths(entry.Owner, entry.Repo, entry.Branch); msg != "" {
+		return BuildStatusResponse{
+			Owner:      entry.Owner,
+			Repository: entry.Repo,
+			Error:      msg,
+			Retryable:  retryableFlag(nil),
+		}, errors.New(msg)
+	}
+
+	if !repoAllowed(entry.Owner, entry.Repo) {
+		msg := notAllowedMessage(entry.Owner, entry.Repo)
+		return BuildStatusResponse{
+			Owner:      entry.Owner,
+			Repository: entry.Repo,
+			Error:      msg,
+			Retryable:  retryableFlag(nil),
+		}, errors.New(msg)
+	}
+
+	release, err := lookups.acquire(ctx, repoKey(entry.Owner, entry.Repo), config.MaxConcurrentLookups)
+	if err != nil {
+		return BuildStatusResponse{
+			Owner:      entry.Owner,
+			Repository: entry.Repo,
+			Error:      fmt.Sprintf("Gave up waiting for a lookup slot: %v", err),
+			Retryable:  retryableFlag(err),
+		}, err
+	}
+	defer release()
+
+	response, err := resolveBuildStatus(ctx, entry.Owner, entry.Repo, entry.Branch, StatusOptions{})
+	if err != nil {
+		response.Error = withPermissionHint(quietError(response.Error, err), err)
+		response.Retryable = retryableFlag(err)
+	}
+	return response, err
+}
+
+// forEachBatchEntry resolves every entry with at most concurrency lookups in
+// flight, calling done with each result and the error it failed with as it
+// completes. Calls to done are serialized.
+func forEachBatchEntry(ctx context.Context, entries []BatchEntry, concurrency int, done func(i int, response BuildStatusResponse, err error)) {
+	sem := make(chan struct{}, concurrency)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		wg.Add(1)
+		go func(i int, entry BatchEntry) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			response, err := resolveBatchEntry(ctx, entry)
+			mu.Lock()
+			defer mu.Unlock()
+			done(i, response, err)
+		}(i, entry)
+	}
+	wg.Wait()
+}
+
+// resolveBatch fetches the build status of every entry concurrently,
+// returning the results in request order
+func resolveBatch(ctx context.Context, entries []BatchEntry) BatchResponse {
+	batch := BatchResponse{Results: make([]BuildStatusResponse, len(entries))}
+	forEachBatchEntry(ctx, entries, config.BatchConcurrency, func(i int, response BuildStatusResponse, err error) {
+		batch.Results[i] = response
+		if err != nil {
+			batch.Failed++
+		} else {
+			batch.Succeeded++
+		}
+	})
+	return batch
+}
+
+// streamBatch writes each entry's result as a line of newline-delimited JSON
+// as soon as it completes, so results arrive in completion order
+func streamBatch(w http.ResponseWriter, r *http.Request, entries []BatchEntry) {
+	flusher, _ := w.(http.Flusher)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	forEachBatchEntry(r.Context(), entries, config.BatchConcurrency, func(i int, response BuildStatusResponse, _ error) {
+		if err := encoder.Encode(response); err != nil {
+			log.Printf("Error encoding batch result: %v", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+}
+
+// batchHTTPCode picks the overall status code for a batch: 200 when every
+// entry succeeded, 500 when every entry failed and 207 for a mix of both
+func batchHTTPCode(batch BatchResponse) int {
+	switch {
+	case batch.Failed == 0:
+		return http.StatusOK
+	case batch.Succeeded == 0:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusMultiStatus
+	}
+}
+
+// batchStatusHandler handles the /status/batch endpoint
+func batchStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var request BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, BatchResponse{
+			Error: fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+
+	logBody("request", r, request)
+
+	if len(request.Repos) == 0 {
+		writeJSON(w, http.StatusBadRequest, BatchResponse{
+			Error: "At least one entry in 'repos' is required",
+		})
+		return
+	}
+
+	if len(request.Repos) > config.MaxBatchSize {
+		writeJSON(w, http.StatusBadRequest, BatchResponse{
+			Error: fmt.Sprintf("At most %d entries are allowed per batch", config.MaxBatchSize),
+		})
+		return
+	}
+
+	if r.URL.Query().Get("format") == formatNDJSON {
+		streamBatch(w, r, request.Repos)
+		return
+	}
+
+	batch := resolveBatch(r.Context(), request.Repos)
+	logBody("response", r, batch)
+	writeJSON(w, batchHTTPCode(batch), batch)
+}
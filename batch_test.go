@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// batchMock serves a successful status for every repo except those listed as failing
+func batchMock(failing ...string) func(req *http.Request) (*http.Response, error) {
+	return func(req *http.Request) (*http.Response, error) {
+		for _, repo := range failing {
+			if strings.Contains(req.URL.Path, "/repos/testowner/"+repo) {
+				return createHTTPResponse(500, `{"message": "Internal Server Error"}`), nil
+			}
+		}
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	}
+}
+
+func TestBatchStatusHandler(t *testing.T) {
+	body := `{"repos": [{"owner": "testowner", "repo": "one"}, {"owner": "testowner", "repo": "two"}]}`
+
+	tests := []struct {
+		name              string
+		failing           []string
+		expectedStatus    int
+		expectedSucceeded int
+		expectedFailed    int
+	}{
+		{"all success", nil, http.StatusOK, 2, 0},
+		{"all fail", []string{"one", "two"}, http.StatusInternalServerError, 0, 2},
+		{"mixed", []string{"two"}, http.StatusMultiStatus, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, batchMock(tt.failing...))
+
+			req := httptest.NewRequest("POST", "/status/batch", strings.NewReader(body))
+			rr := httptest.NewRecorder()
+			batchStatusHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			var response BatchResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.Succeeded != tt.expectedSucceeded || response.Failed != tt.expectedFailed {
+				t.Errorf("Expected %d succeeded/%d failed, got %d/%d",
+					tt.expectedSucceeded, tt.expectedFailed, response.Succeeded, response.Failed)
+			}
+			if len(response.Results) != 2 {
+				t.Fatalf("Expected 2 results, got %d", len(response.Results))
+			}
+
+			// Results keep request order and carry per-entry state or error
+			for i, repo := range []string{"one", "two"} {
+				result := response.Results[i]
+				if result.Repository != repo {
+					t.Errorf("Expected result %d for '%s', got '%s'", i, repo, result.Repository)
+				}
+				failed := strings.Contains(strings.Join(tt.failing, ","), repo)
+				if failed && result.Error == "" {
+					t.Errorf("Expected error for '%s', got none", repo)
+				}
+				if !failed && result.State != "success" {
+					t.Errorf("Expected state 'success' for '%s', got '%s'", repo, result.State)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchStatusHandler_InvalidRequests(t *testing.T) {
+	tooMany := make([]string, config.MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf(`{"owner": "o", "repo": "r%d"}`, i)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+	}{
+		{"wrong method", "GET", "", http.StatusMethodNotAllowed},
+		{"invalid JSON", "POST", `{"repos": [`, http.StatusBadRequest},
+		{"empty batch", "POST", `{"repos": []}`, http.StatusBadRequest},
+		{"too many entries", "POST", `{"repos": [` + strings.Join(tooMany, ",") + `]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/status/batch", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			batchStatusHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestBatchStatusHandler_MissingFieldsFailEntry(t *testing.T) {
+	setMockService(t, batchMock())
+
+	body := `{"repos": [{"owner": "testowner", "repo": "one"}, {"owner": "testowner"}]}`
+	req := httptest.NewRequest("POST", "/status/batch", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	batchStatusHandler(rr, req)
+
+	if rr.Code != http.StatusMultiStatus {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusMultiStatus)
+	}
+}
+
+func TestBatchStatusHandler_OverlongEntryIsIdentified(t *testing.T) {
+	setMockService(t, batchMock())
+
+	branch := strings.Repeat("b", config.MaxParamLength+1)
+	body := `{"repos": [{"owner": "testowner", "repo": "one"}, {"owner": "testowner", "repo": "two", "branch": "` + branch + `"}]}`
+	req := httptest.NewRequest("POST", "/status/batch", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	batchStatusHandler(rr, req)
+
+	var response BatchResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	failed := response.Results[1]
+	if failed.Error == "" || failed.Owner != "testowner" || failed.Repository != "two" {
+		t.Errorf("Expected a failed entry for testowner/two, got %+v", failed)
+	}
+}
+
+func TestBatchStatusHandler_NDJSON(t *testing.T) {
+	setMockService(t, batchMock("two"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status/batch", batchStatusHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	body := `{"repos": [{"owner": "testowner", "repo": "one"}, {"owner": "testowner", "repo": "two"}, {"repo": "three"}]}`
+	resp, err := http.Post(server.URL+"/status/batch?format=ndjson", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to post batch: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected application/x-ndjson, got %q", ct)
+	}
+
+	errors := make(map[string]string)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var result BuildStatusResponse
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("Line %q does not parse on its own: %v", scanner.Text(), err)
+		}
+		errors[result.Repository] = result.Error
+	}
+
+	if len(errors) != 3 {
+		t.Fatalf("Expected 3 result lines, got %v", errors)
+	}
+	if errors["one"] != "" || errors["two"] == "" || errors["three"] == "" {
+		t.Errorf("Expected only 'one' to succeed, got %v", errors)
+	}
+}
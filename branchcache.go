@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// branchEntry holds a cached default branch until it expires
+type branchEntry struct {
+	branch  string
+	expires time.Time
+}
+
+// branchCache keeps the default branch of recently queried repositories.
+// Default branches rarely change, so they are kept for BRANCH_CACHE_TTL,
+// longer than statuses.
+type branchCache struct {
+	mu      sync.Mutex
+	entries map[string]branchEntry
+}
+
+// newBranchCache creates an empty default branch cache
+func newBranchCache() *branchCache {
+	return &branchCache{entries: make(map[string]branchEntry)}
+}
+
+var defaultBranches = newBranchCache()
+
+// get returns the cached default branch of owner/repo when present and not yet expired
+func (c *branchCache) get(owner, repo string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.ToLower(owner + "/" + repo)
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.branch, true
+}
+
+// set stores the default branch of owner/repo for the given time to live
+func (c *branchCache) set(owner, repo, branch string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[strings.ToLower(owner+"/"+repo)] = branchEntry{branch: branch, expires: time.Now().Add(ttl)}
+}
+
+// rememberDefaultBranch caches the default branch from freshly fetched repository metadata
+func rememberDefaultBranch(owner, repo string, repository *Repository) {
+	if config.BranchCacheTTL > 0 && repository.DefaultBranch != "" {
+		defaultBranches.set(owner, repo, repository.DefaultBranch, config.BranchCacheTTL)
+	}
+}
+
+// fetchDefaultBranch returns the default branch of owner/repo, consulting
+// the branch cache first when BRANCH_CACHE_TTL is set
+func fetchDefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	if config.BranchCacheTTL > 0 {
+		if branch, ok := defaultBranches.get(owner, repo); ok {
+			return branch, nil
+		}
+	}
+
+	repository, err := service.withContext(ctx).GetRepository(owner, repo)
+	if err != nil {
+		return "", err
+	}
+	rememberDefaultBranch(owner, repo, repository)
+	return repository.DefaultBranch, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetBranchCache gives the test an empty default branch cache, so default
+// branches mocked by earlier tests are not served from it
+func resetBranchCache(t *testing.T) {
+	t.Helper()
+
+	originalBranches := defaultBranches
+	defaultBranches = newBranchCache()
+	t.Cleanup(func() { defaultBranches = originalBranches })
+}
+
+func TestBranchCache_Expiry(t *testing.T) {
+	c := newBranchCache()
+	c.set("myorg", "fresh", "main", time.Minute)
+	c.set("myorg", "stale", "main", -time.Second)
+
+	if branch, ok := c.get("MyOrg", "Fresh"); !ok || branch != "main" {
+		t.Errorf("Expected fresh entry to be served, got %q, %v", branch, ok)
+	}
+	if _, ok := c.get("myorg", "stale"); ok {
+		t.Error("Expected expired entry to be a miss")
+	}
+}
+
+func TestStatusHandler_DefaultBranchCachedSeparately(t *testing.T) {
+	enableCache(t, time.Second)
+	config.BranchCacheTTL = 5 * time.Minute
+
+	repoCalls, statusCalls := 0, 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			repoCalls++
+			return createHTTPResponse(200, `{"default_branch": "develop"}`), nil
+		}
+		statusCalls++
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	for i := 0; i < 3; i++ {
+		// Expire the status cache so every request refreshes the status
+		cache = newStatusCache()
+
+		req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo", nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected status 200, got %d", i, rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), `"branch":"develop"`) {
+			t.Errorf("Request %d: expected the cached default branch, got %s", i, rr.Body.String())
+		}
+	}
+
+	if repoCalls != 1 {
+		t.Errorf("Expected the default branch to be looked up once, got %d repository calls", repoCalls)
+	}
+	if statusCalls != 3 {
+		t.Errorf("Expected the status to be refreshed on every request, got %d status calls", statusCalls)
+	}
+}
+
+func TestStatusHandler_DefaultBranchCacheDisabled(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.BranchCacheTTL = 0
+
+	repoCalls := 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			repoCalls++
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo", nil)
+		statusHandler(httptest.NewRecorder(), req)
+	}
+	if repoCalls != 2 {
+		t.Errorf("Expected a repository lookup per request without BRANCH_CACHE_TTL, got %d", repoCalls)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// splitList splits a comma-separated parameter, dropping blanks and duplicates
+func splitList(value string) []string {
+	var items []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		items = append(items, item)
+	}
+	return items
+}
+
+// resolveWorstBranch fetches the status of several branches concurrently
+// and rolls them up into a single response carrying the worst state.
+// Any branch failing to resolve fails the whole lookup, since the rollup
+// would otherwise under-report.
+func resolveWorstBranch(ctx context.Context, owner, repo string, branches []string, opts StatusOptions) (BuildStatusResponse, error) {
+	results := make([]BuildStatusResponse, len(branches))
+	errs := make([]error, len(branches))
+
+	sem := make(chan struct{}, config.BatchConcurrency)
+	var wg sync.WaitGroup
+	for i, branch := range branches {
+		wg.Add(1)
+		go func(i int, branch string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i], errs[i] = resolveBuildStatus(ctx, owner, repo, branch, opts)
+		}(i, branch)
+	}
+	wg.Wait()
+
+	response := BuildStatusResponse{
+		Owner:      owner,
+		Repository: repo,
+		Branches:   branches,
+	}
+
+	states := make([]string, len(results))
+	for i, result := range results {
+		if errs[i] != nil {
+			response.Error = fmt.Sprintf("Branch '%s': %s", branches[i], result.Error)
+			return response, errs[i]
+		}
+		states[i] = result.State
+	}
+
+	response.State = worstState(states...)
+	response.Ok = isOk(response.State)
+	response.Symbol = mapStateToSymbol(response.State)
+	response.Color = mapStateToColor(response.State)
+	response.StateCode = mapStateToCode(response.State)
+	for _, result := range results {
+		// Report the first branch holding the worst state so clients know where to look
+		if result.State == response.State {
+			response.Branch = result.Branch
+			response.SHA = result.SHA
+			response.Commit = result.Commit
+			response.CommitsBack = result.CommitsBack
+			response.Stale = result.Stale
+			response.Summary = result.Summary
+			response.BuildDuration = result.BuildDuration
+			response.Raw = result.Raw
+			break
+		}
+	}
+	for _, result := range results {
+		// Report the oldest fetch so clients never overestimate freshness
+		if result.FetchedAt != "" && (response.FetchedAt == "" || result.FetchedAt < response.FetchedAt) {
+			response.FetchedAt = result.FetchedAt
+		}
+	}
+	if opts.Detail {
+		for _, result := range results {
+			response.Statuses = append(response.Statuses, result.Statuses...)
+			response.Matrix = append(response.Matrix, result.Matrix...)
+			for context, url := range result.ContextURLs {
+				if response.ContextURLs == nil {
+					response.ContextURLs = make(map[string]string)
+				}
+				// Keep the first branch's URL when several report the same context
+				if _, ok := response.ContextURLs[context]; !ok {
+					response.ContextURLs[context] = url
+				}
+			}
+		}
+		sortBySeverity(response.Statuses)
+		sort.SliceStable(response.Matrix, func(i, j int) bool {
+			return response.Matrix[i].Context < response.Matrix[j].Context
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	result := splitList(" main, production,,main ")
+	expected := []string{"main", "production"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("splitList() = %v, want %v", result, expected)
+	}
+}
+
+// branchStatesMock serves the given state for each branch's commit status
+func branchStatesMock(states map[string]string) func(req *http.Request) (*http.Response, error) {
+	return func(req *http.Request) (*http.Response, error) {
+		for branch, state := range states {
+			if strings.HasSuffix(req.URL.Path, "/commits/"+branch+"/status") {
+				if state == "" {
+					return createHTTPResponse(500, `{"message": "Internal Server Error"}`), nil
+				}
+				return createHTTPResponse(200, `{"state": "`+state+`", "statuses": [], "total_count": 1}`), nil
+			}
+		}
+		return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+	}
+}
+
+func TestStatusHandler_WorstOfBranches(t *testing.T) {
+	tests := []struct {
+		name           string
+		states         map[string]string
+		expectedState  string
+		expectedBranch string
+		expectedStatus int
+	}{
+		{
+			name:           "both green",
+			states:         map[string]string{"main": "success", "production": "success"},
+			expectedState:  "success",
+			expectedBranch: "main",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "production failing",
+			states:         map[string]string{"main": "success", "production": "failure"},
+			expectedState:  "failure",
+			expectedBranch: "production",
+			expectedStatus: http.StatusExpectationFailed,
+		},
+		{
+			name:           "pending and error",
+			states:         map[string]string{"main": "pending", "production": "error"},
+			expectedState:  "error",
+			expectedBranch: "production",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, branchStatesMock(tt.states))
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branches=main,production", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.State != tt.expectedState {
+				t.Errorf("Expected state '%s', got '%s'", tt.expectedState, response.State)
+			}
+			if response.Branch != tt.expectedBranch {
+				t.Errorf("Expected branch '%s', got '%s'", tt.expectedBranch, response.Branch)
+			}
+			if !reflect.DeepEqual(response.Branches, []string{"main", "production"}) {
+				t.Errorf("Expected branches [main production], got %v", response.Branches)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_WorstOfBranchesFailure(t *testing.T) {
+	setMockService(t, branchStatesMock(map[string]string{"main": "success", "production": ""}))
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branches=main,production", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusInternalServerError)
+	}
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if !strings.Contains(response.Error, "production") {
+		t.Errorf("Expected error naming the failing branch, got '%s'", response.Error)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// compressThreshold is the encoded size above which CACHE_COMPRESS gzips an entry
+const compressThreshold = 1024
+
+// cacheEntry holds a cached commit status until it expires. Large statuses
+// are held gzipped in compressed instead of status when CACHE_COMPRESS is on,
+// with the status's fetch time and raw upstream body kept alongside in
+// fetched and raw.
+type cacheEntry struct {
+	status     *StatusResponse
+	compressed []byte
+	fetched    time.Time
+	raw        json.RawMessage
+	expires    time.Time
+}
+
+// size reports roughly how many bytes the entry's payload occupies
+func (e cacheEntry) size() int {
+	if e.compressed != nil {
+		return len(e.compressed)
+	}
+	encoded, err := json.Marshal(e.status)
+	if err != nil {
+		return 0
+	}
+	return len(encoded)
+}
+
+// compressStatus gzips the JSON encoding of status, returning nil when it is
+// small enough that compression is not worthwhile
+func compressStatus(status *StatusResponse) ([]byte, error) {
+	encoded, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) <= compressThreshold {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(encoded); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressStatus restores a status stored by compressStatus
+func decompressStatus(compressed []byte) (*StatusResponse, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	encoded, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var status StatusResponse
+	if err := json.Unmarshal(encoded, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Cache holds recently fetched commit statuses for CACHE_TTL. The
+// in-memory statusCache is the default; CACHE_BACKEND=redis shares one
+// Redis-backed cache between replicas. Backends give up when ctx is done,
+// treating the lookup as a miss.
+type Cache interface {
+	// Get returns the cached status for key when present and not yet expired
+	Get(ctx context.Context, key string) (*StatusResponse, bool)
+	// Set stores status under key for the given time to live
+	Set(ctx context.Context, key string, status *StatusResponse, ttl time.Duration)
+	// Delete drops every cached status whose key starts with prefix
+	Delete(ctx context.Context, prefix string)
+	// Describe summarizes the cache for the health check, failing when
+	// the backend cannot be reached
+	Describe(ctx context.Context) (string, error)
+}
+
+// cacheSweepSize is how many cached statuses trigger a sweep of the expired ones
+const cacheSweepSize = 1024
+
+// statusCache keeps recently fetched commit statuses in memory
+type statusCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// newStatusCache creates an empty status cache
+func newStatusCache() *statusCache {
+	return &statusCache{entries: make(map[string]cacheEntry)}
+}
+
+var cache Cache = newStatusCache()
+
+// cacheKey builds the key for the status of ref in a repository. With an
+// empty ref it is the prefix shared by every key of the repository.
+func cacheKey(owner, repo, ref string) string {
+	return strings.Join([]string{owner, repo, ref}, "/")
+}
+
+// cacheKeyHeader names the debug response header listing the cache keys a lookup used
+const cacheKeyHeader = "X-Cache-Key"
+
+// cacheKeyRecorder collects the cache keys used on behalf of a request
+type cacheKeyRecorder struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+type cacheKeyRecorderKey struct{}
+
+// recordCacheKey notes key on the request's recorder, if it has one
+func recordCacheKey(ctx context.Context, key string) {
+	recorder, ok := ctx.Value(cacheKeyRecorderKey{}).(*cacheKeyRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if !slices.Contains(recorder.keys, key) {
+		recorder.keys = append(recorder.keys, key)
+	}
+}
+
+func (c *cacheKeyRecorder) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.keys)
+}
+
+// Get returns the cached status for key when present and not yet expired
+func (c *statusCache) Get(ctx context.Context, key string) (*StatusResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	if entry.compressed != nil {
+		status, err := decompressStatus(entry.compressed)
+		if err != nil {
+			log.Printf("Error decompressing cached status for %s: %v", key, err)
+			delete(c.entries, key)
+			return nil, false
+		}
+		status.FetchedAt = entry.fetched
+		status.Raw = entry.raw
+		return status, true
+	}
+	return entry.status, true
+}
+
+// Set stores status under key for the given time to live
+func (c *statusCache) Set(ctx context.Context, key string, status *StatusResponse, ttl time.Duration) {
+	entry := cacheEntry{status: status, expires: time.Now().Add(ttl)}
+	if config.CacheCompress {
+		compressed, err := compressStatus(status)
+		if err != nil {
+			log.Printf("Error compressing cached status for %s: %v", key, err)
+		} else if compressed != nil {
+			entry = cacheEntry{compressed: compressed, fetched: status.FetchedAt, raw: status.Raw, expires: entry.expires}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Keys include commit SHAs that may never be read again, so expired
+	// entries are swept out rather than left for a read to find
+	if len(c.entries) >= cacheSweepSize {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = entry
+}
+
+// Delete drops every cached status whose key starts with prefix
+func (c *statusCache) Delete(ctx context.Context, prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *statusCache) Describe(ctx context.Context) (string, error) {
+	return fmt.Sprintf("in-memory, %d entries, %d bytes", c.len(), c.size()), nil
+}
+
+// len reports the number of entries currently held, including expired ones not yet evicted
+func (c *statusCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// size reports the approximate number of bytes held by cached payloads
+func (c *statusCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := 0
+	for _, entry := range c.entries {
+		total += entry.size()
+	}
+	return total
+}
+
+// Case normalization modes for owner and repository names
+const (
+	caseLower = "lower"
+	caseNone  = "none"
+)
+
+// canonicalName normalizes an owner or repository name to the configured
+// case. Gitea matches these case-insensitively, so normalizing keeps
+// differently-cased requests on the same cache entry.
+func canonicalName(name string) string {
+	if config.CaseNormalization == caseLower {
+		return strings.ToLower(name)
+	}
+	return name
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// enableCache turns on status caching with an empty cache for the duration of the test
+func enableCache(t *testing.T, ttl time.Duration) {
+	t.Helper()
+
+	originalConfig := config
+	originalCache := cache
+	config.CacheTTL = ttl
+	cache = newStatusCache()
+	t.Cleanup(func() {
+		config = originalConfig
+		cache = originalCache
+	})
+}
+
+func TestStatusCache_Expiry(t *testing.T) {
+	c := newStatusCache()
+	c.Set(context.Background(), "fresh", &StatusResponse{State: "success"}, time.Minute)
+	c.Set(context.Background(), "stale", &StatusResponse{State: "failure"}, -time.Second)
+
+	if status, ok := c.Get(context.Background(), "fresh"); !ok || status.State != "success" {
+		t.Errorf("Expected fresh entry to be served, got %v, %v", status, ok)
+	}
+	if _, ok := c.Get(context.Background(), "stale"); ok {
+		t.Error("Expected expired entry to be a miss")
+	}
+	if c.len() != 1 {
+		t.Errorf("Expected expired entry to be evicted, got %d entries", c.len())
+	}
+}
+
+func TestStatusCache_SweepsExpiredEntries(t *testing.T) {
+	c := newStatusCache()
+	for i := range cacheSweepSize {
+		c.Set(context.Background(), fmt.Sprintf("myorg/myrepo/sha-%d", i), &StatusResponse{State: "success"}, -time.Second)
+	}
+	c.Set(context.Background(), "myorg/myrepo/live", &StatusResponse{State: "success"}, time.Minute)
+	c.Set(context.Background(), "myorg/myrepo/next", &StatusResponse{State: "success"}, time.Minute)
+
+	if c.len() != 2 {
+		t.Errorf("Expected expired entries never read again to be swept, got %d entries", c.len())
+	}
+}
+
+func TestStatusHandler_CaseInsensitiveCacheSharing(t *testing.T) {
+	enableCache(t, time.Minute)
+
+	statusCalls := 0
+	var requestedPaths []string
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		requestedPaths = append(requestedPaths, req.URL.Path)
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		statusCalls++
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	for _, query := range []string{"owner=MyOrg&repo=MyRepo", "owner=myorg&repo=myrepo"} {
+		req := httptest.NewRequest("GET", "/status?"+query, nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+
+		var response BuildStatusResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Could not parse response JSON: %v", err)
+		}
+
+		// The client's own spelling is echoed back
+		expectedOwner := strings.TrimPrefix(strings.Split(query, "&")[0], "owner=")
+		if response.Owner != expectedOwner {
+			t.Errorf("Expected owner '%s', got '%s'", expectedOwner, response.Owner)
+		}
+	}
+
+	if statusCalls != 1 {
+		t.Errorf("Expected differently-cased requests to share one cache entry, got %d status calls", statusCalls)
+	}
+	if entries := cache.(*statusCache).len(); entries != 1 {
+		t.Errorf("Expected 1 cache entry, got %d", entries)
+	}
+	for _, path := range requestedPaths {
+		if path != strings.ToLower(path) {
+			t.Errorf("Expected upstream path in canonical case, got %s", path)
+		}
+	}
+}
+
+func TestCanonicalName_None(t *testing.T) {
+	originalConfig := config
+	config.CaseNormalization = caseNone
+	defer func() { config = originalConfig }()
+
+	if result := canonicalName("MyOrg"); result != "MyOrg" {
+		t.Errorf("Expected name to be left as-is, got '%s'", result)
+	}
+}
+
+func TestLoadConfig_CaseNormalization(t *testing.T) {
+	t.Setenv("CASE_NORMALIZATION", "upper")
+	if _, err := loadConfig(); err == nil {
+		t.Error("Expected error for unsupported case normalization, got nil")
+	}
+}
+
+// largeStatus builds a status with enough checks to cross the compression threshold
+func largeStatus() *StatusResponse {
+	updated := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	status := &StatusResponse{State: "failure", TotalCount: 40}
+	for i := 0; i < 40; i++ {
+		state := "success"
+		if i%7 == 0 {
+			state = "failure"
+		}
+		status.Statuses = append(status.Statuses, CommitStatus{
+			ID:          int64(i + 1),
+			State:       state,
+			Context:     fmt.Sprintf("ci/job-%d", i),
+			TargetURL:   fmt.Sprintf("https://ci.example.com/builds/%d", i),
+			Description: "Build finished",
+			CreatedAt:   updated,
+			UpdatedAt:   updated.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	return status
+}
+
+func TestStatusCache_CompressedRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   *StatusResponse
+		compress bool
+	}{
+		{"large compressed", largeStatus(), true},
+		{"small kept plain", &StatusResponse{State: "success", TotalCount: 1, Statuses: []CommitStatus{{ID: 1, State: "success", Context: "ci/build"}}}, true},
+		{"compression off", largeStatus(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.CacheCompress = tt.compress
+			defer func() { config = originalConfig }()
+
+			c := newStatusCache()
+			c.Set(context.Background(), "key", tt.status, time.Minute)
+
+			got, ok := c.Get(context.Background(), "key")
+			if !ok {
+				t.Fatal("Expected cached entry to be served")
+			}
+			if !reflect.DeepEqual(got, tt.status) {
+				t.Errorf("Expected cached status to round-trip, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestStatusCache_CompressionShrinksEntries(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+
+	config.CacheCompress = false
+	plain := newStatusCache()
+	plain.Set(context.Background(), "key", largeStatus(), time.Minute)
+
+	config.CacheCompress = true
+	compressed := newStatusCache()
+	compressed.Set(context.Background(), "key", largeStatus(), time.Minute)
+
+	if compressed.entries["key"].compressed == nil {
+		t.Fatal("Expected large entry to be stored compressed")
+	}
+	if compressed.size() >= plain.size() {
+		t.Errorf("Expected compressed entry to be smaller, got %d bytes vs %d plain", compressed.size(), plain.size())
+	}
+}
+
+func TestStatusHandler_ServesCompressedCacheEntries(t *testing.T) {
+	enableCache(t, time.Minute)
+	config.CacheCompress = true
+
+	statusCalls := 0
+	body, err := json.Marshal(largeStatus())
+	if err != nil {
+		t.Fatalf("Could not encode status: %v", err)
+	}
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/status") {
+			statusCalls++
+		}
+		return createHTTPResponse(200, string(body)), nil
+	})
+
+	var bodies []string
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main&detail=true", nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+		bodies = append(bodies, rr.Body.String())
+	}
+
+	if statusCalls != 1 {
+		t.Errorf("Expected second request to be served from cache, got %d upstream calls", statusCalls)
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("Expected cached response to match the fresh one:\n%s\n%s", bodies[0], bodies[1])
+	}
+}
+
+func TestStatusHandler_CacheKeyHeader(t *testing.T) {
+	enableCache(t, time.Minute)
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	tests := []struct {
+		debug    bool
+		expected string
+	}{
+		{false, ""},
+		{true, "myorg/myrepo/main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("debug=%v", tt.debug), func(t *testing.T) {
+			config.DebugCacheKeys = tt.debug
+
+			req := httptest.NewRequest("GET", "/status?owner=MyOrg&repo=MyRepo&branches=main", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if header := rr.Header().Get(cacheKeyHeader); header != tt.expected {
+				t.Errorf("Expected %s header %q, got %q", cacheKeyHeader, tt.expected, header)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_CachedResponseKeepsFetchTime(t *testing.T) {
+	enableCache(t, time.Hour)
+
+	fetched := time.Now().Add(-30 * time.Minute).Truncate(time.Second)
+	cache.Set(context.Background(), cacheKey("myorg", "myrepo", "main"), &StatusResponse{
+		State:      "success",
+		TotalCount: 1,
+		FetchedAt:  fetched,
+	}, time.Hour)
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		t.Errorf("Expected the cached status to be served, got upstream request %s", req.URL.Path)
+		return createHTTPResponse(500, `{}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if expected := fetched.UTC().Format(time.RFC3339); response.FetchedAt != expected {
+		t.Errorf("Expected fetched_at %s from the original fetch, got %s", expected, response.FetchedAt)
+	}
+}
+
+func TestStatusHandler_FreshResponseFetchTime(t *testing.T) {
+	enableCache(t, time.Hour)
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	before := time.Now().Add(-time.Second)
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	fetchedAt, err := time.Parse(time.RFC3339, response.FetchedAt)
+	if err != nil {
+		t.Fatalf("Expected an RFC3339 fetched_at, got %q: %v", response.FetchedAt, err)
+	}
+	if fetchedAt.Before(before) || fetchedAt.After(time.Now()) {
+		t.Errorf("Expected fetched_at to be the fetch time, got %s", response.FetchedAt)
+	}
+}
+
+func TestStatusCache_CompressedEntryKeepsFetchTime(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.CacheCompress = true
+
+	status := &StatusResponse{State: "success", FetchedAt: time.Now().Add(-time.Hour).Truncate(time.Second)}
+	for i := 0; i < 50; i++ {
+		status.Statuses = append(status.Statuses, CommitStatus{Context: fmt.Sprintf("ci/job-%d", i), State: "success"})
+	}
+
+	c := newStatusCache()
+	c.Set(context.Background(), "key", status, time.Minute)
+	cached, ok := c.Get(context.Background(), "key")
+	if !ok {
+		t.Fatal("Expected the compressed entry to be served")
+	}
+	if !cached.FetchedAt.Equal(status.FetchedAt) {
+		t.Errorf("Expected fetch time %s, got %s", status.FetchedAt, cached.FetchedAt)
+	}
+}
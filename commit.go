@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CommitSummary describes the commit a status belongs to, for notices
+// such as "build for commit by Alice: success"
+type CommitSummary struct {
+	SHA       string `json:"sha"`
+	Author    string `json:"author"`
+	Committer string `json:"committer,omitempty"`
+	Summary   string `json:"summary"`
+}
+
+// MarshalJSON encodes the commit using the configured field naming style
+func (c CommitSummary) MarshalJSON() ([]byte, error) {
+	type plain CommitSummary
+	return marshalWithNaming(plain(c))
+}
+
+// GetCommit fetches a single commit by its full or abbreviated SHA
+func (g *GiteaService) GetCommit(owner, repo, sha string) (*Commit, error) {
+	endpoint, err := g.apiURL("repos", owner, repo, "git", "commits", sha)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Commit
+	if err := g.forRepo(owner, repo).getJSON(endpoint, "get commit", &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// applyCommitSummary describes the commit status was reported for. The
+// SHA already resolved for the response is used when there is one, then
+// the one Gitea reported with the status, so the branch is not resolved
+// again and cannot move in between.
+func applyCommitSummary(ctx context.Context, response *BuildStatusResponse, owner, repo, ref string, status *StatusResponse) error {
+	sha := response.SHA
+	if sha == "" {
+		sha = status.SHA
+	}
+	if sha == "" {
+		sha = ref
+	}
+
+	commit, err := service.withContext(ctx).GetCommit(canonicalName(owner), canonicalName(repo), sha)
+	if err != nil {
+		response.Error = fmt.Sprintf("Failed to get commit details: %v", err)
+		return err
+	}
+
+	summary := &CommitSummary{
+		SHA:     commit.SHA,
+		Author:  commit.Commit.Author.Name,
+		Summary: commitSubject(commit.Commit.Message),
+	}
+	if committer := commit.Commit.Committer.Name; committer != summary.Author {
+		summary.Committer = committer
+	}
+	response.Commit = summary
+	return nil
+}
+
+// commitSubject returns the first line of a commit message
+func commitSubject(message string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(subject)
+}
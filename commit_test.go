@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const commitPayload = `{
+    "sha": "0123456789abcdef0123456789abcdef01234567",
+    "commit": {
+        "message": "Fix the deploy script\n\nThe old one forgot the migrations.\n",
+        "author": {"name": "Alice", "email": "alice@example.com"},
+        "committer": {"name": "Gitea", "email": "noreply@example.com"}
+    }
+}`
+
+func TestStatusHandler_IncludeCommit(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	var commitPaths []string
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "/git/commits/") {
+			commitPaths = append(commitPaths, req.URL.Path)
+			return createHTTPResponse(200, commitPayload), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "sha": "`+sha+`", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main&include_commit=true", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	expected := CommitSummary{SHA: sha, Author: "Alice", Committer: "Gitea", Summary: "Fix the deploy script"}
+	if response.Commit == nil || *response.Commit != expected {
+		t.Errorf("Expected commit %+v, got %+v", expected, response.Commit)
+	}
+	// The SHA reported with the status is reused rather than resolving the branch again
+	if len(commitPaths) != 1 || !strings.HasSuffix(commitPaths[0], "/repos/myorg/myrepo/git/commits/"+sha) {
+		t.Errorf("Expected one lookup of the status commit, got %v", commitPaths)
+	}
+}
+
+func TestStatusHandler_IncludeCommitOffByDefault(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "/git/commits/") {
+			t.Error("Expected no commit lookup without include_commit")
+		}
+		return createHTTPResponse(200, `{"state": "success", "sha": "abc123", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if strings.Contains(rr.Body.String(), `"commit"`) {
+		t.Errorf("Expected no commit object, got %s", rr.Body.String())
+	}
+}
+
+func TestStatusHandler_IncludeCommitFails(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "/git/commits/") {
+			return createHTTPResponse(404, `{"message": "not found"}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "sha": "abc123", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main&include_commit=true", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if rr.Code < http.StatusBadRequest || !strings.Contains(response.Error, "Failed to get commit details") {
+		t.Errorf("Expected the commit lookup error, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestCommitSubject(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"Fix the build", "Fix the build"},
+		{"Fix the build\n\nDetails follow", "Fix the build"},
+		{"\n  Leading blank lines  \nrest", "Leading blank lines"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if result := commitSubject(tt.message); result != tt.expected {
+			t.Errorf("commitSubject(%q) = %q, want %q", tt.message, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,745 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Config holds optional service settings resolved from the environment
+type Config struct {
+	BatchConcurrency       int
+	OrgConcurrency         int
+	OrgTimeout             time.Duration
+	PerHostConcurrency     int
+	MaxConcurrentLookups   int
+	MaxBatchSize           int
+	DialTimeout            time.Duration
+	MaxRedirects           int
+	StateHTTPCodes         map[string]int
+	APIKey                 string
+	StateColors            map[string]string
+	StateSymbols           map[string]string
+	MaxQueryLength         int
+	UpstreamErrorBodyLimit int
+	MaxParamLength         int
+	DefaultOwner           string
+	TraceBufferSize        int
+	UpstreamRetryAfter     time.Duration
+	FieldNaming            string
+	ErrorField             string
+	RequestDeadline        time.Duration
+	StatusDeadline         time.Duration
+	BatchDeadline          time.Duration
+	OrgDeadline            time.Duration
+	CacheTTL               time.Duration
+	BranchCacheTTL         time.Duration
+	CacheCompress          bool
+	CacheBackend           string
+	RedisURL               string
+	PublicGiteaURL         string
+	CaseNormalization      string
+	WebhookSecret          string
+	SigningSecret          string
+	SigningHeader          string
+	SSEKeepAlive           time.Duration
+	ShutdownTimeout        time.Duration
+	EnableH2C              bool
+	LongPollTimeout        time.Duration
+	LongPollInterval       time.Duration
+	RepoTokens             map[string]string
+	RepoAllowlist          []string
+	TrustedProxies         []string
+	DebugLogBodies         bool
+	DebugCacheKeys         bool
+	DebugRawUpstream       bool
+	MaintenanceMode        bool
+	Envelope               bool
+	StartupCheck           bool
+	PendingGrace           time.Duration
+	MaxLookback            int
+	MinRepoInterval        time.Duration
+	MaxStatusAge           time.Duration
+	StaleDowngrade         bool
+	OkIncludesWarning      bool
+	NoCIState              string
+	PrimaryContext         string
+	SummaryTemplate        string
+	SuggestPermissions     bool
+	QuietErrors            bool
+	AllowAnonymousFallback bool
+	UpstreamRetries        int
+	RetryBackoff           time.Duration
+	RetryBudget            time.Duration
+	MaxSubscribers         int
+	WarningHTTPCode        int
+	HistorySize            int
+	LogSampleRate          float64
+	DegradedLatency        time.Duration
+	DegradedErrorRate      float64
+	TestMode               bool
+	InjectDelay            map[string]time.Duration
+}
+
+var config = defaultConfig()
+
+// defaultConfig returns the settings used when no overrides are present
+func defaultConfig() Config {
+	return Config{
+		BatchConcurrency:       5,
+		OrgConcurrency:         2,
+		MaxBatchSize:           50,
+		DialTimeout:            3 * time.Second,
+		MaxRedirects:           5,
+		MaxQueryLength:         2048,
+		UpstreamErrorBodyLimit: 200,
+		MaxParamLength:         100,
+		TraceBufferSize:        100,
+		UpstreamRetryAfter:     5 * time.Second,
+		FieldNaming:            namingSnake,
+		ErrorField:             errorFieldOmit,
+		CaseNormalization:      caseLower,
+		SSEKeepAlive:           15 * time.Second,
+		ShutdownTimeout:        10 * time.Second,
+		LongPollTimeout:        30 * time.Second,
+		LongPollInterval:       5 * time.Second,
+		MaxLookback:            10,
+		SummaryTemplate:        defaultSummaryTemplate,
+		RetryBackoff:           200 * time.Millisecond,
+		MaxSubscribers:         100,
+		WarningHTTPCode:        http.StatusOK,
+		HistorySize:            50,
+		LogSampleRate:          1,
+		DegradedLatency:        2 * time.Second,
+		DegradedErrorRate:      0.5,
+		BranchCacheTTL:         5 * time.Minute,
+		CacheBackend:           cacheBackendMemory,
+		SigningHeader:          defaultSigningHeader,
+	}
+}
+
+// loadConfig reads optional settings from the environment on top of the defaults
+func loadConfig() (Config, error) {
+	cfg := defaultConfig()
+
+	var err error
+	if cfg.BatchConcurrency, err = getEnvInt("BATCH_CONCURRENCY", cfg.BatchConcurrency); err != nil {
+		return cfg, err
+	}
+	if cfg.OrgConcurrency, err = getEnvInt("ORG_CONCURRENCY", cfg.OrgConcurrency); err != nil {
+		return cfg, err
+	}
+	if cfg.OrgTimeout, err = getEnvDuration("ORG_TIMEOUT", cfg.OrgTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.PerHostConcurrency, err = getEnvInt("PER_HOST_CONCURRENCY", cfg.PerHostConcurrency); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxConcurrentLookups, err = getEnvInt("MAX_CONCURRENT_LOOKUPS", cfg.MaxConcurrentLookups); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxBatchSize, err = getEnvInt("MAX_BATCH_SIZE", cfg.MaxBatchSize); err != nil {
+		return cfg, err
+	}
+	if cfg.DialTimeout, err = getEnvDuration("DIAL_TIMEOUT", cfg.DialTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxRedirects, err = getEnvInt("MAX_REDIRECTS", cfg.MaxRedirects); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxQueryLength, err = getEnvInt("MAX_QUERY_LENGTH", cfg.MaxQueryLength); err != nil {
+		return cfg, err
+	}
+	if cfg.UpstreamErrorBodyLimit, err = getEnvInt("UPSTREAM_ERROR_BODY_LIMIT", cfg.UpstreamErrorBodyLimit); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxParamLength, err = getEnvInt("MAX_PARAM_LENGTH", cfg.MaxParamLength); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxLookback, err = getEnvInt("MAX_LOOKBACK", cfg.MaxLookback); err != nil {
+		return cfg, err
+	}
+	if cfg.MinRepoInterval, err = getEnvDuration("MIN_REPO_INTERVAL", cfg.MinRepoInterval); err != nil {
+		return cfg, err
+	}
+	if cfg.UpstreamRetries, err = getEnvInt("UPSTREAM_RETRIES", cfg.UpstreamRetries); err != nil {
+		return cfg, err
+	}
+	if cfg.RetryBackoff, err = getEnvDuration("RETRY_BACKOFF", cfg.RetryBackoff); err != nil {
+		return cfg, err
+	}
+	if cfg.RetryBudget, err = getEnvDuration("RETRY_BUDGET", cfg.RetryBudget); err != nil {
+		return cfg, err
+	}
+	if cfg.UpstreamRetryAfter, err = getEnvDuration("UPSTREAM_RETRY_AFTER", cfg.UpstreamRetryAfter); err != nil {
+		return cfg, err
+	}
+	if cfg.RequestDeadline, err = getEnvDuration("REQUEST_DEADLINE", cfg.RequestDeadline); err != nil {
+		return cfg, err
+	}
+	if cfg.StatusDeadline, err = getEnvDuration("STATUS_DEADLINE", cfg.StatusDeadline); err != nil {
+		return cfg, err
+	}
+	if cfg.BatchDeadline, err = getEnvDuration("BATCH_DEADLINE", cfg.BatchDeadline); err != nil {
+		return cfg, err
+	}
+	if cfg.OrgDeadline, err = getEnvDuration("ORG_DEADLINE", cfg.OrgDeadline); err != nil {
+		return cfg, err
+	}
+	if cfg.CacheTTL, err = getEnvDuration("CACHE_TTL", cfg.CacheTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.BranchCacheTTL, err = getEnvDuration("BRANCH_CACHE_TTL", cfg.BranchCacheTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.CacheCompress, err = getEnvBool("CACHE_COMPRESS", cfg.CacheCompress); err != nil {
+		return cfg, err
+	}
+	if backend := os.Getenv("CACHE_BACKEND"); backend != "" {
+		if backend != cacheBackendMemory && backend != cacheBackendRedis {
+			return cfg, fmt.Errorf("CACHE_BACKEND must be %q or %q, got %q", cacheBackendMemory, cacheBackendRedis, backend)
+		}
+		cfg.CacheBackend = backend
+	}
+	cfg.RedisURL = os.Getenv("REDIS_URL")
+	if cfg.CacheBackend == cacheBackendRedis {
+		if cfg.RedisURL == "" {
+			return cfg, fmt.Errorf("REDIS_URL is required with CACHE_BACKEND=%s", cacheBackendRedis)
+		}
+		if _, err := parseRedisURL(cfg.RedisURL); err != nil {
+			return cfg, fmt.Errorf("REDIS_URL is invalid: %v", err)
+		}
+	}
+	if public := os.Getenv("PUBLIC_GITEA_URL"); public != "" {
+		u, err := url.Parse(public)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return cfg, fmt.Errorf("PUBLIC_GITEA_URL must be an absolute http or https URL, got %q", public)
+		}
+		cfg.PublicGiteaURL = public
+	}
+	if cfg.PendingGrace, err = getEnvDuration("PENDING_GRACE", cfg.PendingGrace); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxStatusAge, err = getEnvDuration("MAX_STATUS_AGE", cfg.MaxStatusAge); err != nil {
+		return cfg, err
+	}
+	if cfg.StaleDowngrade, err = getEnvBool("STALE_DOWNGRADE", cfg.StaleDowngrade); err != nil {
+		return cfg, err
+	}
+	if cfg.OkIncludesWarning, err = getEnvBool("OK_INCLUDES_WARNING", cfg.OkIncludesWarning); err != nil {
+		return cfg, err
+	}
+	cfg.NoCIState = os.Getenv("NO_CI_STATE")
+	if cfg.NoCIState != "" && !isCanonicalState(cfg.NoCIState) {
+		return cfg, fmt.Errorf("NO_CI_STATE has unknown state %q", cfg.NoCIState)
+	}
+	cfg.PrimaryContext = os.Getenv("PRIMARY_CONTEXT")
+	if normalization := os.Getenv("CASE_NORMALIZATION"); normalization != "" {
+		if normalization != caseLower && normalization != caseNone {
+			return cfg, fmt.Errorf("CASE_NORMALIZATION must be %q or %q, got %q", caseLower, caseNone, normalization)
+		}
+		cfg.CaseNormalization = normalization
+	}
+	if rate := os.Getenv("LOG_SAMPLE_RATE"); rate != "" {
+		if cfg.LogSampleRate, err = strconv.ParseFloat(rate, 64); err != nil || cfg.LogSampleRate < 0 || cfg.LogSampleRate > 1 {
+			return cfg, fmt.Errorf("LOG_SAMPLE_RATE must be a number between 0 and 1, got %q", rate)
+		}
+	}
+	if cfg.DegradedLatency, err = getEnvDuration("DEGRADED_LATENCY", cfg.DegradedLatency); err != nil {
+		return cfg, err
+	}
+	if rate := os.Getenv("DEGRADED_ERROR_RATE"); rate != "" {
+		if cfg.DegradedErrorRate, err = strconv.ParseFloat(rate, 64); err != nil || cfg.DegradedErrorRate < 0 || cfg.DegradedErrorRate > 1 {
+			return cfg, fmt.Errorf("DEGRADED_ERROR_RATE must be a number between 0 and 1, got %q", rate)
+		}
+	}
+	if cfg.TraceBufferSize, err = getEnvInt("TRACE_BUFFER_SIZE", cfg.TraceBufferSize); err != nil {
+		return cfg, err
+	}
+	if naming := os.Getenv("FIELD_NAMING"); naming != "" {
+		if naming != namingSnake && naming != namingCamel {
+			return cfg, fmt.Errorf("FIELD_NAMING must be %q or %q, got %q", namingSnake, namingCamel, naming)
+		}
+		cfg.FieldNaming = naming
+	}
+	if errorField := os.Getenv("ERROR_FIELD"); errorField != "" {
+		if errorField != errorFieldOmit && errorField != errorFieldAlways {
+			return cfg, fmt.Errorf("ERROR_FIELD must be %q or %q, got %q", errorFieldOmit, errorFieldAlways, errorField)
+		}
+		cfg.ErrorField = errorField
+	}
+	if cfg.SSEKeepAlive, err = getEnvDuration("SSE_KEEPALIVE", cfg.SSEKeepAlive); err != nil {
+		return cfg, err
+	}
+	if cfg.ShutdownTimeout, err = getEnvDuration("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.EnableH2C, err = getEnvBool("ENABLE_H2C", cfg.EnableH2C); err != nil {
+		return cfg, err
+	}
+	if cfg.LongPollTimeout, err = getEnvDuration("LONG_POLL_TIMEOUT", cfg.LongPollTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.LongPollInterval, err = getEnvDuration("LONG_POLL_INTERVAL", cfg.LongPollInterval); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxSubscribers, err = getEnvInt("MAX_SUBSCRIBERS", cfg.MaxSubscribers); err != nil {
+		return cfg, err
+	}
+	if cfg.HistorySize, err = getEnvInt("HISTORY_SIZE", cfg.HistorySize); err != nil {
+		return cfg, err
+	}
+	if cfg.SuggestPermissions, err = getEnvBool("SUGGEST_PERMISSIONS", cfg.SuggestPermissions); err != nil {
+		return cfg, err
+	}
+	if cfg.QuietErrors, err = getEnvBool("QUIET_ERRORS", cfg.QuietErrors); err != nil {
+		return cfg, err
+	}
+	if cfg.AllowAnonymousFallback, err = getEnvBool("ALLOW_ANONYMOUS_FALLBACK", cfg.AllowAnonymousFallback); err != nil {
+		return cfg, err
+	}
+	if cfg.DebugLogBodies, err = getEnvBool("DEBUG_LOG_BODIES", cfg.DebugLogBodies); err != nil {
+		return cfg, err
+	}
+	if cfg.DebugCacheKeys, err = getEnvBool("DEBUG_CACHE_KEYS", cfg.DebugCacheKeys); err != nil {
+		return cfg, err
+	}
+	if cfg.DebugRawUpstream, err = getEnvBool("DEBUG_RAW_UPSTREAM", cfg.DebugRawUpstream); err != nil {
+		return cfg, err
+	}
+	if cfg.MaintenanceMode, err = getEnvBool("MAINTENANCE_MODE", cfg.MaintenanceMode); err != nil {
+		return cfg, err
+	}
+	if cfg.StartupCheck, err = getEnvBool("STARTUP_CHECK", cfg.StartupCheck); err != nil {
+		return cfg, err
+	}
+	if cfg.Envelope, err = getEnvBool("ENVELOPE", cfg.Envelope); err != nil {
+		return cfg, err
+	}
+	if summary := os.Getenv("SUMMARY_TEMPLATE"); summary != "" {
+		if _, err := template.New("summary").Parse(summary); err != nil {
+			return cfg, fmt.Errorf("SUMMARY_TEMPLATE is not a valid template: %v", err)
+		}
+		cfg.SummaryTemplate = summary
+	}
+	cfg.WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+	cfg.SigningSecret = os.Getenv("SIGNING_SECRET")
+	if header := os.Getenv("SIGNING_HEADER"); header != "" {
+		cfg.SigningHeader = http.CanonicalHeaderKey(header)
+	}
+	cfg.APIKey = os.Getenv("API_KEY")
+	cfg.DefaultOwner = os.Getenv("DEFAULT_OWNER")
+	if cfg.StateColors, err = parseStatePairs("STATE_COLORS", os.Getenv("STATE_COLORS")); err != nil {
+		return cfg, err
+	}
+	if cfg.StateSymbols, err = parseStatePairs("STATE_SYMBOLS", os.Getenv("STATE_SYMBOLS")); err != nil {
+		return cfg, err
+	}
+	if cfg.StateHTTPCodes, err = parseStateHTTPCodes(os.Getenv("STATE_HTTP_CODES")); err != nil {
+		return cfg, err
+	}
+	if cfg.TestMode, err = getEnvBool("TEST_MODE", cfg.TestMode); err != nil {
+		return cfg, err
+	}
+	if cfg.InjectDelay, err = parseInjectDelay(os.Getenv("INJECT_DELAY")); err != nil {
+		return cfg, err
+	}
+	if cfg.WarningHTTPCode, err = getEnvInt("WARNING_HTTP_CODE", cfg.WarningHTTPCode); err != nil {
+		return cfg, err
+	}
+	if cfg.WarningHTTPCode < 100 || cfg.WarningHTTPCode > 599 {
+		return cfg, fmt.Errorf("WARNING_HTTP_CODE must be an HTTP code between 100 and 599, got %d", cfg.WarningHTTPCode)
+	}
+	if cfg.RepoTokens, err = parseRepoTokens(os.Getenv("REPO_TOKENS")); err != nil {
+		return cfg, err
+	}
+	if cfg.RepoAllowlist, err = parseRepoAllowlist(os.Getenv("REPO_ALLOWLIST")); err != nil {
+		return cfg, err
+	}
+	if cfg.TrustedProxies, err = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES")); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// getEnvInt reads a positive integer from the environment, falling back to def when unset
+func getEnvInt(name string, def int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", name, value)
+	}
+	return n, nil
+}
+
+// getEnvDuration reads a positive duration (e.g. "2s") from the environment, falling back to def when unset
+func getEnvDuration(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", name, value)
+	}
+	return d, nil
+}
+
+// getEnvBool reads a boolean (e.g. "true" or "1") from the environment, falling back to def when unset
+func getEnvBool(name string, def bool) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean, got %q", name, value)
+	}
+	return b, nil
+}
+
+// parseStatePairs parses a comma-separated list of state=value pairs from
+// the named variable, requiring each state to be canonical
+func parseStatePairs(name, value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		state, v, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return nil, fmt.Errorf("%s entry %q must be in state=value form", name, pair)
+		}
+
+		state = strings.TrimSpace(state)
+		if !isCanonicalState(state) {
+			return nil, fmt.Errorf("%s entry %q has unknown state %q", name, pair, state)
+		}
+		pairs[state] = strings.TrimSpace(v)
+	}
+	return pairs, nil
+}
+
+// parseStateHTTPCodes parses a comma-separated list of state=code overrides
+// (e.g. "error=417,failure=417") for the state to HTTP code mapping
+func parseStateHTTPCodes(value string) (map[string]int, error) {
+	pairs, err := parseStatePairs("STATE_HTTP_CODES", value)
+	if err != nil || pairs == nil {
+		return nil, err
+	}
+
+	codes := make(map[string]int, len(pairs))
+	for state, rawCode := range pairs {
+		code, err := strconv.Atoi(rawCode)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("STATE_HTTP_CODES entry for %q must use an HTTP code between 100 and 599", state)
+		}
+		codes[state] = code
+	}
+	return codes, nil
+}
+
+// parseRepoTokens parses a comma-separated list of owner/repo=token
+// overrides. Keys are lowercased since Gitea matches names case-insensitively.
+func parseRepoTokens(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, repoToken, found := strings.Cut(strings.TrimSpace(pair), "=")
+		owner, repo, slash := strings.Cut(strings.TrimSpace(name), "/")
+		if !found || !slash || owner == "" || repo == "" || strings.TrimSpace(repoToken) == "" {
+			// Avoid echoing the entry, which contains the token
+			return nil, fmt.Errorf("REPO_TOKENS entries must be in owner/repo=token form, got one for %q", name)
+		}
+		tokens[strings.ToLower(owner+"/"+repo)] = strings.TrimSpace(repoToken)
+	}
+	return tokens, nil
+}
+
+// parseRepoAllowlist parses a comma-separated list of owner/repo entries,
+// where the repo may be "*" to allow every repository of an owner
+func parseRepoAllowlist(value string) ([]string, error) {
+	var allowlist []string
+	for _, entry := range splitList(value) {
+		owner, repo, found := strings.Cut(entry, "/")
+		if !found || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return nil, fmt.Errorf("REPO_ALLOWLIST entries must be in owner/repo or owner/* form, got %q", entry)
+		}
+		allowlist = append(allowlist, strings.ToLower(entry))
+	}
+	return allowlist, nil
+}
+
+// parseTrustedProxies parses a comma-separated list of proxy IP addresses
+// and CIDR ranges, where "unix" stands for connections over UNIX_SOCKET
+func parseTrustedProxies(value string) ([]string, error) {
+	var proxies []string
+	for _, entry := range splitList(value) {
+		if entry != unixProxy {
+			if _, err := parseProxyPrefix(entry); err != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES entries must be IP addresses, CIDR ranges or %q, got %q", unixProxy, entry)
+			}
+		}
+		proxies = append(proxies, entry)
+	}
+	return proxies, nil
+}
+
+// parseInjectDelay parses a comma-separated list of state=duration pairs
+// (e.g. "pending=2s") for the latency injected in test mode
+func parseInjectDelay(value string) (map[string]time.Duration, error) {
+	pairs, err := parseStatePairs("INJECT_DELAY", value)
+	if err != nil || pairs == nil {
+		return nil, err
+	}
+
+	delays := make(map[string]time.Duration, len(pairs))
+	for state, rawDelay := range pairs {
+		delay, err := time.ParseDuration(rawDelay)
+		if err != nil || delay <= 0 {
+			return nil, fmt.Errorf("INJECT_DELAY entry for %q must be a positive duration", state)
+		}
+		delays[state] = delay
+	}
+	return delays, nil
+}
+
+// redactedValue replaces secrets in diagnostic output
+const redactedValue = "[REDACTED]"
+
+// EffectiveConfig represents the non-secret settings reported by /config
+type EffectiveConfig struct {
+	GiteaURL               string            `json:"gitea_url"`
+	GiteaMirrors           []string          `json:"gitea_mirrors,omitempty"`
+	PublicGiteaURL         string            `json:"public_gitea_url,omitempty"`
+	DefaultOwner           string            `json:"default_owner,omitempty"`
+	Token                  string            `json:"token"`
+	UpstreamTimeout        string            `json:"upstream_timeout"`
+	RequestDeadline        string            `json:"request_deadline,omitempty"`
+	StatusDeadline         string            `json:"status_deadline,omitempty"`
+	BatchDeadline          string            `json:"batch_deadline,omitempty"`
+	OrgDeadline            string            `json:"org_deadline,omitempty"`
+	CacheTTL               string            `json:"cache_ttl,omitempty"`
+	BranchCacheTTL         string            `json:"branch_cache_ttl,omitempty"`
+	CacheCompress          bool              `json:"cache_compress"`
+	CacheBackend           string            `json:"cache_backend"`
+	RedisURL               string            `json:"redis_url,omitempty"`
+	PendingGrace           string            `json:"pending_grace,omitempty"`
+	MaxStatusAge           string            `json:"max_status_age,omitempty"`
+	StaleDowngrade         bool              `json:"stale_downgrade"`
+	OkIncludesWarning      bool              `json:"ok_includes_warning"`
+	NoCIState              string            `json:"no_ci_state,omitempty"`
+	PrimaryContext         string            `json:"primary_context,omitempty"`
+	CaseNormalization      string            `json:"case_normalization"`
+	DialTimeout            string            `json:"dial_timeout"`
+	MaxRedirects           int               `json:"max_redirects"`
+	UpstreamRetryAfter     string            `json:"upstream_retry_after"`
+	UpstreamRetries        int               `json:"upstream_retries"`
+	RetryBackoff           string            `json:"retry_backoff"`
+	RetryBudget            string            `json:"retry_budget,omitempty"`
+	BatchConcurrency       int               `json:"batch_concurrency"`
+	OrgConcurrency         int               `json:"org_concurrency"`
+	OrgTimeout             string            `json:"org_timeout,omitempty"`
+	PerHostConcurrency     int               `json:"per_host_concurrency,omitempty"`
+	MaxConcurrentLookups   int               `json:"max_concurrent_lookups,omitempty"`
+	MaxBatchSize           int               `json:"max_batch_size"`
+	MaxQueryLength         int               `json:"max_query_length"`
+	UpstreamErrorBodyLimit int               `json:"upstream_error_body_limit"`
+	MaxParamLength         int               `json:"max_param_length"`
+	MaxLookback            int               `json:"max_lookback"`
+	MinRepoInterval        string            `json:"min_repo_interval,omitempty"`
+	TraceBufferSize        int               `json:"trace_buffer_size"`
+	LogSampleRate          float64           `json:"log_sample_rate"`
+	DegradedLatency        string            `json:"degraded_latency"`
+	DegradedErrorRate      float64           `json:"degraded_error_rate"`
+	TestMode               bool              `json:"test_mode"`
+	InjectDelay            map[string]string `json:"inject_delay,omitempty"`
+	FieldNaming            string            `json:"field_naming"`
+	ErrorField             string            `json:"error_field"`
+	SummaryTemplate        string            `json:"summary_template"`
+	StateHTTPCodes         map[string]int    `json:"state_http_codes,omitempty"`
+	WarningHTTPCode        int               `json:"warning_http_code"`
+	StateColors            map[string]string `json:"state_colors,omitempty"`
+	StateSymbols           map[string]string `json:"state_symbols,omitempty"`
+	SSEKeepAlive           string            `json:"sse_keepalive"`
+	ShutdownTimeout        string            `json:"shutdown_timeout"`
+	EnableH2C              bool              `json:"enable_h2c"`
+	LongPollTimeout        string            `json:"long_poll_timeout"`
+	LongPollInterval       string            `json:"long_poll_interval"`
+	MaxSubscribers         int               `json:"max_subscribers"`
+	HistorySize            int               `json:"history_size"`
+	WebhookSecret          string            `json:"webhook_secret,omitempty"`
+	SigningSecret          string            `json:"signing_secret,omitempty"`
+	SigningHeader          string            `json:"signing_header"`
+	RepoTokens             map[string]string `json:"repo_tokens,omitempty"`
+	RepoAllowlist          []string          `json:"repo_allowlist,omitempty"`
+	TrustedProxies         []string          `json:"trusted_proxies,omitempty"`
+	DebugLogBodies         bool              `json:"debug_log_bodies"`
+	DebugCacheKeys         bool              `json:"debug_cache_keys"`
+	DebugRawUpstream       bool              `json:"debug_raw_upstream"`
+	MaintenanceMode        bool              `json:"maintenance_mode"`
+	Envelope               bool              `json:"envelope"`
+	StartupCheck           bool              `json:"startup_check"`
+	SuggestPermissions     bool              `json:"suggest_permissions"`
+	QuietErrors            bool              `json:"quiet_errors"`
+	AllowAnonymousFallback bool              `json:"allow_anonymous_fallback"`
+	APIKeyRequired         bool              `json:"api_key_required"`
+}
+
+// MarshalJSON encodes the configuration using the configured field naming style
+func (c EffectiveConfig) MarshalJSON() ([]byte, error) {
+	type plain EffectiveConfig
+	return marshalWithNaming(plain(c))
+}
+
+// redact hides a secret while still showing whether it was set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactRepoTokens lists the repositories with token overrides without revealing the tokens
+func redactRepoTokens(tokens map[string]string) map[string]string {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	redacted := make(map[string]string, len(tokens))
+	for name := range tokens {
+		redacted[name] = redactedValue
+	}
+	return redacted
+}
+
+// durationString formats an optional duration, leaving unset (zero) durations empty
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// durationStrings formats a map of durations for display
+func durationStrings(durations map[string]time.Duration) map[string]string {
+	if len(durations) == 0 {
+		return nil
+	}
+
+	formatted := make(map[string]string, len(durations))
+	for key, d := range durations {
+		formatted[key] = d.String()
+	}
+	return formatted
+}
+
+// effectiveConfig reports the resolved configuration with secrets redacted
+func effectiveConfig(cfg Config) EffectiveConfig {
+	return EffectiveConfig{
+		GiteaURL:               service.BaseURL,
+		GiteaMirrors:           service.Mirrors,
+		PublicGiteaURL:         cfg.PublicGiteaURL,
+		DefaultOwner:           cfg.DefaultOwner,
+		Token:                  redact(service.Token),
+		UpstreamTimeout:        upstreamTimeout.String(),
+		RequestDeadline:        durationString(cfg.RequestDeadline),
+		StatusDeadline:         durationString(cfg.StatusDeadline),
+		BatchDeadline:          durationString(cfg.BatchDeadline),
+		OrgDeadline:            durationString(cfg.OrgDeadline),
+		CacheTTL:               durationString(cfg.CacheTTL),
+		BranchCacheTTL:         durationString(cfg.BranchCacheTTL),
+		CacheCompress:          cfg.CacheCompress,
+		CacheBackend:           cfg.CacheBackend,
+		RedisURL:               redactRedisURL(cfg.RedisURL),
+		PendingGrace:           durationString(cfg.PendingGrace),
+		MaxStatusAge:           durationString(cfg.MaxStatusAge),
+		StaleDowngrade:         cfg.StaleDowngrade,
+		OkIncludesWarning:      cfg.OkIncludesWarning,
+		NoCIState:              cfg.NoCIState,
+		PrimaryContext:         cfg.PrimaryContext,
+		CaseNormalization:      cfg.CaseNormalization,
+		DialTimeout:            cfg.DialTimeout.String(),
+		MaxRedirects:           cfg.MaxRedirects,
+		UpstreamRetryAfter:     cfg.UpstreamRetryAfter.String(),
+		UpstreamRetries:        cfg.UpstreamRetries,
+		RetryBackoff:           cfg.RetryBackoff.String(),
+		RetryBudget:            durationString(cfg.RetryBudget),
+		BatchConcurrency:       cfg.BatchConcurrency,
+		OrgConcurrency:         cfg.OrgConcurrency,
+		OrgTimeout:             durationString(cfg.OrgTimeout),
+		PerHostConcurrency:     cfg.PerHostConcurrency,
+		MaxConcurrentLookups:   cfg.MaxConcurrentLookups,
+		MaxBatchSize:           cfg.MaxBatchSize,
+		MaxQueryLength:         cfg.MaxQueryLength,
+		UpstreamErrorBodyLimit: cfg.UpstreamErrorBodyLimit,
+		MaxParamLength:         cfg.MaxParamLength,
+		MaxLookback:            cfg.MaxLookback,
+		MinRepoInterval:        durationString(cfg.MinRepoInterval),
+		TraceBufferSize:        cfg.TraceBufferSize,
+		LogSampleRate:          cfg.LogSampleRate,
+		DegradedLatency:        cfg.DegradedLatency.String(),
+		DegradedErrorRate:      cfg.DegradedErrorRate,
+		TestMode:               cfg.TestMode,
+		InjectDelay:            durationStrings(cfg.InjectDelay),
+		FieldNaming:            cfg.FieldNaming,
+		ErrorField:             cfg.ErrorField,
+		SummaryTemplate:        cfg.SummaryTemplate,
+		StateHTTPCodes:         cfg.StateHTTPCodes,
+		WarningHTTPCode:        cfg.WarningHTTPCode,
+		StateColors:            cfg.StateColors,
+		StateSymbols:           cfg.StateSymbols,
+		SSEKeepAlive:           cfg.SSEKeepAlive.String(),
+		ShutdownTimeout:        cfg.ShutdownTimeout.String(),
+		EnableH2C:              cfg.EnableH2C,
+		LongPollTimeout:        cfg.LongPollTimeout.String(),
+		LongPollInterval:       cfg.LongPollInterval.String(),
+		MaxSubscribers:         cfg.MaxSubscribers,
+		HistorySize:            cfg.HistorySize,
+		WebhookSecret:          redact(cfg.WebhookSecret),
+		SigningSecret:          redact(cfg.SigningSecret),
+		SigningHeader:          cfg.SigningHeader,
+		RepoTokens:             redactRepoTokens(cfg.RepoTokens),
+		RepoAllowlist:          cfg.RepoAllowlist,
+		TrustedProxies:         cfg.TrustedProxies,
+		DebugLogBodies:         cfg.DebugLogBodies,
+		DebugCacheKeys:         cfg.DebugCacheKeys,
+		DebugRawUpstream:       cfg.DebugRawUpstream,
+		MaintenanceMode:        cfg.MaintenanceMode,
+		Envelope:               cfg.Envelope,
+		StartupCheck:           cfg.StartupCheck,
+		SuggestPermissions:     cfg.SuggestPermissions,
+		QuietErrors:            cfg.QuietErrors,
+		AllowAnonymousFallback: cfg.AllowAnonymousFallback,
+		APIKeyRequired:         cfg.APIKey != "",
+	}
+}
+
+// configHandler handles the /config endpoint
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !requireAPIKey(w, r) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, effectiveConfig(config))
+}
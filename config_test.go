@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(cfg, defaultConfig()) {
+		t.Errorf("Expected default config %+v, got %+v", defaultConfig(), cfg)
+	}
+}
+
+func TestLoadConfig_DialTimeout(t *testing.T) {
+	t.Setenv("DIAL_TIMEOUT", "750ms")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.DialTimeout != 750*time.Millisecond {
+		t.Errorf("Expected dial timeout 750ms, got %v", cfg.DialTimeout)
+	}
+}
+
+func TestLoadConfig_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"BATCH_CONCURRENCY", "zero"},
+		{"MAX_BATCH_SIZE", "-1"},
+		{"DIAL_TIMEOUT", "soon"},
+		{"DIAL_TIMEOUT", "0s"},
+		{"NO_CI_STATE", "bogus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			if _, err := loadConfig(); err == nil {
+				t.Errorf("Expected error for %s=%s, got nil", tt.name, tt.value)
+			}
+		})
+	}
+}
+
+func TestParseStateHTTPCodes(t *testing.T) {
+	codes, err := parseStateHTTPCodes("error=417, failure=409")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := map[string]int{"error": 417, "failure": 409}
+	if !reflect.DeepEqual(codes, expected) {
+		t.Errorf("Expected %v, got %v", expected, codes)
+	}
+
+	invalid := []string{
+		"error",
+		"error=abc",
+		"error=99",
+		"error=600",
+		"bogus=500",
+	}
+	for _, value := range invalid {
+		if _, err := parseStateHTTPCodes(value); err == nil {
+			t.Errorf("Expected error for %q, got nil", value)
+		}
+	}
+}
+
+func TestLoadConfig_StateColors(t *testing.T) {
+	t.Setenv("STATE_COLORS", "success=lime, pending=amber")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := map[string]string{"success": "lime", "pending": "amber"}
+	if !reflect.DeepEqual(cfg.StateColors, expected) {
+		t.Errorf("Expected %v, got %v", expected, cfg.StateColors)
+	}
+
+	t.Setenv("STATE_COLORS", "purple")
+	if _, err := loadConfig(); err == nil {
+		t.Error("Expected error for malformed STATE_COLORS, got nil")
+	}
+}
+
+func TestMapStateToHTTPCode_CustomPolicy(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+
+	t.Setenv("STATE_HTTP_CODES", "error=417,warning=418")
+	var err error
+	if config, err = loadConfig(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		state    string
+		expected int
+	}{
+		{"error", 417},
+		{"warning", 418},
+		{"failure", 417},
+		{"success", 200},
+		{"pending", 202},
+	}
+	for _, tt := range tests {
+		if result := mapStateToHTTPCode(tt.state); result != tt.expected {
+			t.Errorf("mapStateToHTTPCode(%s) = %d, want %d", tt.state, result, tt.expected)
+		}
+	}
+}
+
+func TestLoadConfig_InvalidStateHTTPCode(t *testing.T) {
+	t.Setenv("STATE_HTTP_CODES", "failure=700")
+	if _, err := loadConfig(); err == nil {
+		t.Error("Expected error for out-of-range code, got nil")
+	}
+}
+
+func TestParseRepoTokens(t *testing.T) {
+	tokens, err := parseRepoTokens("Acme/Secret=abc, other/repo = def")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"acme/secret": "abc", "other/repo": "def"}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected %v, got %v", expected, tokens)
+	}
+
+	for _, value := range []string{"acme=abc", "acme/secret", "/repo=abc", "acme/secret="} {
+		if _, err := parseRepoTokens(value); err == nil {
+			t.Errorf("Expected error for %q, got nil", value)
+		}
+	}
+}
+
+func TestConfigHandler_RedactsToken(t *testing.T) {
+	setMockService(t, nil)
+	originalConfig := config
+	config.APIKey = "service-key"
+	config.StateHTTPCodes = map[string]int{"error": 417}
+	config.RepoTokens = map[string]string{"acme/secret": "repo-token"}
+	defer func() { config = originalConfig }()
+
+	req := httptest.NewRequest("GET", "/config", nil)
+	req.Header.Set("X-API-Key", "service-key")
+	rr := httptest.NewRecorder()
+	configHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	body := rr.Body.String()
+	for _, secret := range []string{"test-token", "service-key", "repo-token"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("Expected secret %q to be redacted, got %s", secret, body)
+		}
+	}
+
+	var dump EffectiveConfig
+	if err := json.Unmarshal(rr.Body.Bytes(), &dump); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if dump.Token != redactedValue {
+		t.Errorf("Expected token '%s', got '%s'", redactedValue, dump.Token)
+	}
+	if dump.GiteaURL != "https://git.example.com" {
+		t.Errorf("Expected gitea_url 'https://git.example.com', got '%s'", dump.GiteaURL)
+	}
+	if dump.BatchConcurrency != config.BatchConcurrency || dump.DialTimeout != config.DialTimeout.String() {
+		t.Errorf("Expected resolved settings in dump, got %+v", dump)
+	}
+	if dump.RepoTokens["acme/secret"] != redactedValue {
+		t.Errorf("Expected repo token override to be listed redacted, got %v", dump.RepoTokens)
+	}
+	if dump.StateHTTPCodes["error"] != 417 || !dump.APIKeyRequired {
+		t.Errorf("Expected overrides and API key flag in dump, got %+v", dump)
+	}
+}
+
+func TestConfigHandler_RequiresAPIKey(t *testing.T) {
+	originalConfig := config
+	config.APIKey = "service-key"
+	defer func() { config = originalConfig }()
+
+	req := httptest.NewRequest("GET", "/config", nil)
+	rr := httptest.NewRecorder()
+	configHandler(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLoadConfig_WarningHTTPCode(t *testing.T) {
+	tests := []struct {
+		name         string
+		warningCode  string
+		stateCodes   string
+		expectedCode int
+		expectErr    bool
+	}{
+		{name: "default", expectedCode: http.StatusOK},
+		{name: "override", warningCode: "418", expectedCode: http.StatusTeapot},
+		{name: "state map wins", warningCode: "418", stateCodes: "warning=299", expectedCode: 299},
+		{name: "out of range", warningCode: "999", expectErr: true},
+		{name: "not a number", warningCode: "soft", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WARNING_HTTP_CODE", tt.warningCode)
+			t.Setenv("STATE_HTTP_CODES", tt.stateCodes)
+
+			cfg, err := loadConfig()
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			originalConfig := config
+			config = cfg
+			defer func() { config = originalConfig }()
+
+			if code := mapStateToHTTPCode("warning"); code != tt.expectedCode {
+				t.Errorf("mapStateToHTTPCode(warning) = %d, want %d", code, tt.expectedCode)
+			}
+			if code := mapStateToHTTPCode("success"); code != http.StatusOK {
+				t.Errorf("Expected success to stay 200, got %d", code)
+			}
+		})
+	}
+}
+
+func TestParseInjectDelay(t *testing.T) {
+	delays, err := parseInjectDelay("pending=2s, error=500ms")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if delays["pending"] != 2*time.Second || delays["error"] != 500*time.Millisecond {
+		t.Errorf("Unexpected delays: %v", delays)
+	}
+
+	for _, value := range []string{"pending=soon", "pending=-1s", "bogus=1s"} {
+		if _, err := parseInjectDelay(value); err == nil {
+			t.Errorf("Expected error for %q, got nil", value)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := parseTrustedProxies(" 10.0.0.0/8, 192.168.1.1,unix ")
+	if err != nil {
+		t.Fatalf("parseTrustedProxies() error = %v", err)
+	}
+	if len(proxies) != 3 || proxies[0] != "10.0.0.0/8" || proxies[2] != unixProxy {
+		t.Errorf("parseTrustedProxies() = %v", proxies)
+	}
+
+	for _, invalid := range []string{"proxy.internal", "10.0.0.0/99"} {
+		if _, err := parseTrustedProxies(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// idempotencyHeader names the client-supplied key that groups duplicate requests
+const idempotencyHeader = "Idempotency-Key"
+
+// flight is a lookup in progress that duplicate requests wait on
+type flight struct {
+	done     chan struct{}
+	response BuildStatusResponse
+	err      error
+}
+
+// flightGroup lets concurrent requests with the same key share one lookup
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// newFlightGroup creates a group with no lookups in progress
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: make(map[string]*flight)}
+}
+
+var statusFlights = newFlightGroup()
+
+// do runs fetch for key unless a lookup for key is already in progress, in
+// which case it waits for and shares that lookup's result, giving up when
+// ctx is done. The lookup runs under the context of the request that
+// started it, so when that client goes away the others, whose ctx is still
+// live, look up again themselves.
+func (g *flightGroup) do(ctx context.Context, key string, fetch func() (BuildStatusResponse, error)) (BuildStatusResponse, error) {
+	g.mu.Lock()
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return BuildStatusResponse{Error: fmt.Sprintf("Gave up waiting for the shared lookup: %v", ctx.Err())}, ctx.Err()
+		case <-f.done:
+		}
+		if errors.Is(f.err, context.Canceled) && ctx.Err() == nil {
+			return g.do(ctx, key, fetch)
+		}
+		return f.response, f.err
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.response, f.err = fetch()
+	return f.response, f.err
+}
+
+// dedupKey combines the client's Idempotency-Key with the requested
+// resource. Query parameters are sorted so reordered retries still match.
+// It returns "" when the client sent no key.
+func dedupKey(r *http.Request) string {
+	key := r.Header.Get(idempotencyHeader)
+	if key == "" {
+		return ""
+	}
+	return key + " " + r.URL.Path + "?" + r.URL.Query().Encode()
+}
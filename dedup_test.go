@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// duplicateJoinDelay gives a duplicate request time to join the lookup in
+// progress; one that did not would show up as an extra upstream call
+const duplicateJoinDelay = 50 * time.Millisecond
+
+func TestDedupKey(t *testing.T) {
+	req := httptest.NewRequest("GET", "/status?repo=myrepo&owner=myorg", nil)
+	if key := dedupKey(req); key != "" {
+		t.Errorf("Expected no key without an Idempotency-Key header, got %q", key)
+	}
+
+	req.Header.Set(idempotencyHeader, "abc")
+	reordered := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo", nil)
+	reordered.Header.Set(idempotencyHeader, "abc")
+	if dedupKey(req) != dedupKey(reordered) {
+		t.Errorf("Expected reordered queries to share a key, got %q and %q", dedupKey(req), dedupKey(reordered))
+	}
+
+	other := httptest.NewRequest("GET", "/status?owner=myorg&repo=other", nil)
+	other.Header.Set(idempotencyHeader, "abc")
+	if dedupKey(req) == dedupKey(other) {
+		t.Error("Expected different resources to get different keys")
+	}
+}
+
+func TestStatusHandler_IdempotencyKeySharesLookup(t *testing.T) {
+	var statusCalls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if statusCalls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	queries := []string{"owner=myorg&repo=myrepo&branches=main", "branches=main&repo=myrepo&owner=myorg"}
+	codes := make([]int, len(queries))
+	var wg sync.WaitGroup
+	send := func(i int) {
+		defer wg.Done()
+		req := httptest.NewRequest("GET", "/status?"+queries[i], nil)
+		req.Header.Set(idempotencyHeader, "retry-1")
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+		codes[i] = rr.Code
+	}
+
+	wg.Add(2)
+	go send(0)
+	<-started
+	go send(1)
+
+	time.Sleep(duplicateJoinDelay)
+	close(release)
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Request %d: expected status 200, got %d", i, code)
+		}
+	}
+	if calls := statusCalls.Load(); calls != 1 {
+		t.Errorf("Expected one shared upstream lookup, got %d", calls)
+	}
+}
+
+func TestStatusHandler_IdempotencyKeySurvivesLeaderCancel(t *testing.T) {
+	var statusCalls atomic.Int32
+	started := make(chan struct{})
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if statusCalls.Add(1) == 1 {
+			// The first lookup hangs until its client goes away
+			close(started)
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	target := "/status?owner=myorg&repo=myrepo&branches=main"
+	newRequest := func(ctx context.Context) *http.Request {
+		req := httptest.NewRequest("GET", target, nil).WithContext(ctx)
+		req.Header.Set(idempotencyHeader, "retry-2")
+		return req
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	defer cancelLeader()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		statusHandler(httptest.NewRecorder(), newRequest(leaderCtx))
+	}()
+	<-started
+
+	var dup *httptest.ResponseRecorder
+	go func() {
+		defer wg.Done()
+		dup = httptest.NewRecorder()
+		statusHandler(dup, newRequest(context.Background()))
+	}()
+	time.Sleep(duplicateJoinDelay)
+	cancelLeader()
+	wg.Wait()
+
+	if dup.Code != http.StatusOK {
+		t.Errorf("Expected the duplicate to look up again and get 200, got %d: %s", dup.Code, dup.Body.String())
+	}
+	if calls := statusCalls.Load(); calls != 2 {
+		t.Errorf("Expected the lookup to be retried once, got %d upstream calls", calls)
+	}
+}
+
+func TestFlightGroup_WaiterGivesUpWithItsContext(t *testing.T) {
+	g := newFlightGroup()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	go g.do(context.Background(), "key", func() (BuildStatusResponse, error) {
+		close(started)
+		<-release
+		return BuildStatusResponse{State: "success"}, nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.do(ctx, "key", func() (BuildStatusResponse, error) {
+			t.Error("Expected the waiter not to look up itself")
+			return BuildStatusResponse{}, nil
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the waiter's cancellation, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a cancelled waiter not to stay behind the lookup")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Upstream outcomes are judged over the most recent degradedWindowSize
+// calls, and not before degradedMinSamples have been seen
+const (
+	degradedWindowSize = 50
+	degradedMinSamples = 5
+)
+
+// upstreamOutcome records how a single upstream call went
+type upstreamOutcome struct {
+	latency time.Duration
+	failed  bool
+}
+
+// outcomeWindow keeps a sliding window of recent upstream outcomes
+type outcomeWindow struct {
+	mu       sync.Mutex
+	outcomes []upstreamOutcome
+	next     int
+}
+
+// newOutcomeWindow creates a window holding up to size outcomes
+func newOutcomeWindow(size int) *outcomeWindow {
+	return &outcomeWindow{outcomes: make([]upstreamOutcome, 0, size)}
+}
+
+var upstreamOutcomes = newOutcomeWindow(degradedWindowSize)
+
+// record adds an outcome, replacing the oldest once the window is full
+func (w *outcomeWindow) record(latency time.Duration, failed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	outcome := upstreamOutcome{latency: latency, failed: failed}
+	if len(w.outcomes) < cap(w.outcomes) {
+		w.outcomes = append(w.outcomes, outcome)
+		return
+	}
+	w.outcomes[w.next] = outcome
+	w.next = (w.next + 1) % len(w.outcomes)
+}
+
+// stats reports the number of outcomes, their mean latency and the share that failed
+func (w *outcomeWindow) stats() (count int, meanLatency time.Duration, errorRate float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.outcomes) == 0 {
+		return 0, 0, 0
+	}
+
+	var total time.Duration
+	failures := 0
+	for _, outcome := range w.outcomes {
+		total += outcome.latency
+		if outcome.failed {
+			failures++
+		}
+	}
+	count = len(w.outcomes)
+	return count, total / time.Duration(count), float64(failures) / float64(count)
+}
+
+// checkUpstreamPerformance reports whether recent upstream calls have been
+// slower than DEGRADED_LATENCY on average or failed more often than
+// DEGRADED_ERROR_RATE. Degradation is a warning, not a failure.
+func checkUpstreamPerformance() HealthCheck {
+	check := HealthCheck{Name: "upstream_performance", Status: healthOK}
+
+	count, meanLatency, errorRate := upstreamOutcomes.stats()
+	check.Detail = fmt.Sprintf("%d recent calls, mean latency %s, %.0f%% failed", count, meanLatency.Round(time.Millisecond), errorRate*100)
+	if count < degradedMinSamples {
+		return check
+	}
+	if meanLatency > config.DegradedLatency || errorRate > config.DegradedErrorRate {
+		check.Status = healthDegraded
+	}
+	return check
+}
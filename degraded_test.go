@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// resetUpstreamOutcomes gives the test an empty outcome window
+func resetUpstreamOutcomes(t *testing.T) {
+	t.Helper()
+
+	original := upstreamOutcomes
+	upstreamOutcomes = newOutcomeWindow(degradedWindowSize)
+	t.Cleanup(func() { upstreamOutcomes = original })
+}
+
+func TestOutcomeWindow_SlidesOverOldOutcomes(t *testing.T) {
+	w := newOutcomeWindow(4)
+	for i := 0; i < 4; i++ {
+		w.record(time.Second, true)
+	}
+	for i := 0; i < 4; i++ {
+		w.record(100*time.Millisecond, false)
+	}
+
+	count, meanLatency, errorRate := w.stats()
+	if count != 4 || meanLatency != 100*time.Millisecond || errorRate != 0 {
+		t.Errorf("Expected only the 4 newest outcomes to count, got %d calls, %s, %.2f", count, meanLatency, errorRate)
+	}
+}
+
+func TestCheckUpstreamPerformance(t *testing.T) {
+	originalConfig := config
+	config.DegradedLatency = 500 * time.Millisecond
+	config.DegradedErrorRate = 0.25
+	defer func() { config = originalConfig }()
+
+	tests := []struct {
+		name     string
+		latency  time.Duration
+		failures int
+		calls    int
+		expected string
+	}{
+		{"healthy", 50 * time.Millisecond, 0, 20, healthOK},
+		{"slow", time.Second, 0, 20, healthDegraded},
+		{"error-prone", 50 * time.Millisecond, 10, 20, healthDegraded},
+		{"errors within threshold", 50 * time.Millisecond, 5, 20, healthOK},
+		{"too few samples", 5 * time.Second, 2, 2, healthOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetUpstreamOutcomes(t)
+			for i := 0; i < tt.calls; i++ {
+				upstreamOutcomes.record(tt.latency, i < tt.failures)
+			}
+
+			if check := checkUpstreamPerformance(); check.Status != tt.expected {
+				t.Errorf("Expected status %q, got %+v", tt.expected, check)
+			}
+		})
+	}
+}
+
+func TestHealthHandler_DegradedFlagFlips(t *testing.T) {
+	resetUpstreamOutcomes(t)
+	originalConfig := config
+	config.DegradedLatency = 500 * time.Millisecond
+	defer func() { config = originalConfig }()
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"version": "1.21.0"}`), nil
+	})
+
+	degraded := func() bool {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/health?detail=true", nil)
+		rr := httptest.NewRecorder()
+		healthHandler(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected degraded health to still answer 200, got %d", rr.Code)
+		}
+		var report HealthReport
+		if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Could not parse response JSON: %v", err)
+		}
+		return report.Degraded
+	}
+
+	for i := 0; i < degradedWindowSize; i++ {
+		upstreamOutcomes.record(2*time.Second, false)
+	}
+	if !degraded() {
+		t.Error("Expected slow upstream calls to flag the service as degraded")
+	}
+
+	for i := 0; i < degradedWindowSize; i++ {
+		upstreamOutcomes.record(10*time.Millisecond, false)
+	}
+	if degraded() {
+		t.Error("Expected the flag to clear once recent calls are fast again")
+	}
+}
+
+func TestGetJSONOnce_RecordsOutcomes(t *testing.T) {
+	resetUpstreamOutcomes(t)
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/api/v1/version" {
+			return createHTTPResponse(200, `{"version": "1.21.0"}`), nil
+		}
+		return createHTTPResponse(502, `bad gateway`), nil
+	})
+
+	service.GetVersion()
+	service.GetRepository("testowner", "testrepo")
+
+	if count, _, errorRate := upstreamOutcomes.stats(); count != 2 || errorRate != 0.5 {
+		t.Errorf("Expected 2 recorded calls with one failure, got %d calls and %.2f failed", count, errorRate)
+	}
+}
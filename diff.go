@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// BranchState represents the build state of one side of a diff
+type BranchState struct {
+	Branch string `json:"branch"`
+	State  string `json:"state"`
+	Symbol string `json:"symbol"`
+}
+
+// MarshalJSON encodes the branch state using the configured field naming style
+func (b BranchState) MarshalJSON() ([]byte, error) {
+	type plain BranchState
+	return marshalWithNaming(plain(b))
+}
+
+// DiffResponse compares the build state of two branches
+type DiffResponse struct {
+	Owner      string      `json:"owner"`
+	Repository string      `json:"repository"`
+	Base       BranchState `json:"base"`
+	Head       BranchState `json:"head"`
+	Same       bool        `json:"same"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the comparison using the configured field naming style
+func (d DiffResponse) MarshalJSON() ([]byte, error) {
+	type plain DiffResponse
+	return marshalWithNaming(plain(d))
+}
+
+// resolveDiff fetches the status of both branches concurrently and reports
+// whether their states match
+func resolveDiff(ctx context.Context, owner, repo, base, head string) (DiffResponse, error) {
+	branches := []string{base, head}
+	results := make([]BuildStatusResponse, len(branches))
+	errs := make([]error, len(branches))
+
+	var wg sync.WaitGroup
+	for i, branch := range branches {
+		wg.Add(1)
+		go func(i int, branch string) {
+			defer wg.Done()
+			results[i], errs[i] = resolveBuildStatus(ctx, owner, repo, branch, StatusOptions{})
+		}(i, branch)
+	}
+	wg.Wait()
+
+	response := DiffResponse{Owner: owner, Repository: repo}
+	for i, err := range errs {
+		if err != nil {
+			response.Error = fmt.Sprintf("Branch '%s': %s", branches[i], results[i].Error)
+			return response, err
+		}
+	}
+
+	response.Base = BranchState{Branch: base, State: results[0].State, Symbol: results[0].Symbol}
+	response.Head = BranchState{Branch: head, State: results[1].State, Symbol: results[1].Symbol}
+	response.Same = response.Base.State == response.Head.State
+	return response, nil
+}
+
+// diffHandler handles the /diff endpoint
+func diffHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	owner := r.URL.Query().Get("owner")
+	if owner == "" {
+		owner = config.DefaultOwner
+	}
+	repo := r.URL.Query().Get("repo")
+	base := r.URL.Query().Get("base")
+	head := r.URL.Query().Get("head")
+
+	if owner == "" || repo == "" || base == "" || head == "" {
+		writeJSON(w, http.StatusBadRequest, DiffResponse{
+			Error: "The 'owner', 'repo', 'base' and 'head' query parameters are required",
+		})
+		return
+	}
+
+	if msg := validateParamLengths(owner, repo, base, head); msg != "" {
+		writeJSON(w, http.StatusBadRequest, DiffResponse{Error: msg})
+		return
+	}
+
+	if !repoAllowed(owner, repo) {
+		writeJSON(w, http.StatusForbidden, DiffResponse{Error: notAllowedMessage(owner, repo)})
+		return
+	}
+
+	response, err := resolveDiff(r.Context(), owner, repo, base, head)
+	if err != nil {
+		response.Error = quietError(response.Error, err)
+		writeJSON(w, errorHTTPCode(w, err), response)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
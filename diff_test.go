@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiffHandler(t *testing.T) {
+	setMockService(t, branchStatesMock(map[string]string{
+		"main":    "success",
+		"release": "failure",
+		"staging": "success",
+	}))
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expected     DiffResponse
+	}{
+		{
+			name:         "same state",
+			query:        "owner=testowner&repo=testrepo&base=main&head=staging",
+			expectedCode: http.StatusOK,
+			expected: DiffResponse{
+				Owner:      "testowner",
+				Repository: "testrepo",
+				Base:       BranchState{Branch: "main", State: "success", Symbol: "✓"},
+				Head:       BranchState{Branch: "staging", State: "success", Symbol: "✓"},
+				Same:       true,
+			},
+		},
+		{
+			name:         "differing state",
+			query:        "owner=testowner&repo=testrepo&base=main&head=release",
+			expectedCode: http.StatusOK,
+			expected: DiffResponse{
+				Owner:      "testowner",
+				Repository: "testrepo",
+				Base:       BranchState{Branch: "main", State: "success", Symbol: "✓"},
+				Head:       BranchState{Branch: "release", State: "failure", Symbol: "✗"},
+				Same:       false,
+			},
+		},
+		{
+			name:         "missing head",
+			query:        "owner=testowner&repo=testrepo&base=main",
+			expectedCode: http.StatusBadRequest,
+			expected:     DiffResponse{Error: "The 'owner', 'repo', 'base' and 'head' query parameters are required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/diff?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			diffHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+
+			var response DiffResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, response)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Symbol encodings selected with the symbol_encoding query parameter
+const (
+	symbolEncodingUnicode = "unicode"
+	symbolEncodingEntity  = "entity"
+)
+
+// symbolEntity rewrites every non-ASCII character of symbol as an HTML
+// numeric entity, e.g. ✓ becomes &#10003;
+func symbolEntity(symbol string) string {
+	var b strings.Builder
+	for _, r := range symbol {
+		if r < 128 {
+			b.WriteRune(r)
+			continue
+		}
+		fmt.Fprintf(&b, "&#%d;", r)
+	}
+	return b.String()
+}
+
+// encodeSymbolEntities switches every symbol in response to HTML entities
+// for clients embedding them in HTML, where raw symbols render inconsistently
+func encodeSymbolEntities(response *BuildStatusResponse) {
+	response.Symbol = symbolEntity(response.Symbol)
+	for i := range response.Matrix {
+		response.Matrix[i].Symbol = symbolEntity(response.Matrix[i].Symbol)
+	}
+}
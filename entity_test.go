@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSymbolEntity(t *testing.T) {
+	tests := []struct {
+		state    string
+		expected string
+	}{
+		{"success", "&#10003;"},
+		{"failure", "&#10007;"},
+		{"error", "&#10007;"},
+		{"pending", "&#9679;"},
+		{"warning", "&#9888;"},
+		{"skipped", "&#8856;"},
+		{"unknown", "&#9675;"},
+		{"unrecognized", "?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			if got := symbolEntity(mapStateToSymbol(tt.state)); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_SymbolEncoding(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [{"context": "ci/build", "status": "success"}], "total_count": 1}`), nil
+	})
+
+	tests := []struct {
+		query          string
+		expectedCode   int
+		expectedSymbol string
+	}{
+		{"", http.StatusOK, "✓"},
+		{"&symbol_encoding=unicode", http.StatusOK, "✓"},
+		{"&symbol_encoding=entity", http.StatusOK, "&#10003;"},
+		{"&symbol_encoding=entity&detail=true&format=matrix", http.StatusOK, "&#10003;"},
+		{"&symbol_encoding=bogus", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.Symbol != tt.expectedSymbol {
+				t.Errorf("Expected symbol %q, got %q", tt.expectedSymbol, response.Symbol)
+			}
+			for _, cell := range response.Matrix {
+				if cell.Symbol != tt.expectedSymbol {
+					t.Errorf("Expected matrix symbol %q, got %q", tt.expectedSymbol, cell.Symbol)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// maxRequestIDLength bounds request IDs taken from X-Request-ID, so a
+// client cannot echo arbitrary amounts of data through the envelope
+const maxRequestIDLength = 128
+
+// Envelope wraps a JSON response body when ENVELOPE is enabled
+type Envelope struct {
+	Data any          `json:"data"`
+	Meta EnvelopeMeta `json:"meta"`
+}
+
+// MarshalJSON encodes the envelope using the configured field naming style
+func (e Envelope) MarshalJSON() ([]byte, error) {
+	type plain Envelope
+	return marshalWithNaming(plain(e))
+}
+
+// EnvelopeMeta describes the request an enveloped response answers
+type EnvelopeMeta struct {
+	RequestID string    `json:"request_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MarshalJSON encodes the metadata using the configured field naming style
+func (m EnvelopeMeta) MarshalJSON() ([]byte, error) {
+	type plain EnvelopeMeta
+	return marshalWithNaming(plain(m))
+}
+
+// envelopeWriter marks a response whose JSON body is wrapped in an
+// envelope, carrying the request ID to report in it
+type envelopeWriter struct {
+	http.ResponseWriter
+	requestID string
+}
+
+// Flush passes flushes through for streaming responses
+func (e envelopeWriter) Flush() {
+	if flusher, ok := e.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (e envelopeWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// withEnvelope marks every response for wrapping when ENVELOPE is enabled.
+// The request ID is taken from X-Request-ID when a proxy set one, and
+// generated otherwise; either way it is echoed in the response header.
+func withEnvelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !config.Envelope {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		next.ServeHTTP(envelopeWriter{ResponseWriter: w, requestID: requestID}, r)
+	})
+}
+
+// newRequestID generates a random identifier for a request
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// enveloped wraps v in an envelope when the response was marked for one,
+// looking through the other writers that wrap it, and returns v unchanged
+// otherwise
+func enveloped(w http.ResponseWriter, v any) any {
+	for {
+		switch wrapped := w.(type) {
+		case envelopeWriter:
+			return Envelope{
+				Data: v,
+				Meta: EnvelopeMeta{RequestID: wrapped.requestID, Timestamp: time.Now().UTC()},
+			}
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = wrapped.Unwrap()
+		default:
+			return v
+		}
+	}
+}
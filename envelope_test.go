@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envelopeHandler serves /status through the middleware that shapes its body
+func envelopeHandler() http.Handler {
+	return withEnvelope(prettyJSON(http.HandlerFunc(statusHandler)))
+}
+
+func TestWithEnvelope_FlatByDefault(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+	rr := httptest.NewRecorder()
+	envelopeHandler().ServeHTTP(rr, req)
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if _, ok := body["state"]; !ok {
+		t.Errorf("Expected a flat response, got %s", rr.Body.String())
+	}
+	if _, ok := body["meta"]; ok {
+		t.Errorf("Expected no envelope by default, got %s", rr.Body.String())
+	}
+	if id := rr.Header().Get("X-Request-ID"); id != "" {
+		t.Errorf("Expected no request ID header by default, got %q", id)
+	}
+}
+
+func TestWithEnvelope_Wrapped(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.Envelope = true
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	tests := []struct {
+		name      string
+		target    string
+		requestID string
+		code      int
+		state     string
+		errorPart string
+	}{
+		{"success", "/status?owner=myorg&repo=myrepo&branches=main", "", http.StatusOK, "success", ""},
+		{"incoming request ID", "/status?owner=myorg&repo=myrepo&branches=main&pretty=true", "abc-123", http.StatusOK, "success", ""},
+		{"oversized request ID", "/status?owner=myorg&repo=myrepo&branches=main", strings.Repeat("x", maxRequestIDLength+1), http.StatusOK, "success", ""},
+		{"error", "/status?owner=myorg", "", http.StatusBadRequest, "", "repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			rr := httptest.NewRecorder()
+			before := time.Now().UTC().Add(-time.Second)
+			envelopeHandler().ServeHTTP(rr, req)
+
+			if rr.Code != tt.code {
+				t.Fatalf("Expected status %d, got %d: %s", tt.code, rr.Code, rr.Body.String())
+			}
+			var response struct {
+				Data BuildStatusResponse `json:"data"`
+				Meta EnvelopeMeta        `json:"meta"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.Data.State != tt.state || !strings.Contains(response.Data.Error, tt.errorPart) {
+				t.Errorf("Expected the response under data, got %s", rr.Body.String())
+			}
+
+			if response.Meta.RequestID == "" {
+				t.Error("Expected a request ID in meta")
+			}
+			if tt.requestID != "" && len(tt.requestID) <= maxRequestIDLength && response.Meta.RequestID != tt.requestID {
+				t.Errorf("Expected the incoming request ID %q, got %q", tt.requestID, response.Meta.RequestID)
+			}
+			if len(tt.requestID) > maxRequestIDLength && response.Meta.RequestID == tt.requestID {
+				t.Error("Expected an oversized request ID to be replaced")
+			}
+			if header := rr.Header().Get("X-Request-ID"); header != response.Meta.RequestID {
+				t.Errorf("Expected header X-Request-ID %q, got %q", response.Meta.RequestID, header)
+			}
+			if response.Meta.Timestamp.Before(before) {
+				t.Errorf("Expected a current timestamp, got %v", response.Meta.Timestamp)
+			}
+		})
+	}
+}
+
+func TestWithEnvelope_JSONP(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.Envelope = true
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main&callback=cb", nil)
+	rr := httptest.NewRecorder()
+	envelopeHandler().ServeHTTP(rr, req)
+
+	body := rr.Body.String()
+	if !strings.HasPrefix(body, `/**/cb({"data":{`) || !strings.Contains(body, `"meta":{"request_id":`) {
+		t.Errorf("Expected an enveloped JSONP payload, got %s", body)
+	}
+}
+
+func TestEnvelopeMeta_FieldNaming(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.FieldNaming = namingCamel
+
+	encoded, err := json.Marshal(EnvelopeMeta{RequestID: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `"requestId":"abc"`) {
+		t.Errorf("Expected camelCase keys, got %s", encoded)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// UpstreamError describes a non-200 response from the Gitea API
+type UpstreamError struct {
+	Operation  string
+	StatusCode int
+	Body       string
+	// NonJSON flags a Body cleaned up by cleanUpstreamBody
+	NonJSON bool
+}
+
+func (e *UpstreamError) Error() string {
+	if e.NonJSON {
+		return fmt.Sprintf("failed to %s: %d - non-JSON response: %s", e.Operation, e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("failed to %s: %d - %s", e.Operation, e.StatusCode, e.Body)
+}
+
+var (
+	htmlBlockPattern = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// cleanUpstreamBody readies an upstream error body for error messages.
+// JSON bodies are kept as sent. Anything else, typically an HTML error page
+// from a proxy in front of Gitea, has its markup stripped, is collapsed onto
+// one line and is truncated to UPSTREAM_ERROR_BODY_LIMIT characters. The
+// second result reports whether the body was not JSON.
+func cleanUpstreamBody(body []byte) (string, bool) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || json.Valid(body) {
+		return string(body), false
+	}
+
+	text := htmlBlockPattern.ReplaceAllString(string(body), " ")
+	text = htmlTagPattern.ReplaceAllString(text, " ")
+	text = strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+	if runes := []rune(text); len(runes) > config.UpstreamErrorBodyLimit {
+		text = string(runes[:config.UpstreamErrorBodyLimit]) + "…"
+	}
+	return text, true
+}
+
+// retryableFlag reports for an error response whether repeating the request
+// may succeed: Gitea being unreachable, slow, overloaded or failing. Not
+// found, permission and validation errors, and nil errors from requests
+// rejected before any lookup, fail the same way again.
+func retryableFlag(err error) *bool {
+	retryable := isRetryable(err) || isUpstreamTimeout(err)
+	return &retryable
+}
+
+// retryableCode reports for a request rejected with code before any
+// lookup whether repeating it may succeed. Rejected requests fail the same
+// way again unless throttled or turned away for lack of capacity.
+func retryableCode(code int) *bool {
+	retryable := code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+	return &retryable
+}
+
+// isUpstreamStatus reports whether err is an UpstreamError with the given status code
+func isUpstreamStatus(err error, code int) bool {
+	var upstreamErr *UpstreamError
+	return errors.As(err, &upstreamErr) && upstreamErr.StatusCode == code
+}
+
+// isUpstreamUnavailable reports whether err means Gitea could not be
+// reached at all, as opposed to Gitea answering with an error
+func isUpstreamUnavailable(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isUpstreamTimeout reports whether err means an upstream call ran out of
+// time, either its own timeout or the request's REQUEST_DEADLINE
+func isUpstreamTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// clientGone reports whether err came from the client cancelling r, in
+// which case there is nobody left to respond to
+func clientGone(r *http.Request, err error) bool {
+	return errors.Is(err, context.Canceled) && errors.Is(r.Context().Err(), context.Canceled)
+}
+
+// quietError returns the error message to show clients. With QUIET_ERRORS
+// set, upstream failures are replaced by a generic message for their class
+// so upstream bodies and hostnames are not exposed, and the full message is
+// logged instead. The service's own lookup errors are always shown as is.
+func quietError(message string, err error) string {
+	if !config.QuietErrors || errors.Is(err, errNoCommitMatch) || errors.Is(err, errAmbiguousCommitMatch) {
+		return message
+	}
+
+	log.Printf("Upstream error (hidden from client): %s", message)
+
+	var upstreamErr *UpstreamError
+	switch {
+	case isUpstreamUnavailable(err):
+		return "Gitea is unavailable"
+	case isUpstreamTimeout(err):
+		return "Gitea did not respond in time"
+	case isUpstreamStatus(err, http.StatusNotFound):
+		return "Repository or ref not found"
+	case isUpstreamStatus(err, http.StatusUnauthorized), isUpstreamStatus(err, http.StatusForbidden):
+		return "Gitea denied access to the repository"
+	case errors.As(err, &upstreamErr):
+		return fmt.Sprintf("Gitea responded with status %d", upstreamErr.StatusCode)
+	}
+	return "Failed to fetch build status from Gitea"
+}
+
+// permissionHint is appended to not-found errors when SUGGEST_PERMISSIONS is set
+const permissionHint = " (the repository may be private or the token may lack access to it)"
+
+// withPermissionHint returns message with permissionHint appended when err is
+// a Gitea 404 and hints are enabled. Gitea answers 404 rather than 403 for
+// private repositories a token cannot see.
+func withPermissionHint(message string, err error) string {
+	if config.SuggestPermissions && isUpstreamStatus(err, http.StatusNotFound) {
+		return message + permissionHint
+	}
+	return message
+}
+
+// errorHTTPCode picks the response code for a failed lookup. When Gitea is
+// unreachable it answers 503 with a Retry-After hint so clients back off,
+// and when Gitea is too slow it answers 504
+// instead of retrying immediately.
+func errorHTTPCode(w http.ResponseWriter, err error) int {
+	switch {
+	case isUpstreamUnavailable(err):
+		w.Header().Set("Retry-After", strconv.Itoa(int(config.UpstreamRetryAfter.Seconds())))
+		return http.StatusServiceUnavailable
+	case isUpstreamTimeout(err):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, errNoCommitMatch):
+		return http.StatusNotFound
+	case errors.Is(err, errAmbiguousCommitMatch):
+		return http.StatusConflict
+	case errors.Is(err, errRedirectLoop):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
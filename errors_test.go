@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// connectionRefused builds the error the HTTP client returns when nothing listens upstream
+func connectionRefused(rawURL string) error {
+	return &url.Error{
+		Op:  "Get",
+		URL: rawURL,
+		Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+	}
+}
+
+func TestIsUpstreamUnavailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"connection refused", connectionRefused("https://git.example.com"), true},
+		{"dial failure", &net.OpError{Op: "dial", Err: fmt.Errorf("no route to host")}, true},
+		{"read failure", &net.OpError{Op: "read", Err: fmt.Errorf("reset")}, false},
+		{"upstream error status", fmt.Errorf("failed to get commit status: 500 - oops"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := isUpstreamUnavailable(tt.err); result != tt.expected {
+				t.Errorf("isUpstreamUnavailable() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_UpstreamUnavailable(t *testing.T) {
+	originalConfig := config
+	config.UpstreamRetryAfter = 7 * time.Second
+	defer func() { config = originalConfig }()
+
+	// Point the service at a server that has already shut down
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	originalService := service
+	service = &GiteaService{
+		BaseURL:    server.URL,
+		Token:      "test-token",
+		HTTPClient: newHTTPClient(config),
+	}
+	defer func() { service = originalService }()
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "7" {
+		t.Errorf("Expected Retry-After '7', got '%s'", retryAfter)
+	}
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if response.Error == "" {
+		t.Error("Expected error message in response")
+	}
+}
+
+func TestStatusHandler_UpstreamErrorStaysInternal(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(502, `{"message": "Bad Gateway"}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusInternalServerError)
+	}
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "" {
+		t.Errorf("Expected no Retry-After header, got '%s'", retryAfter)
+	}
+}
+
+func TestStatusHandler_PermissionHint(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(404, `{"message": "The target couldn't be found."}`), nil
+	})
+
+	for _, enabled := range []bool{false, true} {
+		originalConfig := config
+		config.SuggestPermissions = enabled
+
+		req := httptest.NewRequest("GET", "/status?owner=testowner&repo=private", nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+		config = originalConfig
+
+		var response BuildStatusResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Could not parse response JSON: %v", err)
+		}
+		if !strings.Contains(response.Error, "404") {
+			t.Errorf("Expected the upstream 404 in the error, got %q", response.Error)
+		}
+		if hinted := strings.Contains(response.Error, permissionHint); hinted != enabled {
+			t.Errorf("With SUGGEST_PERMISSIONS=%v expected hint=%v, got %q", enabled, enabled, response.Error)
+		}
+	}
+}
+
+func TestQuietError(t *testing.T) {
+	originalConfig := config
+	config.QuietErrors = true
+	defer func() { config = originalConfig }()
+
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"unavailable", connectionRefused("https://gitea.internal.corp/api/v1/version"), "Gitea is unavailable"},
+		{"not found", &UpstreamError{Operation: "get commit status", StatusCode: 404, Body: "not found"}, "Repository or ref not found"},
+		{"forbidden", &UpstreamError{Operation: "get commit status", StatusCode: 403, Body: "token lacks scope"}, "Gitea denied access to the repository"},
+		{"server error", &UpstreamError{Operation: "get commit status", StatusCode: 502, Body: "bad gateway from gitea-2.internal.corp"}, "Gitea responded with status 502"},
+		{"timeout", &url.Error{Op: "Get", URL: "https://gitea.internal.corp/api/v1/version", Err: context.DeadlineExceeded}, "Gitea did not respond in time"},
+		{"own lookup error", errNoCommitMatch, "original message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := quietError("original message", tt.err); result != tt.expected {
+				t.Errorf("quietError() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_QuietErrors(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(502, `upstream gitea-2.internal.corp timed out`), nil
+	})
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	for _, quiet := range []bool{false, true} {
+		t.Run(fmt.Sprintf("quiet=%v", quiet), func(t *testing.T) {
+			originalConfig := config
+			config.QuietErrors = quiet
+			defer func() { config = originalConfig }()
+			logs.Reset()
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			leaked := strings.Contains(response.Error, "internal.corp")
+			if quiet && (leaked || response.Error != "Gitea responded with status 502") {
+				t.Errorf("Expected a generic error, got %q", response.Error)
+			}
+			if !quiet && !leaked {
+				t.Errorf("Expected the detailed error by default, got %q", response.Error)
+			}
+			if quiet && !strings.Contains(logs.String(), "gitea-2.internal.corp timed out") {
+				t.Errorf("Expected the full error to be logged, got %q", logs.String())
+			}
+		})
+	}
+}
+
+func TestStatusHandler_UpstreamTimeout(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return nil, &url.Error{Op: "Get", URL: req.URL.String(), Err: context.DeadlineExceeded}
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", rr.Code)
+	}
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if response.Error == "" {
+		t.Error("Expected an error message")
+	}
+}
+
+func TestStatusHandler_ClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		// The client hangs up while the upstream call is in flight
+		cancel()
+		return nil, &url.Error{Op: "Get", URL: req.URL.String(), Err: context.Canceled}
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Body.Len() != 0 {
+		t.Errorf("Expected no response for a client that went away, got %s", rr.Body.String())
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "" {
+		t.Errorf("Expected no response headers to be written, got Content-Type %s", contentType)
+	}
+}
+
+func TestStatusHandler_NonJSONUpstreamErrorBody(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html><head><title>502 Bad Gateway</title><style>body { font-family: sans-serif; }</style></head>
+<body><h1>502 Bad Gateway</h1><p>The upstream server &amp; its replicas are unavailable.</p>
+<script>console.log("retry")</script><hr><center>nginx</center></body></html>`
+
+	tests := []struct {
+		name     string
+		body     string
+		limit    int
+		expected string
+	}{
+		{
+			"html page is stripped and flagged",
+			page, 200,
+			"Branch 'main': Failed to get commit status: failed to get commit status: 502 - non-JSON response: 502 Bad Gateway 502 Bad Gateway The upstream server & its replicas are unavailable. nginx",
+		},
+		{
+			"long bodies are truncated",
+			page, 15,
+			"Branch 'main': Failed to get commit status: failed to get commit status: 502 - non-JSON response: 502 Bad Gateway…",
+		},
+		{
+			"json bodies are kept as sent",
+			`{"message": "upstream unavailable"}`, 15,
+			`Branch 'main': Failed to get commit status: failed to get commit status: 502 - {"message": "upstream unavailable"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.UpstreamErrorBodyLimit = tt.limit
+			defer func() { config = originalConfig }()
+
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(http.StatusBadGateway, tt.body), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.Error != tt.expected {
+				t.Errorf("Expected error %q, got %q", tt.expected, response.Error)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_Retryable(t *testing.T) {
+	tests := []struct {
+		name              string
+		query             string
+		upstream          func(req *http.Request) (*http.Response, error)
+		expectedRetryable bool
+	}{
+		{
+			"connection refused", "owner=myorg&repo=myrepo&branches=main",
+			func(req *http.Request) (*http.Response, error) { return nil, connectionRefused(req.URL.String()) },
+			true,
+		},
+		{
+			"timeout", "owner=myorg&repo=myrepo&branches=main",
+			func(req *http.Request) (*http.Response, error) {
+				return nil, &url.Error{Op: "Get", URL: req.URL.String(), Err: context.DeadlineExceeded}
+			},
+			true,
+		},
+		{
+			"server error", "owner=myorg&repo=myrepo&branches=main",
+			func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(502, `{"message": "Bad Gateway"}`), nil
+			},
+			true,
+		},
+		{
+			"not found", "owner=myorg&repo=myrepo&ref=missing",
+			func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+			},
+			false,
+		},
+		{
+			"unauthorized", "owner=myorg&repo=myrepo&branches=main",
+			func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(401, `{"message": "token is required"}`), nil
+			},
+			false,
+		},
+		{
+			"connection refused by id", "id=5",
+			func(req *http.Request) (*http.Response, error) { return nil, connectionRefused(req.URL.String()) },
+			true,
+		},
+		{
+			"unknown id", "id=5",
+			func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+			},
+			false,
+		},
+		{
+			"invalid request", "owner=myorg&repo=myrepo&ref_type=label&ref=main",
+			nil,
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				if tt.upstream == nil {
+					t.Fatal("Expected no upstream call")
+				}
+				return tt.upstream(req)
+			})
+
+			req := httptest.NewRequest("GET", "/status?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.Error == "" {
+				t.Fatalf("Expected an error response, got %s", rr.Body.String())
+			}
+			if response.Retryable == nil {
+				t.Fatal("Expected the retryable flag to be set")
+			}
+			if *response.Retryable != tt.expectedRetryable {
+				t.Errorf("Expected retryable %v, got %v", tt.expectedRetryable, *response.Retryable)
+			}
+		})
+	}
+}
+
+func TestRetryableCode(t *testing.T) {
+	tests := []struct {
+		code     int
+		expected bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusForbidden, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		if got := *retryableCode(tt.code); got != tt.expected {
+			t.Errorf("retryableCode(%d) = %v, want %v", tt.code, got, tt.expected)
+		}
+
+		// writeError flags rejections the same way
+		rr := httptest.NewRecorder()
+		writeError(rr, tt.code, "rejected")
+		var response BuildStatusResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Could not parse response JSON: %v", err)
+		}
+		if response.Retryable == nil || *response.Retryable != tt.expected {
+			t.Errorf("writeError(%d): expected retryable %v, got %s", tt.code, tt.expected, rr.Body.String())
+		}
+	}
+}
+
+func TestStatusHandler_RetryableOmittedOnSuccess(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if strings.Contains(rr.Body.String(), "retryable") {
+		t.Errorf("Expected no retryable flag on success, got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind before events are dropped
+const subscriberBuffer = 16
+
+// eventBroker fans status events out to subscribers of each repository
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan StatusEvent]struct{}
+	closed      bool
+}
+
+// newEventBroker creates a broker with no subscribers
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: make(map[string]map[chan StatusEvent]struct{})}
+}
+
+var broker = newEventBroker()
+
+// activeSubscribers counts open /events connections against MAX_SUBSCRIBERS
+var activeSubscribers atomic.Int64
+
+// repoKey identifies a repository for event routing and lookup turns
+func repoKey(owner, repo string) string {
+	return canonicalName(owner) + "/" + canonicalName(repo)
+}
+
+// subscribe registers interest in a repository's events, returning the
+// event channel and a function that removes the subscription. The channel
+// is closed when the broker shuts down.
+func (b *eventBroker) subscribe(owner, repo string) (chan StatusEvent, func()) {
+	key := repoKey(owner, repo)
+	ch := make(chan StatusEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subscribers[key] == nil {
+		b.subscribers[key] = make(map[chan StatusEvent]struct{})
+	}
+	b.subscribers[key][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers[key], ch)
+		if len(b.subscribers[key]) == 0 {
+			delete(b.subscribers, key)
+		}
+	}
+}
+
+// publish delivers an event to every subscriber of its repository without
+// blocking, returning how many subscribers received it
+func (b *eventBroker) publish(event StatusEvent) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delivered := 0
+	for ch := range b.subscribers[repoKey(event.Owner, event.Repository)] {
+		select {
+		case ch <- event:
+			delivered++
+		default:
+			log.Printf("Dropping event for slow subscriber of %s/%s", event.Owner, event.Repository)
+		}
+	}
+	return delivered
+}
+
+// close ends every subscription by closing its channel, so streams finish
+// when the server shuts down. Later subscriptions are closed immediately.
+func (b *eventBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for key, subs := range b.subscribers {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(b.subscribers, key)
+	}
+}
+
+// count reports the number of active subscriptions across all repositories
+func (b *eventBroker) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	total := 0
+	for _, subs := range b.subscribers {
+		total += len(subs)
+	}
+	return total
+}
+
+// eventsHandler handles the /events server-sent events endpoint
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	owner := r.URL.Query().Get("owner")
+	if owner == "" {
+		owner = config.DefaultOwner
+	}
+	repo := r.URL.Query().Get("repo")
+	if owner == "" || repo == "" {
+		writeError(w, http.StatusBadRequest, "Both 'owner' and 'repo' query parameters are required")
+		return
+	}
+
+	if !repoAllowed(owner, repo) {
+		writeError(w, http.StatusForbidden, notAllowedMessage(owner, repo))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	if activeSubscribers.Add(1) > int64(config.MaxSubscribers) {
+		activeSubscribers.Add(-1)
+		writeError(w, http.StatusServiceUnavailable, "Too many event subscribers")
+		return
+	}
+	defer activeSubscribers.Add(-1)
+
+	events, unsubscribe := broker.subscribe(owner, repo)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(config.SSEKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				// EventSource clients reconnect on their own once the stream ends
+				fmt.Fprint(w, ": server shutting down\n\n")
+				flusher.Flush()
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("Error encoding event: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const statusWebhookBody = `{
+	"sha": "abc123",
+	"context": "ci/build",
+	"state": "failure",
+	"target_url": "https://ci.example.com/1",
+	"branches": [{"name": "main"}],
+	"repository": {"name": "repo", "owner": {"login": "owner"}}
+}`
+
+// testWebhookSecret is the WEBHOOK_SECRET tests sign their webhooks with
+const testWebhookSecret = "hook-secret"
+
+// signWebhook signs a webhook request carrying body with testWebhookSecret
+func signWebhook(req *http.Request, body string) {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(body))
+	req.Header.Set("X-Gitea-Signature", hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestEventsHandler_ReceivesWebhookEvent(t *testing.T) {
+	originalBroker := broker
+	broker = newEventBroker()
+	defer func() { broker = originalBroker }()
+	originalConfig := config
+	config.WebhookSecret = testWebhookSecret
+	defer func() { config = originalConfig }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", eventsHandler)
+	mux.HandleFunc("/webhook", webhookHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events?owner=Owner&repo=repo", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+	for broker.count() == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	hook, _ := http.NewRequest(http.MethodPost, server.URL+"/webhook", strings.NewReader(statusWebhookBody))
+	hook.Header.Set("X-Gitea-Event", "status")
+	signWebhook(hook, statusWebhookBody)
+	hookResp, err := http.DefaultClient.Do(hook)
+	if err != nil {
+		t.Fatalf("Failed to post webhook: %v", err)
+	}
+	hookResp.Body.Close()
+	if hookResp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected webhook status 204, got %d", hookResp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var event StatusEvent
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Fatalf("Failed to decode event: %v", err)
+			}
+			break
+		}
+	}
+
+	if event.State != "failure" || event.Symbol != "✗" || event.SHA != "abc123" || event.Context != "ci/build" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if len(event.Branches) != 1 || event.Branches[0] != "main" {
+		t.Errorf("Expected branches [main], got %v", event.Branches)
+	}
+}
+
+func TestEventBroker_RoutesByRepository(t *testing.T) {
+	b := newEventBroker()
+	ch, unsubscribe := b.subscribe("owner", "repo")
+
+	if n := b.publish(StatusEvent{Owner: "owner", Repository: "other"}); n != 0 {
+		t.Errorf("Expected no deliveries for another repository, got %d", n)
+	}
+	if n := b.publish(StatusEvent{Owner: "OWNER", Repository: "repo", State: "success"}); n != 1 {
+		t.Errorf("Expected one delivery, got %d", n)
+	}
+	if event := <-ch; event.State != "success" {
+		t.Errorf("Expected success event, got %+v", event)
+	}
+
+	unsubscribe()
+	if b.count() != 0 {
+		t.Errorf("Expected no subscribers after unsubscribe, got %d", b.count())
+	}
+}
+
+func TestWebhookHandler_Signature(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+
+	tests := []struct {
+		name         string
+		secret       string
+		signature    string
+		expectedCode int
+	}{
+		{"valid signature", testWebhookSecret, "valid", http.StatusNoContent},
+		{"invalid signature", testWebhookSecret, "deadbeef", http.StatusUnauthorized},
+		{"missing signature", testWebhookSecret, "", http.StatusUnauthorized},
+		{"no secret configured", "", "", http.StatusForbidden},
+		{"no secret configured but signed", "", "valid", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.WebhookSecret = tt.secret
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(statusWebhookBody))
+			req.Header.Set("X-Gitea-Event", "status")
+			switch tt.signature {
+			case "valid":
+				signWebhook(req, statusWebhookBody)
+			case "":
+			default:
+				req.Header.Set("X-Gitea-Signature", tt.signature)
+			}
+			w := httptest.NewRecorder()
+			webhookHandler(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestEventsHandler_MaxSubscribers(t *testing.T) {
+	originalConfig := config
+	originalBroker := broker
+	config.MaxSubscribers = 2
+	broker = newEventBroker()
+	defer func() {
+		config = originalConfig
+		broker = originalBroker
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", eventsHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	open := func(ctx context.Context) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events?owner=owner&repo=repo", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to open event stream: %v", err)
+		}
+		return resp
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	defer cancelFirst()
+	second, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+
+	for _, ctx := range []context.Context{first, second} {
+		resp := open(ctx)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected stream within the limit to open, got %d", resp.StatusCode)
+		}
+	}
+
+	rejected := open(context.Background())
+	var rejection BuildStatusResponse
+	if err := json.NewDecoder(rejected.Body).Decode(&rejection); err != nil {
+		t.Fatalf("Could not parse rejection JSON: %v", err)
+	}
+	rejected.Body.Close()
+	if rejected.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 past the limit, got %d", rejected.StatusCode)
+	}
+	if rejection.Retryable == nil || !*rejection.Retryable {
+		t.Error("Expected running out of subscriber slots to be retryable")
+	}
+
+	// Disconnecting a subscriber frees its slot
+	cancelFirst()
+	deadline := time.Now().Add(5 * time.Second)
+	for activeSubscribers.Load() >= 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	third, cancelThird := context.WithCancel(context.Background())
+	defer cancelThird()
+	resp := open(third)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected a stream to open after a disconnect, got %d", resp.StatusCode)
+	}
+}
+
+func TestEventsHandler_OutlivesRequestDeadline(t *testing.T) {
+	originalBroker := broker
+	broker = newEventBroker()
+	defer func() { broker = originalBroker }()
+	originalConfig := config
+	config.RequestDeadline = 50 * time.Millisecond
+	config.WebhookSecret = testWebhookSecret
+	defer func() { config = originalConfig }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", eventsHandler)
+	mux.HandleFunc("/webhook", webhookHandler)
+	server := httptest.NewServer(withRequestDeadline(mux))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events?owner=owner&repo=repo", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+	for broker.count() == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Well past the deadline, the stream still delivers events
+	time.Sleep(4 * config.RequestDeadline)
+	hook, _ := http.NewRequest(http.MethodPost, server.URL+"/webhook", strings.NewReader(statusWebhookBody))
+	hook.Header.Set("X-Gitea-Event", "status")
+	signWebhook(hook, statusWebhookBody)
+	hookResp, err := http.DefaultClient.Do(hook)
+	if err != nil {
+		t.Fatalf("Failed to post webhook: %v", err)
+	}
+	hookResp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	received := false
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "data: ") {
+			received = true
+			break
+		}
+	}
+	if !received {
+		t.Errorf("Expected the stream to outlive REQUEST_DEADLINE, ended with %v", scanner.Err())
+	}
+}
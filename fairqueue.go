@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// fairLimiter bounds concurrent lookups. Once the cap is reached, waiting
+// requests queue per repository and freed slots go to the repositories in
+// turn rather than in arrival order, so a flood of requests for one
+// repository cannot keep the others waiting behind it.
+type fairLimiter struct {
+	mu     sync.Mutex
+	active int
+	queues map[string][]*fairWaiter
+	// turns lists the repositories with waiting requests, next to be served first
+	turns []string
+}
+
+// fairWaiter is a request waiting for a slot; ready is closed once granted
+type fairWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// newFairLimiter creates a limiter with no lookups in flight
+func newFairLimiter() *fairLimiter {
+	return &fairLimiter{queues: make(map[string][]*fairWaiter)}
+}
+
+var lookups = newFairLimiter()
+
+// acquire waits for one of limit slots on behalf of key, returning a func
+// that frees it. Lookups are unlimited when limit is not positive.
+func (l *fairLimiter) acquire(ctx context.Context, key string, limit int) (func(), error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	release := func() { l.release(limit) }
+
+	l.mu.Lock()
+	if l.active < limit && len(l.turns) == 0 {
+		l.active++
+		l.mu.Unlock()
+		return release, nil
+	}
+	w := &fairWaiter{ready: make(chan struct{})}
+	if len(l.queues[key]) == 0 {
+		l.turns = append(l.turns, key)
+	}
+	l.queues[key] = append(l.queues[key], w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		granted := w.granted
+		if !granted {
+			l.remove(key, w)
+		}
+		l.mu.Unlock()
+
+		// A slot granted as the request gave up is passed on
+		if granted {
+			release()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// release frees a slot and hands free slots to waiting repositories in turn
+func (l *fairLimiter) release(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	for l.active < limit && len(l.turns) > 0 {
+		key := l.turns[0]
+		l.turns = l.turns[1:]
+
+		queue := l.queues[key]
+		w := queue[0]
+		if len(queue) > 1 {
+			l.queues[key] = queue[1:]
+			// Back of the line until every other waiting repository had a turn
+			l.turns = append(l.turns, key)
+		} else {
+			delete(l.queues, key)
+		}
+
+		w.granted = true
+		close(w.ready)
+		l.active++
+	}
+}
+
+// remove drops a waiter that gave up; l.mu must be held
+func (l *fairLimiter) remove(key string, w *fairWaiter) {
+	queue := slices.DeleteFunc(l.queues[key], func(q *fairWaiter) bool { return q == w })
+	if len(queue) > 0 {
+		l.queues[key] = queue
+		return
+	}
+	delete(l.queues, key)
+	l.turns = slices.DeleteFunc(l.turns, func(k string) bool { return k == key })
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waiting reports how many requests are queued for a slot
+func (l *fairLimiter) waiting() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	total := 0
+	for _, queue := range l.queues {
+		total += len(queue)
+	}
+	return total
+}
+
+// waitForQueue waits until n requests are queued on l
+func waitForQueue(t *testing.T, l *fairLimiter, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for l.waiting() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d queued requests, got %d", n, l.waiting())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFairLimiter_TakesTurnsAcrossRepositories(t *testing.T) {
+	l := newFairLimiter()
+	release, err := l.acquire(context.Background(), "myorg/busy", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	enqueue := func(key, label string, queued int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done, err := l.acquire(context.Background(), key, 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, label)
+			mu.Unlock()
+			done()
+		}()
+		waitForQueue(t, l, queued)
+	}
+	for i, label := range []string{"busy-1", "busy-2", "busy-3", "busy-4"} {
+		enqueue("myorg/busy", label, i+1)
+	}
+	enqueue("myorg/quiet", "quiet-1", 5)
+	enqueue("myorg/other", "other-1", 6)
+
+	release()
+	wg.Wait()
+
+	expected := "busy-1 quiet-1 other-1 busy-2 busy-3 busy-4"
+	if got := strings.Join(order, " "); got != expected {
+		t.Errorf("Expected slots granted in order %q, got %q", expected, got)
+	}
+}
+
+func TestFairLimiter_GivingUpLeavesTheQueue(t *testing.T) {
+	l := newFairLimiter()
+	release, err := l.acquire(context.Background(), "myorg/busy", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "myorg/other", 1); err == nil {
+		t.Fatal("Expected waiting past the deadline to fail")
+	}
+	if l.waiting() != 0 || len(l.turns) != 0 {
+		t.Errorf("Expected the abandoned request to leave the queue, got %d waiting", l.waiting())
+	}
+
+	release()
+	next, err := l.acquire(context.Background(), "myorg/other", 1)
+	if err != nil {
+		t.Fatalf("Expected the freed slot to be available, got %v", err)
+	}
+	next()
+}
+
+func TestFairLimiter_Unlimited(t *testing.T) {
+	l := newFairLimiter()
+	for range 100 {
+		if _, err := l.acquire(context.Background(), "myorg/busy", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l.active != 0 {
+		t.Errorf("Expected unlimited lookups not to be counted, got %d", l.active)
+	}
+}
+
+func TestStatusHandler_FloodedRepositoryDoesNotStarveOthers(t *testing.T) {
+	originalConfig := config
+	originalLookups := lookups
+	config.MaxConcurrentLookups = 2
+	lookups = newFairLimiter()
+	defer func() {
+		config = originalConfig
+		lookups = originalLookups
+	}()
+
+	var busyDone atomic.Int64
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "/busy/") {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	const flood = 20
+	var wg sync.WaitGroup
+	for range flood {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "/status?owner=myorg&repo=busy&branches=main", nil)
+			statusHandler(httptest.NewRecorder(), req)
+			busyDone.Add(1)
+		}()
+	}
+	waitForQueue(t, lookups, flood-2)
+
+	before := busyDone.Load()
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=quiet&branches=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+	servedAhead := busyDone.Load() - before
+	wg.Wait()
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+	// The two lookups in flight and at most one more turn of the flooded repository
+	if servedAhead > 4 {
+		t.Errorf("Expected the quiet repository to be served within a turn, but %d flooded requests finished first", servedAhead)
+	}
+}
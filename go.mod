@@ -1,3 +1,3 @@
 module github.com/fred-drake/gitea-check-service
 
-go 1.23
+go 1.24
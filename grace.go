@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// graceSweepSize is how many remembered refs trigger a sweep of the ones
+// that no longer matter
+const graceSweepSize = 1024
+
+// graceIdleTTL is how long a ref that is not looked up is remembered. A
+// re-run starting after that reports pending straight away.
+const graceIdleTTL = 24 * time.Hour
+
+// terminalEntry remembers the last terminal state seen for a ref, when the
+// ref most recently went pending after it and when it was last looked up
+type terminalEntry struct {
+	state        string
+	pendingSince time.Time
+	seen         time.Time
+}
+
+// graceTracker smooths brief pending states during re-runs by reporting
+// the previous terminal state until the grace window has passed
+type graceTracker struct {
+	mu      sync.Mutex
+	entries map[string]terminalEntry
+}
+
+// newGraceTracker creates a tracker with no remembered states
+func newGraceTracker() *graceTracker {
+	return &graceTracker{entries: make(map[string]terminalEntry)}
+}
+
+var grace = newGraceTracker()
+
+// isTerminalState reports whether state is a finished outcome worth remembering
+func isTerminalState(state string) bool {
+	switch state {
+	case "success", "failure", "error", "warning":
+		return true
+	}
+	return false
+}
+
+// smooth returns the state to report for key. Terminal states are
+// remembered; a pending state is replaced by the remembered terminal state
+// until it has been pending for longer than window.
+func (g *graceTracker) smooth(key, state string, window time.Duration, now time.Time) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if isTerminalState(state) {
+		if _, ok := g.entries[key]; !ok && len(g.entries) >= graceSweepSize {
+			g.sweep(window, now)
+		}
+		g.entries[key] = terminalEntry{state: state, seen: now}
+		return state
+	}
+
+	entry, ok := g.entries[key]
+	if state != "pending" || !ok {
+		return state
+	}
+
+	if entry.pendingSince.IsZero() {
+		entry.pendingSince = now
+	}
+	entry.seen = now
+	g.entries[key] = entry
+	if now.Sub(entry.pendingSince) <= window {
+		return entry.state
+	}
+	return state
+}
+
+// sweep forgets refs whose grace window has passed or that have not been
+// looked up for graceIdleTTL. Keys include commit SHAs that may never be
+// looked up again, so they would otherwise accumulate. g.mu must be held.
+func (g *graceTracker) sweep(window time.Duration, now time.Time) {
+	for key, entry := range g.entries {
+		expired := !entry.pendingSince.IsZero() && now.Sub(entry.pendingSince) > window
+		if expired || now.Sub(entry.seen) > graceIdleTTL {
+			delete(g.entries, key)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGraceTracker_Smooth(t *testing.T) {
+	g := newGraceTracker()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	window := time.Minute
+
+	steps := []struct {
+		state    string
+		at       time.Duration
+		expected string
+	}{
+		{"pending", 0, "pending"},
+		{"success", 0, "success"},
+		{"pending", 10 * time.Second, "success"},
+		{"pending", 70 * time.Second, "success"},
+		{"pending", 71 * time.Second, "pending"},
+		{"failure", 80 * time.Second, "failure"},
+		{"pending", 90 * time.Second, "failure"},
+	}
+
+	for i, step := range steps {
+		if got := g.smooth("owner/repo/main", step.state, window, start.Add(step.at)); got != step.expected {
+			t.Errorf("Step %d: expected %q for %q, got %q", i, step.expected, step.state, got)
+		}
+	}
+}
+
+func TestStatusHandler_PendingGrace(t *testing.T) {
+	originalConfig := config
+	originalGrace := grace
+	config.PendingGrace = time.Minute
+	grace = newGraceTracker()
+	defer func() {
+		config = originalConfig
+		grace = originalGrace
+	}()
+
+	state := "success"
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "`+state+`", "statuses": [], "total_count": 1}`), nil
+	})
+
+	for _, current := range []string{"success", "pending"} {
+		state = current
+		req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main", nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected upstream %q to be reported as success (200), got %d: %s", current, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestGraceTracker_SweepsForgottenRefs(t *testing.T) {
+	g := newGraceTracker()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	window := time.Minute
+
+	for i := range graceSweepSize {
+		g.smooth(fmt.Sprintf("owner/repo/sha-%d", i), "success", window, start)
+	}
+	// A ref still in use survives
+	g.smooth("owner/repo/main", "success", window, start.Add(graceIdleTTL))
+
+	later := start.Add(graceIdleTTL + time.Hour)
+	g.smooth("owner/repo/new", "success", window, later)
+
+	g.mu.Lock()
+	remaining := len(g.entries)
+	g.mu.Unlock()
+	if remaining != 2 {
+		t.Errorf("Expected refs not looked up for graceIdleTTL to be swept, got %d entries", remaining)
+	}
+	if got := g.smooth("owner/repo/main", "pending", window, later); got != "success" {
+		t.Errorf("Expected the ref in use to keep its grace, got %q", got)
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import "net/http"
+
+// newServer creates the HTTP server for handler. With ENABLE_H2C it also
+// accepts HTTP/2 over cleartext connections, for service meshes that speak
+// h2c, alongside HTTP/1.1.
+func newServer(handler http.Handler) *http.Server {
+	server := &http.Server{Handler: handler}
+	if config.EnableH2C {
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+	return server
+}
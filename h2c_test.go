@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+// startServer serves handler through newServer on a local port until the test ends
+func startServer(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- serve(ctx, newServer(handler), listener) }()
+	t.Cleanup(func() {
+		cancel()
+		<-served
+	})
+	return "http://" + listener.Addr().String()
+}
+
+func TestNewServer_H2C(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.EnableH2C = true
+
+	originalBroker := broker
+	broker = newEventBroker()
+	defer func() { broker = originalBroker }()
+
+	baseURL := startServer(t, http.HandlerFunc(healthHandler))
+
+	h2cTransport := &http.Transport{Protocols: new(http.Protocols)}
+	h2cTransport.Protocols.SetUnencryptedHTTP2(true)
+	defer h2cTransport.CloseIdleConnections()
+
+	clients := []struct {
+		name          string
+		client        *http.Client
+		expectedProto string
+	}{
+		{"http/1.1", &http.Client{}, "HTTP/1.1"},
+		{"h2c", &http.Client{Transport: h2cTransport}, "HTTP/2.0"},
+	}
+
+	for _, tc := range clients {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := tc.client.Get(baseURL + "/health")
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected status 200, got %d: %s", resp.StatusCode, body)
+			}
+			if resp.Proto != tc.expectedProto {
+				t.Errorf("Expected %s, got %s", tc.expectedProto, resp.Proto)
+			}
+		})
+	}
+}
+
+func TestNewServer_H2CDisabledByDefault(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.EnableH2C = false
+
+	if server := newServer(http.NotFoundHandler()); server.Protocols != nil {
+		t.Errorf("Expected the default protocols without ENABLE_H2C, got %v", server.Protocols)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Health check outcomes
+const (
+	healthOK       = "ok"
+	healthFail     = "fail"
+	healthDegraded = "degraded"
+)
+
+// HealthCheck reports the state of a single dependency
+type HealthCheck struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail"`
+	Critical bool   `json:"critical"`
+}
+
+// MarshalJSON encodes the check using the configured field naming style
+func (c HealthCheck) MarshalJSON() ([]byte, error) {
+	type plain HealthCheck
+	return marshalWithNaming(plain(c))
+}
+
+// HealthReport represents the detailed /health response
+type HealthReport struct {
+	Status   string        `json:"status"`
+	Degraded bool          `json:"degraded"`
+	Checks   []HealthCheck `json:"checks"`
+}
+
+// MarshalJSON encodes the report using the configured field naming style
+func (h HealthReport) MarshalJSON() ([]byte, error) {
+	type plain HealthReport
+	return marshalWithNaming(plain(h))
+}
+
+// Version represents the Gitea server version response
+type Version struct {
+	Version string `json:"version"`
+}
+
+// GetVersion fetches the Gitea server version, confirming the API is reachable
+func (g *GiteaService) GetVersion() (string, error) {
+	endpoint, err := g.apiURL("version")
+	if err != nil {
+		return "", err
+	}
+
+	var version Version
+	if err := g.getJSON(endpoint, "get version", &version); err != nil {
+		return "", err
+	}
+	return version.Version, nil
+}
+
+// checkGitea reports whether the Gitea API can be reached
+func checkGitea(r *http.Request) HealthCheck {
+	check := HealthCheck{Name: "gitea", Status: healthOK, Critical: true}
+	version, err := service.withContext(r.Context()).GetVersion()
+	if err != nil {
+		check.Status = healthFail
+		check.Detail = quietError(err.Error(), err)
+		return check
+	}
+	check.Detail = fmt.Sprintf("reachable, version %s", version)
+	return check
+}
+
+// checkCache reports on the status cache. An unreachable shared cache
+// degrades the service rather than failing it, as lookups fall back to
+// Gitea.
+func checkCache(r *http.Request) HealthCheck {
+	check := HealthCheck{Name: "cache", Status: healthOK}
+	if config.CacheTTL <= 0 {
+		check.Detail = "disabled"
+		return check
+	}
+	detail, err := cache.Describe(r.Context())
+	if err != nil {
+		check.Status = healthDegraded
+		detail = fmt.Sprintf("%s, unreachable: %v", detail, err)
+	}
+	check.Detail = detail
+	return check
+}
+
+// checkWebhooks reports the number of active event stream subscribers
+func checkWebhooks() HealthCheck {
+	return HealthCheck{
+		Name:   "webhook_subscribers",
+		Status: healthOK,
+		Detail: fmt.Sprintf("%d subscribers", broker.count()),
+	}
+}
+
+// buildHealthReport runs every dependency check. The report fails when any
+// critical check fails and is flagged degraded when any check is.
+func buildHealthReport(r *http.Request) HealthReport {
+	report := HealthReport{
+		Status: healthOK,
+		Checks: []HealthCheck{checkGitea(r), checkCache(r), checkWebhooks(), checkUpstreamPerformance()},
+	}
+	for _, check := range report.Checks {
+		if check.Status == healthDegraded {
+			report.Degraded = true
+		} else if check.Critical && check.Status != healthOK {
+			report.Status = healthFail
+		}
+	}
+	return report
+}
+
+// writeDetailedHealth writes the dependency report, responding 503 when a critical check fails
+func writeDetailedHealth(w http.ResponseWriter, r *http.Request) {
+	report := buildHealthReport(r)
+	code := http.StatusOK
+	if report.Status != healthOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, report)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthHandler_Detail(t *testing.T) {
+	tests := []struct {
+		name           string
+		doFunc         func(req *http.Request) (*http.Response, error)
+		expectedCode   int
+		expectedStatus string
+		giteaDetail    string
+	}{
+		{
+			name: "gitea reachable",
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(200, `{"version": "1.21.0"}`), nil
+			},
+			expectedCode:   http.StatusOK,
+			expectedStatus: healthOK,
+			giteaDetail:    "reachable, version 1.21.0",
+		},
+		{
+			name: "gitea unreachable",
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				return nil, connectionRefused(req.URL.String())
+			},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: healthFail,
+			giteaDetail:    "connection refused",
+		},
+		{
+			name: "gitea erroring",
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(502, `bad gateway`), nil
+			},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: healthFail,
+			giteaDetail:    "failed to get version: 502",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, tt.doFunc)
+			resetUpstreamOutcomes(t)
+
+			req := httptest.NewRequest("GET", "/health?detail=true", nil)
+			rr := httptest.NewRecorder()
+			healthHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+
+			var report HealthReport
+			if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if report.Status != tt.expectedStatus {
+				t.Errorf("Expected overall status %q, got %q", tt.expectedStatus, report.Status)
+			}
+			if len(report.Checks) != 4 {
+				t.Fatalf("Expected 4 checks, got %+v", report.Checks)
+			}
+			if gitea := report.Checks[0]; gitea.Name != "gitea" || !strings.Contains(gitea.Detail, tt.giteaDetail) {
+				t.Errorf("Expected gitea check detail containing %q, got %+v", tt.giteaDetail, gitea)
+			}
+			for _, check := range report.Checks[1:] {
+				if check.Status != healthOK {
+					t.Errorf("Expected non-critical check %q to pass, got %+v", check.Name, check)
+				}
+			}
+		})
+	}
+}
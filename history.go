@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Transition records a context on a branch changing state
+type Transition struct {
+	Time          time.Time `json:"time"`
+	SHA           string    `json:"sha"`
+	Context       string    `json:"context"`
+	State         string    `json:"state"`
+	PreviousState string    `json:"previous_state,omitempty"`
+}
+
+// MarshalJSON encodes the transition using the configured field naming style
+func (t Transition) MarshalJSON() ([]byte, error) {
+	type plain Transition
+	return marshalWithNaming(plain(t))
+}
+
+// HistoryResponse represents the /history response
+type HistoryResponse struct {
+	Owner       string       `json:"owner"`
+	Repository  string       `json:"repository"`
+	Branch      string       `json:"branch"`
+	Transitions []Transition `json:"transitions"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the history using the configured field naming style
+func (h HistoryResponse) MarshalJSON() ([]byte, error) {
+	type plain HistoryResponse
+	return marshalWithNaming(plain(h))
+}
+
+// historyMaxBranches bounds how many branches keep a history. Webhooks can
+// name any branch, so past this the branch updated longest ago is dropped.
+const historyMaxBranches = 1024
+
+// historyStore keeps the most recent state transitions of each branch in
+// memory, fed by webhook events
+type historyStore struct {
+	mu          sync.Mutex
+	transitions map[string][]Transition
+}
+
+// newHistoryStore creates an empty history store
+func newHistoryStore() *historyStore {
+	return &historyStore{transitions: make(map[string][]Transition)}
+}
+
+var history = newHistoryStore()
+
+// historyKey identifies a branch for history storage
+func historyKey(owner, repo, branch string) string {
+	return repoKey(owner, repo) + "/" + branch
+}
+
+// record adds the event to the history of each of its branches when it
+// changes the state of its context, keeping at most HISTORY_SIZE
+// transitions per branch and historyMaxBranches branches
+func (h *historyStore) record(event StatusEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, branch := range event.Branches {
+		key := historyKey(event.Owner, event.Repository, branch)
+		transitions := h.transitions[key]
+
+		previous := ""
+		for i := len(transitions) - 1; i >= 0; i-- {
+			if transitions[i].Context == event.Context {
+				previous = transitions[i].State
+				break
+			}
+		}
+		if previous == event.State {
+			continue
+		}
+
+		transitions = append(transitions, Transition{
+			Time:          event.Time,
+			SHA:           event.SHA,
+			Context:       event.Context,
+			State:         event.State,
+			PreviousState: previous,
+		})
+		if len(transitions) > config.HistorySize {
+			transitions = transitions[len(transitions)-config.HistorySize:]
+		}
+		if _, ok := h.transitions[key]; !ok && len(h.transitions) >= historyMaxBranches {
+			h.evictOldest()
+		}
+		h.transitions[key] = transitions
+	}
+}
+
+// evictOldest drops the branch whose latest transition is the oldest.
+// h.mu must be held.
+func (h *historyStore) evictOldest() {
+	oldestKey := ""
+	var oldest time.Time
+	for key, transitions := range h.transitions {
+		latest := transitions[len(transitions)-1].Time
+		if oldestKey == "" || latest.Before(oldest) {
+			oldestKey, oldest = key, latest
+		}
+	}
+	delete(h.transitions, oldestKey)
+}
+
+// get returns a copy of a branch's transitions, oldest first
+func (h *historyStore) get(owner, repo, branch string) []Transition {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]Transition{}, h.transitions[historyKey(owner, repo, branch)]...)
+}
+
+// historyHandler handles the /history endpoint
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	owner := r.URL.Query().Get("owner")
+	if owner == "" {
+		owner = config.DefaultOwner
+	}
+	repo := r.URL.Query().Get("repo")
+	branch := r.URL.Query().Get("branch")
+
+	if owner == "" || repo == "" || branch == "" {
+		writeJSON(w, http.StatusBadRequest, HistoryResponse{
+			Error: "The 'owner', 'repo' and 'branch' query parameters are required",
+		})
+		return
+	}
+
+	if msg := validateParamLengths(owner, repo, branch); msg != "" {
+		writeJSON(w, http.StatusBadRequest, HistoryResponse{Error: msg})
+		return
+	}
+
+	if !repoAllowed(owner, repo) {
+		writeJSON(w, http.StatusForbidden, HistoryResponse{Error: notAllowedMessage(owner, repo)})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, HistoryResponse{
+		Owner:       owner,
+		Repository:  repo,
+		Branch:      branch,
+		Transitions: history.get(owner, repo, branch),
+	})
+}
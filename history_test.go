@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryHandler_RecordsWebhookTransitions(t *testing.T) {
+	originalConfig := config
+	originalHistory := history
+	config.HistorySize = 3
+	config.WebhookSecret = testWebhookSecret
+	history = newHistoryStore()
+	defer func() {
+		config = originalConfig
+		history = originalHistory
+	}()
+
+	post := func(sha, context, state string) {
+		t.Helper()
+		body := `{"sha": "` + sha + `", "context": "` + context + `", "state": "` + state + `",
+			"branches": [{"name": "main"}], "repository": {"name": "repo", "owner": {"login": "owner"}}}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("X-Gitea-Event", "status")
+		signWebhook(req, body)
+		w := httptest.NewRecorder()
+		webhookHandler(w, req)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected webhook status 204, got %d", w.Code)
+		}
+	}
+
+	post("a1", "ci/build", "pending")
+	post("a1", "ci/build", "pending") // repeated state is not a transition
+	post("a1", "ci/build", "failure")
+	post("b2", "ci/build", "pending")
+	post("b2", "lint", "success")
+	post("b2", "ci/build", "success")
+
+	req := httptest.NewRequest("GET", "/history?owner=Owner&repo=repo&branch=main", nil)
+	rr := httptest.NewRecorder()
+	historyHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var response HistoryResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+
+	// Only the newest HISTORY_SIZE transitions are kept, oldest first
+	expected := []Transition{
+		{SHA: "b2", Context: "ci/build", State: "pending", PreviousState: "failure"},
+		{SHA: "b2", Context: "lint", State: "success"},
+		{SHA: "b2", Context: "ci/build", State: "success", PreviousState: "pending"},
+	}
+	if len(response.Transitions) != len(expected) {
+		t.Fatalf("Expected %d transitions, got %+v", len(expected), response.Transitions)
+	}
+	for i, transition := range response.Transitions {
+		transition.Time = expected[i].Time
+		if transition != expected[i] {
+			t.Errorf("Transition %d: expected %+v, got %+v", i, expected[i], transition)
+		}
+	}
+}
+
+func TestHistoryHandler_RequiresBranch(t *testing.T) {
+	req := httptest.NewRequest("GET", "/history?owner=owner&repo=repo", nil)
+	rr := httptest.NewRecorder()
+	historyHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+}
+
+func TestHistoryStore_EvictsOldestBranch(t *testing.T) {
+	h := newHistoryStore()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := range historyMaxBranches {
+		h.record(StatusEvent{
+			Owner: "owner", Repository: "repo", Branches: []string{fmt.Sprintf("branch-%d", i)},
+			SHA: "a1", Context: "ci/build", State: "success", Time: start.Add(time.Duration(i) * time.Second),
+		})
+	}
+	// The first branch is updated again, leaving branch-1 the oldest
+	h.record(StatusEvent{
+		Owner: "owner", Repository: "repo", Branches: []string{"branch-0"},
+		SHA: "b2", Context: "ci/build", State: "failure", Time: start.Add(time.Hour),
+	})
+	h.record(StatusEvent{
+		Owner: "owner", Repository: "repo", Branches: []string{"new"},
+		SHA: "c3", Context: "ci/build", State: "success", Time: start.Add(2 * time.Hour),
+	})
+
+	h.mu.Lock()
+	branches := len(h.transitions)
+	h.mu.Unlock()
+	if branches != historyMaxBranches {
+		t.Errorf("Expected at most %d branches, got %d", historyMaxBranches, branches)
+	}
+	if got := h.get("owner", "repo", "branch-1"); len(got) != 0 {
+		t.Errorf("Expected the branch updated longest ago to be evicted, got %+v", got)
+	}
+	if got := h.get("owner", "repo", "branch-0"); len(got) != 2 {
+		t.Errorf("Expected the recently updated branch to be kept, got %+v", got)
+	}
+	if got := h.get("owner", "repo", "new"); len(got) != 1 {
+		t.Errorf("Expected the new branch to be recorded, got %+v", got)
+	}
+}
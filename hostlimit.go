@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// hostLimiter bounds concurrent upstream requests per Gitea host, so a slow
+// instance cannot use up the capacity meant for a healthy one
+type hostLimiter struct {
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+// newHostLimiter creates a limiter with no hosts seen yet
+func newHostLimiter() *hostLimiter {
+	return &hostLimiter{sems: make(map[string]chan struct{})}
+}
+
+var hostLimits = newHostLimiter()
+
+// acquire waits for a free slot for host, returning a func that frees it.
+// Requests are unlimited when PER_HOST_CONCURRENCY is unset.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	if config.PerHostConcurrency <= 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	sem, ok := l.sems[host]
+	if !ok {
+		sem = make(chan struct{}, config.PerHostConcurrency)
+		l.sems[host] = sem
+	}
+	l.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter_IndependentPerHost(t *testing.T) {
+	originalConfig := config
+	originalLimits := hostLimits
+	config.PerHostConcurrency = 2
+	hostLimits = newHostLimiter()
+	defer func() {
+		config = originalConfig
+		hostLimits = originalLimits
+	}()
+
+	var mu sync.Mutex
+	inFlight := map[string]int{}
+	maxInFlight := map[string]int{}
+	unblockSlow := make(chan struct{})
+	client := &MockHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+		host := req.URL.Host
+		mu.Lock()
+		inFlight[host]++
+		maxInFlight[host] = max(maxInFlight[host], inFlight[host])
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight[host]--
+			mu.Unlock()
+		}()
+
+		if host == "slow.example.com" {
+			<-unblockSlow
+		}
+		return createHTTPResponse(200, `{"version": "1.21.0"}`), nil
+	}}
+	slow := &GiteaService{BaseURL: "https://slow.example.com", Token: "test-token", HTTPClient: client}
+	healthy := &GiteaService{BaseURL: "https://healthy.example.com", Token: "test-token", HTTPClient: client}
+
+	var slowDone sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		slowDone.Add(1)
+		go func() {
+			defer slowDone.Done()
+			slow.GetVersion()
+		}()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		saturated := inFlight["slow.example.com"] == config.PerHostConcurrency
+		mu.Unlock()
+		if saturated {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the slow host to fill its slots")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The slow host's slots are all taken, yet the healthy host answers
+	done := make(chan error)
+	go func() {
+		_, err := healthy.GetVersion()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected healthy host to answer, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the healthy host not to wait on the slow one")
+	}
+
+	close(unblockSlow)
+	slowDone.Wait()
+
+	if maxInFlight["slow.example.com"] != config.PerHostConcurrency {
+		t.Errorf("Expected the slow host to be capped at %d requests, got %d", config.PerHostConcurrency, maxInFlight["slow.example.com"])
+	}
+}
+
+func TestHostLimiter_RespectsCancellation(t *testing.T) {
+	originalConfig := config
+	config.PerHostConcurrency = 1
+	defer func() { config = originalConfig }()
+
+	limiter := newHostLimiter()
+	release, err := limiter.acquire(context.Background(), "git.example.com")
+	if err != nil {
+		t.Fatalf("Expected a free slot, got %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx, "git.example.com"); err == nil {
+		t.Error("Expected waiting for a full host to end with the request")
+	}
+}
+
+func TestHostLimiter_UnlimitedByDefault(t *testing.T) {
+	limiter := newHostLimiter()
+	for i := 0; i < 100; i++ {
+		if _, err := limiter.acquire(context.Background(), "git.example.com"); err != nil {
+			t.Fatalf("Expected no limit when PER_HOST_CONCURRENCY is unset, got %v", err)
+		}
+	}
+}
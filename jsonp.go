@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+)
+
+// callbackPattern matches safe JavaScript identifiers, optionally dotted (e.g. "app.onStatus")
+var callbackPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+// maxCallbackLength bounds the callback name to keep the wrapper predictable
+const maxCallbackLength = 64
+
+// isValidCallback reports whether name is safe to echo back as a JSONP callback
+func isValidCallback(name string) bool {
+	return len(name) <= maxCallbackLength && callbackPattern.MatchString(name)
+}
+
+// writeJSONP wraps the JSON encoding of v in a call to callback. Script tags
+// ignore non-2xx responses, so JSONP always answers 200 and leaves the state
+// to the payload.
+func writeJSONP(w http.ResponseWriter, callback string, v any) {
+	marshal := json.Marshal
+	if isPretty(w) {
+		marshal = func(v any) ([]byte, error) { return json.MarshalIndent(v, "", "  ") }
+	}
+	body, err := marshal(enveloped(w, v))
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/javascript")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	// The leading comment guards against content-sniffing attacks on the callback name
+	if _, err := w.Write([]byte("/**/" + callback + "(" + string(body) + ");")); err != nil {
+		log.Printf("Error writing JSONP response: %v", err)
+	}
+}
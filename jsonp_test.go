@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsValidCallback(t *testing.T) {
+	tests := []struct {
+		callback string
+		expected bool
+	}{
+		{"onStatus", true},
+		{"app.handlers.onStatus", true},
+		{"$jsonp_1", true},
+		{"alert(1)", false},
+		{"a;b", false},
+		{"1abc", false},
+		{"a..b", false},
+		{strings.Repeat("a", maxCallbackLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.callback, func(t *testing.T) {
+			if result := isValidCallback(tt.callback); result != tt.expected {
+				t.Errorf("isValidCallback(%s) = %v, want %v", tt.callback, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_JSONPCallback(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "failure", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&callback=onStatus", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/javascript" {
+		t.Errorf("handler returned wrong content type: got %v want %v", contentType, "application/javascript")
+	}
+
+	body := rr.Body.String()
+	prefix := "/**/onStatus("
+	if !strings.HasPrefix(body, prefix) || !strings.HasSuffix(body, ");") {
+		t.Fatalf("Expected body wrapped in callback, got %s", body)
+	}
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal([]byte(strings.TrimSuffix(strings.TrimPrefix(body, prefix), ");")), &response); err != nil {
+		t.Fatalf("Could not parse wrapped JSON: %v", err)
+	}
+	if response.State != "failure" {
+		t.Errorf("Expected state 'failure', got '%s'", response.State)
+	}
+}
+
+func TestStatusHandler_JSONPRejectsUnsafeCallback(t *testing.T) {
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&callback=alert(document.cookie)", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("handler returned wrong content type: got %v want %v", contentType, "application/json")
+	}
+	if strings.Contains(rr.Body.String(), "alert") {
+		t.Errorf("Unsafe callback was echoed back: %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import "net/url"
+
+// repoURL builds the web address of a repository in Gitea for include_links,
+// from PUBLIC_GITEA_URL when Gitea is reached internally under another
+// address, or else the primary base URL, with any credentials removed
+func repoURL(owner, repo string) (string, error) {
+	public := config.PublicGiteaURL
+	if public == "" {
+		public = service.BaseURL
+	}
+	base, err := url.Parse(public)
+	if err != nil {
+		return "", err
+	}
+	base.User = nil
+	base.RawQuery = ""
+	base.Fragment = ""
+	return base.JoinPath(owner, repo).String(), nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.sock")
+
+	listener, err := listenUnix(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected socket file to exist, got %v", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		t.Errorf("Expected %s to be a socket, got mode %v", path, info.Mode())
+	}
+	if perm := info.Mode().Perm(); perm != unixSocketMode {
+		t.Errorf("Expected permissions %v, got %v", unixSocketMode, perm)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Expected to connect to socket, got %v", err)
+	}
+	_ = conn.Close()
+}
+
+func TestListenUnix_RemovesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.sock")
+
+	// Leave a socket file behind as a crashed process would
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Expected no error creating stale socket, got %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	listener, err := listenUnix(path)
+	if err != nil {
+		t.Fatalf("Expected stale socket to be replaced, got %v", err)
+	}
+	_ = listener.Close()
+}
+
+func TestListenUnix_RefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not-a-socket")
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := listenUnix(path); err == nil {
+		t.Error("Expected error for regular file, got nil")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected regular file to be left in place, got %v", err)
+	}
+}
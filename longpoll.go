@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// responseETag derives a strong ETag from the JSON encoding of a response payload
+func responseETag(v any) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// weakETag derives a weak ETag, for responses that may differ in detail
+// while staying equivalent for the client
+func weakETag(v any) string {
+	etag := responseETag(v)
+	if etag == "" {
+		return ""
+	}
+	return "W/" + etag
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, ignoring
+// weak validator prefixes
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	if header == "" || etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForChange holds a long poll while current still matches the client's
+// etag, re-fetching every LONG_POLL_INTERVAL and whenever a webhook reports
+// a status for the repository. It returns the first differing response, or
+// the unchanged one once LONG_POLL_TIMEOUT passes, the request ends or the
+// server shuts down.
+func waitForChange(ctx context.Context, owner, repo, etag string, current BuildStatusResponse, fetch func() (BuildStatusResponse, error), etagOf func(BuildStatusResponse) string) (BuildStatusResponse, error) {
+	if !etagMatches(etag, etagOf(current)) {
+		return current, nil
+	}
+
+	events, unsubscribe := broker.subscribe(owner, repo)
+	defer unsubscribe()
+
+	timeout := time.NewTimer(config.LongPollTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(config.LongPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return current, nil
+		case <-timeout.C:
+			return current, nil
+		case <-ticker.C:
+		case _, ok := <-events:
+			if !ok {
+				// The server is shutting down
+				return current, nil
+			}
+		}
+
+		next, err := fetch()
+		if err != nil {
+			if ctx.Err() != nil {
+				return current, nil
+			}
+			return next, err
+		}
+		current = next
+		if !etagMatches(etag, etagOf(current)) {
+			return current, nil
+		}
+	}
+}
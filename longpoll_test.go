@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setLongPoll shortens the long-poll timings for the duration of the test
+func setLongPoll(t *testing.T, timeout, interval time.Duration) {
+	t.Helper()
+
+	originalConfig := config
+	config.LongPollTimeout = timeout
+	config.LongPollInterval = interval
+	t.Cleanup(func() { config = originalConfig })
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{`*`, true},
+		{`"xyz"`, false},
+		{``, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if result := etagMatches(tt.header, `"abc"`); result != tt.expected {
+				t.Errorf("etagMatches(%q) = %v, want %v", tt.header, result, tt.expected)
+			}
+			if result := etagMatches(tt.header, `W/"abc"`); result != tt.expected {
+				t.Errorf("etagMatches(%q) against a weak tag = %v, want %v", tt.header, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_IfNoneMatch(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag on a successful response")
+	}
+
+	req = httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Errorf("Expected an empty 304, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestStatusHandler_WaitForChangeReturnsNewState(t *testing.T) {
+	setLongPoll(t, 5*time.Second, 10*time.Millisecond)
+
+	var state atomic.Value
+	state.Store("pending")
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "`+state.Load().(string)+`", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main&minimal=true", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+	etag := rr.Header().Get("ETag")
+
+	time.AfterFunc(50*time.Millisecond, func() { state.Store("success") })
+
+	start := time.Now()
+	req = httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main&minimal=true&wait_for_change=true", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected the changed state with 200, got %d", rr.Code)
+	}
+	var response MinimalResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if response.State != "success" {
+		t.Errorf("Expected state 'success', got %q", response.State)
+	}
+	if rr.Header().Get("ETag") == etag {
+		t.Error("Expected a new ETag for the changed state")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the request to be held until the change, returned after %s", elapsed)
+	}
+}
+
+func TestStatusHandler_WaitForChangeWakesOnWebhook(t *testing.T) {
+	setLongPoll(t, 5*time.Second, time.Hour)
+
+	var state atomic.Value
+	state.Store("pending")
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "`+state.Load().(string)+`", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+	etag := rr.Header().Get("ETag")
+
+	time.AfterFunc(20*time.Millisecond, func() {
+		state.Store("failure")
+		broker.publish(StatusEvent{Owner: "testowner", Repository: "testrepo", State: "failure"})
+	})
+
+	req = httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main&wait_for_change=true", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if response.State != "failure" {
+		t.Errorf("Expected the webhook to wake the poll with 'failure', got %q", response.State)
+	}
+}
+
+func TestStatusHandler_WaitForChangeTimesOut(t *testing.T) {
+	setLongPoll(t, 60*time.Millisecond, 10*time.Millisecond)
+
+	var calls atomic.Int32
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return createHTTPResponse(200, `{"state": "pending", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+	etag := rr.Header().Get("ETag")
+
+	start := time.Now()
+	req = httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main&wait_for_change=true", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 on timeout, got %d", rr.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected the request to be held for the timeout, returned after %s", elapsed)
+	}
+	if calls.Load() < 3 {
+		t.Errorf("Expected the status to be re-checked while waiting, got %d upstream calls", calls.Load())
+	}
+}
+
+func TestStatusHandler_ETagIgnoresFetchTime(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	etagAt := func() string {
+		req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branches=main&detail=true", nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+		return rr.Header().Get("ETag")
+	}
+
+	first := etagAt()
+	// Cross a second boundary so fetched_at differs between the responses
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	if second := etagAt(); second != first {
+		t.Errorf("Expected the ETag to ignore fetched_at, got %s and %s", first, second)
+	}
+}
+
+func TestStatusHandler_WeakAndStrongETags(t *testing.T) {
+	buildState := "pending"
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, fmt.Sprintf(`{"state": "pending", "statuses": [
+            {"context": "ci/build", "status": %q},
+            {"context": "ci/test", "status": "pending"}
+        ], "total_count": 2}`, buildState)), nil
+	})
+
+	etagOf := func(query string) string {
+		req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branches=main"+query, nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+		return rr.Header().Get("ETag")
+	}
+
+	weak, strong := etagOf(""), etagOf("&detail=true")
+	if !strings.HasPrefix(weak, `W/"`) {
+		t.Errorf("Expected a weak ETag by default, got %s", weak)
+	}
+	if !strings.HasPrefix(strong, `"`) {
+		t.Errorf("Expected a strong ETag with detail, got %s", strong)
+	}
+
+	// One check finishes while the overall state stays pending
+	buildState = "success"
+	if got := etagOf(""); got != weak {
+		t.Errorf("Expected the weak ETag to ignore individual checks, got %s and %s", weak, got)
+	}
+	if got := etagOf("&detail=true"); got == strong {
+		t.Error("Expected the strong ETag to change with an individual check")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Commit represents the parts of a Gitea commit used when walking history
+// and describing commits
+type Commit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Message   string       `json:"message"`
+		Author    CommitPerson `json:"author"`
+		Committer CommitPerson `json:"committer"`
+	} `json:"commit"`
+}
+
+// CommitPerson is the git identity of a commit's author or committer
+type CommitPerson struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// GetRecentCommits fetches up to limit commits reachable from ref, newest first
+func (g *GiteaService) GetRecentCommits(owner, repo, ref string, limit int) ([]Commit, error) {
+	endpoint, err := g.apiURL("repos", owner, repo, "commits")
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("sha", ref)
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("stat", "false")
+
+	var commits []Commit
+	if err := g.forRepo(owner, repo).getJSON(endpoint+"?"+query.Encode(), "list commits", &commits); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// lookbackResult describes the commit a lookback fell back to
+type lookbackResult struct {
+	status   *StatusResponse
+	sha      string
+	distance int
+}
+
+// lookbackStatus walks back from ref through up to lookback parent commits
+// looking for the most recent one with a known status. It returns nil when
+// none of them has one.
+func lookbackStatus(ctx context.Context, owner, repo, ref string, lookback int) (*lookbackResult, error) {
+	commits, err := service.withContext(ctx).GetRecentCommits(owner, repo, ref, lookback+1)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first commit is ref itself, whose status is already known to be unknown
+	for i := 1; i < len(commits) && i <= lookback; i++ {
+		status, err := fetchCommitStatus(ctx, owner, repo, commits[i].SHA)
+		if err != nil {
+			return nil, err
+		}
+		if rollupState(status) != "unknown" {
+			return &lookbackResult{status: status, sha: commits[i].SHA, distance: i}, nil
+		}
+	}
+	return nil, nil
+}
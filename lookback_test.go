@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// lookbackMock serves a branch whose tip has no status but whose parent does
+func lookbackMock(t *testing.T) {
+	t.Helper()
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/commits"):
+			if req.URL.Query().Get("sha") != "main" {
+				t.Errorf("Expected commits listed from main, got %q", req.URL.Query().Get("sha"))
+			}
+			return createHTTPResponse(200, `[{"sha": "tip"}, {"sha": "parent"}, {"sha": "grandparent"}]`), nil
+		case strings.Contains(req.URL.Path, "/commits/main/"), strings.Contains(req.URL.Path, "/commits/tip/"):
+			return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+		case strings.Contains(req.URL.Path, "/commits/parent/"):
+			return createHTTPResponse(200, `{"state": "failure", "statuses": [], "total_count": 1}`), nil
+		}
+		t.Errorf("Unexpected request to %s", req.URL.Path)
+		return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+	})
+}
+
+func TestStatusHandler_Lookback(t *testing.T) {
+	lookbackMock(t)
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expected     BuildStatusResponse
+	}{
+		{
+			name:         "without lookback",
+			query:        "owner=testowner&repo=testrepo&branches=main",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "falls back to parent",
+			query:        "owner=testowner&repo=testrepo&branches=main&lookback=2",
+			expectedCode: http.StatusExpectationFailed,
+			expected:     BuildStatusResponse{State: "failure", SHA: "parent", CommitsBack: 1},
+		},
+		{
+			name:         "above the cap",
+			query:        "owner=testowner&repo=testrepo&branches=main&lookback=11",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if tt.expected.State == "" {
+				return
+			}
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.State != tt.expected.State || response.SHA != tt.expected.SHA || response.CommitsBack != tt.expected.CommitsBack {
+				t.Errorf("Expected %+v, got %+v", tt.expected, response)
+			}
+		})
+	}
+}
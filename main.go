@@ -1,35 +1,89 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
 // StatusResponse represents the Gitea commit status response
 type StatusResponse struct {
-	State      string `json:"state"`
-	Statuses   []any  `json:"statuses"`
-	TotalCount int    `json:"total_count"`
+	State      string         `json:"state"`
+	SHA        string         `json:"sha"`
+	Statuses   []CommitStatus `json:"statuses"`
+	TotalCount int            `json:"total_count"`
+	// FetchedAt records when the status was retrieved from Gitea
+	FetchedAt time.Time `json:"-"`
+	// Raw holds Gitea's response body as sent when DEBUG_RAW_UPSTREAM is on
+	Raw json.RawMessage `json:"-"`
 }
 
 // Repository represents basic repo info from Gitea
 type Repository struct {
 	DefaultBranch string `json:"default_branch"`
+	Description   string `json:"description"`
+	Private       bool   `json:"private"`
+	Stars         int    `json:"stars_count"`
 }
 
 // BuildStatusResponse represents our API response
 type BuildStatusResponse struct {
-	Owner      string `json:"owner"`
-	Repository string `json:"repository"`
-	Branch     string `json:"branch"`
-	State      string `json:"state"`
-	Symbol     string `json:"symbol"`
-	Error      string `json:"error,omitempty"`
+	Owner         string                 `json:"owner"`
+	Repository    string                 `json:"repository"`
+	Branch        string                 `json:"branch"`
+	State         string                 `json:"state"`
+	StateCode     *int                   `json:"state_code,omitempty"`
+	Ok            bool                   `json:"ok"`
+	Symbol        string                 `json:"symbol"`
+	Color         string                 `json:"color"`
+	Summary       string                 `json:"summary,omitempty"`
+	BuildDuration string                 `json:"build_duration,omitempty"`
+	Statuses      []ContextStatus        `json:"statuses,omitempty"`
+	Groups        map[string]GroupStatus `json:"groups,omitempty"`
+	ContextURLs   map[string]string      `json:"context_urls,omitempty"`
+	Matrix        []MatrixCell           `json:"matrix,omitempty"`
+	Branches      []string               `json:"branches,omitempty"`
+	Ref           string                 `json:"ref,omitempty"`
+	RefType       string                 `json:"ref_type,omitempty"`
+	Refs          map[string]string      `json:"refs,omitempty"`
+	SHA           string                 `json:"sha,omitempty"`
+	Commit        *CommitSummary         `json:"commit,omitempty"`
+	CommitsBack   int                    `json:"commits_back,omitempty"`
+	Stale         bool                   `json:"stale,omitempty"`
+	Repo          *Repository            `json:"repo,omitempty"`
+	RepoURL       string                 `json:"repo_url,omitempty"`
+	ServerVersion string                 `json:"server_version,omitempty"`
+	FetchedAt     string                 `json:"fetched_at,omitempty"`
+	Raw           json.RawMessage        `json:"raw,omitempty"`
+	Retryable     *bool                  `json:"retryable,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+}
+
+// MinimalResponse is the reduced payload returned with minimal=true for high-volume polling
+type MinimalResponse struct {
+	State  string `json:"state"`
+	Symbol string `json:"symbol"`
+}
+
+// PendingResponse is the payload returned with pending_only=true, listing
+// the checks still running
+type PendingResponse struct {
+	Owner           string   `json:"owner"`
+	Repository      string   `json:"repository"`
+	Branch          string   `json:"branch"`
+	State           string   `json:"state"`
+	PendingContexts []string `json:"pending_contexts"`
 }
 
 // GiteaService handles interactions with Gitea API
@@ -37,6 +91,32 @@ type GiteaService struct {
 	BaseURL    string
 	Token      string
 	HTTPClient HTTPClient
+
+	// Mirrors are fallback base URLs tried in order when BaseURL fails
+	Mirrors []string
+
+	// ctx bounds the upstream requests made through this service
+	ctx context.Context
+}
+
+// withContext returns a copy of the service whose upstream requests are bound to ctx
+func (g *GiteaService) withContext(ctx context.Context) *GiteaService {
+	bound := *g
+	bound.ctx = ctx
+	return &bound
+}
+
+// forRepo returns a copy of the service authenticating with the repository's
+// token override from REPO_TOKENS, or the service itself when there is none
+func (g *GiteaService) forRepo(owner, repo string) *GiteaService {
+	repoToken, ok := config.RepoTokens[strings.ToLower(owner+"/"+repo)]
+	if !ok {
+		return g
+	}
+
+	bound := *g
+	bound.Token = repoToken
+	return &bound
 }
 
 // HTTPClient interface for testing
@@ -46,13 +126,19 @@ type HTTPClient interface {
 
 var (
 	giteaURL string
+	mirrors  []string
 	token    string
 	client   *http.Client
 	service  *GiteaService
 )
 
 func init() {
-	giteaURL = os.Getenv("GITEA_URL")
+	// GITEA_URLS lists the primary followed by its mirrors
+	if urls := splitList(os.Getenv("GITEA_URLS")); len(urls) > 0 {
+		giteaURL, mirrors = urls[0], urls[1:]
+	} else {
+		giteaURL = os.Getenv("GITEA_URL")
+	}
 	if giteaURL == "" {
 		log.Fatal("GITEA_URL environment variable is required")
 	}
@@ -62,32 +148,104 @@ func init() {
 		log.Fatal("TOKEN environment variable is required")
 	}
 
-	// Create HTTP client with timeout
-	client = &http.Client{
-		Timeout: 10 * time.Second,
+	var err error
+	if config, err = loadConfig(); err != nil {
+		log.Fatal(err)
 	}
 
+	traces = newTraceRing(config.TraceBufferSize)
+	cache = newCache(config)
+	maintenance.Store(config.MaintenanceMode)
+
+	// Create HTTP client with timeout
+	client = newHTTPClient(config)
+
 	// Initialize service
 	service = &GiteaService{
 		BaseURL:    giteaURL,
 		Token:      token,
 		HTTPClient: client,
+		Mirrors:    mirrors,
 	}
 }
 
-// GetDefaultBranch fetches the default branch for a repository
-func (g *GiteaService) GetDefaultBranch(owner, repo string) (string, error) {
-	url := fmt.Sprintf("%s/api/v1/repos/%s/%s", g.BaseURL, owner, repo)
+// upstreamTimeout bounds each request made to the Gitea API
+const upstreamTimeout = 10 * time.Second
+
+// newDialer builds the dialer used for upstream connections so that
+// connecting to an unreachable host fails within the dial timeout
+func newDialer(cfg Config) *net.Dialer {
+	return &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+}
+
+// newHTTPClient builds the upstream HTTP client from the resolved configuration
+func newHTTPClient(cfg Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = newDialer(cfg).DialContext
+
+	return &http.Client{
+		Timeout:       upstreamTimeout,
+		Transport:     transport,
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// apiURL joins path elements onto the API root of BaseURL, respecting any
+// subpath Gitea is mounted under and avoiding doubled slashes
+func (g *GiteaService) apiURL(elem ...string) (string, error) {
+	return url.JoinPath(g.BaseURL, append([]string{"api", "v1"}, elem...)...)
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// newRequest builds an authenticated GET request against the Gitea API
+func (g *GiteaService) newRequest(endpoint string) (*http.Request, error) {
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if g.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", g.Token))
+	}
+	signRequest(req)
+	return req, nil
+}
+
+// getJSON fetches endpoint and decodes the JSON response into v, failing
+// over to mirrors and retrying transient failures when configured. Non-200 responses are
+// returned as an *UpstreamError describing operation.
+func (g *GiteaService) getJSON(endpoint, operation string, v any) error {
+	err := g.retry(func(ctx context.Context) error {
+		return g.withContext(ctx).getJSONWithFailover(endpoint, operation, v)
+	})
+	return g.getJSONAnonymously(endpoint, operation, v, err)
+}
+
+// getJSONOnce makes a single attempt at fetching and decoding endpoint
+func (g *GiteaService) getJSONOnce(endpoint, operation string, v any) (err error) {
+	req, err := g.newRequest(endpoint)
+	if err != nil {
+		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", g.Token))
+
+	release, err := hostLimits.acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	start := time.Now()
+	defer func() { upstreamOutcomes.record(time.Since(start), isRetryable(err)) }()
 
 	resp, err := g.HTTPClient.Do(req)
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -97,11 +255,32 @@ func (g *GiteaService) GetDefaultBranch(owner, repo string) (string, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to get repository info: %d - %s", resp.StatusCode, string(body))
+		cleaned, nonJSON := cleanUpstreamBody(body)
+		return &UpstreamError{Operation: operation, StatusCode: resp.StatusCode, Body: cleaned, NonJSON: nonJSON}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// GetRepository fetches the metadata of a repository
+func (g *GiteaService) GetRepository(owner, repo string) (*Repository, error) {
+	endpoint, err := g.apiURL("repos", owner, repo)
+	if err != nil {
+		return nil, err
 	}
 
 	var repository Repository
-	if err := json.NewDecoder(resp.Body).Decode(&repository); err != nil {
+	if err := g.forRepo(owner, repo).getJSON(endpoint, "get repository info", &repository); err != nil {
+		return nil, err
+	}
+
+	return &repository, nil
+}
+
+// GetDefaultBranch fetches the default branch for a repository
+func (g *GiteaService) GetDefaultBranch(owner, repo string) (string, error) {
+	repository, err := g.GetRepository(owner, repo)
+	if err != nil {
 		return "", err
 	}
 
@@ -115,40 +294,61 @@ func getDefaultBranch(owner, repo string) (string, error) {
 
 // GetCommitStatus fetches the commit status for a repository
 func (g *GiteaService) GetCommitStatus(owner, repo, branch string) (*StatusResponse, error) {
-	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/commits/%s/status", g.BaseURL, owner, repo, branch)
-
-	req, err := http.NewRequest("GET", url, nil)
+	endpoint, err := g.apiURL("repos", owner, repo, "commits", branch, "status")
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", g.Token))
 
-	resp, err := g.HTTPClient.Do(req)
-	if err != nil {
+	// Keep the body as sent for debug=raw when it may be exposed
+	var status StatusResponse
+	var raw json.RawMessage
+	var target any = &status
+	if config.DebugRawUpstream {
+		target = &raw
+	}
+	if err := g.forRepo(owner, repo).getJSON(endpoint, "get commit status", target); err != nil {
+		if isUpstreamStatus(err, http.StatusNotFound) {
+			// No status available
+			return &StatusResponse{State: "unknown"}, nil
+		}
 		return nil, err
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Printf("Error closing response body: %v", err)
+	if raw != nil {
+		if err := json.Unmarshal(raw, &status); err != nil {
+			return nil, err
 		}
-	}()
-
-	if resp.StatusCode == http.StatusNotFound {
-		// No status available
-		return &StatusResponse{State: "unknown"}, nil
+		status.Raw = raw
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get commit status: %d - %s", resp.StatusCode, string(body))
+	// Gitea occasionally counts statuses without returning a combined state
+	// or the statuses themselves, so list them to roll the state up here.
+	// Gitea caps pages at 50 items by default.
+	if status.State == "" && status.TotalCount > 0 && len(status.Statuses) == 0 {
+		statuses, err := g.ListCommitStatuses(owner, repo, branch, min(status.TotalCount, 50))
+		if err != nil {
+			return nil, err
+		}
+		status.Statuses = statuses
 	}
 
-	var status StatusResponse
-	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+	return &status, nil
+}
+
+// ListCommitStatuses fetches up to limit individual statuses of a ref, newest first
+func (g *GiteaService) ListCommitStatuses(owner, repo, ref string, limit int) ([]CommitStatus, error) {
+	endpoint, err := g.apiURL("repos", owner, repo, "commits", ref, "statuses")
+	if err != nil {
 		return nil, err
 	}
 
-	return &status, nil
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+
+	var statuses []CommitStatus
+	if err := g.forRepo(owner, repo).getJSON(endpoint+"?"+query.Encode(), "list commit statuses", &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
 }
 
 // getCommitStatus is a wrapper for backward compatibility
@@ -156,14 +356,21 @@ func getCommitStatus(owner, repo, branch string) (*StatusResponse, error) {
 	return service.GetCommitStatus(owner, repo, branch)
 }
 
-// mapStateToSymbol converts Gitea state to a symbol
+// mapStateToSymbol converts Gitea state to a symbol, honoring any
+// operator-configured overrides
 func mapStateToSymbol(state string) string {
+	if symbol, ok := config.StateSymbols[state]; ok {
+		return symbol
+	}
+
 	symbolMap := map[string]string{
 		"success": "✓",
 		"failure": "✗",
 		"error":   "✗",
 		"pending": "●",
 		"warning": "⚠",
+		"skipped": "⊘",
+		"neutral": "–",
 		"unknown": "○",
 	}
 
@@ -173,14 +380,74 @@ func mapStateToSymbol(state string) string {
 	return "?"
 }
 
-// mapStateToHTTPCode converts Gitea state to appropriate HTTP status code
+// mapStateToColor converts Gitea state to a color name, honoring any
+// operator-configured palette overrides
+func mapStateToColor(state string) string {
+	if color, ok := config.StateColors[state]; ok {
+		return color
+	}
+
+	colorMap := map[string]string{
+		"success": "green",
+		"failure": "red",
+		"error":   "red",
+		"pending": "yellow",
+		"warning": "orange",
+		"skipped": "grey",
+		"neutral": "grey",
+		"unknown": "grey",
+	}
+
+	if color, ok := colorMap[state]; ok {
+		return color
+	}
+	return "grey"
+}
+
+// stateCodes gives each canonical state a stable number for clients that
+// store or graph states numerically. New states must only ever be appended.
+var stateCodes = map[string]int{
+	"success": 0,
+	"pending": 1,
+	"warning": 2,
+	"failure": 3,
+	"error":   4,
+	"unknown": 5,
+	"skipped": 6,
+	"neutral": 7,
+}
+
+// mapStateToCode converts Gitea state to its numeric code, treating
+// unrecognized states as unknown
+func mapStateToCode(state string) *int {
+	code, ok := stateCodes[state]
+	if !ok {
+		code = stateCodes["unknown"]
+	}
+	return &code
+}
+
+// isOk reports whether state counts as green: success, and warning too
+// when OK_INCLUDES_WARNING is set
+func isOk(state string) bool {
+	return state == "success" || (config.OkIncludesWarning && state == "warning")
+}
+
+// mapStateToHTTPCode converts Gitea state to appropriate HTTP status code,
+// honoring any operator-configured overrides
 func mapStateToHTTPCode(state string) int {
+	if code, ok := config.StateHTTPCodes[state]; ok {
+		return code
+	}
+
 	codeMap := map[string]int{
 		"success": http.StatusOK,                  // 200
 		"failure": http.StatusExpectationFailed,   // 417
 		"error":   http.StatusInternalServerError, // 500
 		"pending": http.StatusAccepted,            // 202
-		"warning": http.StatusOK,                  // 200 (successful but with warnings)
+		"warning": config.WarningHTTPCode,         // 200 by default (successful but with warnings)
+		"skipped": http.StatusOK,                  // 200 (nothing ran, nothing failed)
+		"neutral": http.StatusOK,                  // 200 (no CI configured)
 		"unknown": http.StatusNoContent,           // 204
 	}
 
@@ -190,107 +457,613 @@ func mapStateToHTTPCode(state string) int {
 	return http.StatusOK // default to 200
 }
 
+// writeJSON encodes v as the JSON response body with the given status code
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	if isPretty(w) {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(enveloped(w, v)); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// writeError writes msg as a JSON error shaped like a build status, so
+// clients handle every failure the same way
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, BuildStatusResponse{Error: msg, Retryable: retryableCode(code)})
+}
+
+// notFoundHandler answers paths no endpoint serves
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, "Not found")
+}
+
+// Output formats selected with the format query parameter
+const (
+	formatMatrix = "matrix"
+	formatNDJSON = "ndjson"
+	formatProm   = "prom"
+)
+
+// debugRaw is the debug query parameter value that includes Gitea's
+// unmodified combined status in /status responses
+const debugRaw = "raw"
+
+// StatusOptions controls which optional parts of a build status are computed
+type StatusOptions struct {
+	Detail      bool
+	Format      string
+	IncludeRepo bool
+	// IncludeCommit adds the author and message of the commit the status belongs to
+	IncludeCommit bool
+	Lookback      int
+	RequiredOnly  bool
+	// Raw includes Gitea's unmodified combined status, for debug=raw
+	Raw bool
+}
+
+// queryBool reports whether the named query parameter is set to a true value
+func queryBool(r *http.Request, name string) bool {
+	value, _ := strconv.ParseBool(r.URL.Query().Get(name))
+	return value
+}
+
+// resolveBuildStatus looks up the build status of a branch, using the
+// repository's default branch when none is given. The repository lookup
+// also supplies the metadata for include_repo. On failure the returned
+// response carries the error message alongside whatever was resolved.
+func resolveBuildStatus(ctx context.Context, owner, repo, branch string, opts StatusOptions) (BuildStatusResponse, error) {
+	response := BuildStatusResponse{
+		Owner:      owner,
+		Repository: repo,
+	}
+
+	if opts.IncludeRepo {
+		repository, err := service.withContext(ctx).GetRepository(canonicalName(owner), canonicalName(repo))
+		if err != nil {
+			response.Error = fmt.Sprintf("Failed to get repository info: %v", err)
+			return response, err
+		}
+		rememberDefaultBranch(canonicalName(owner), canonicalName(repo), repository)
+		if branch == "" {
+			branch = repository.DefaultBranch
+		}
+		response.Repo = repository
+	} else if branch == "" {
+		var err error
+		branch, err = fetchDefaultBranch(ctx, canonicalName(owner), canonicalName(repo))
+		if err != nil {
+			response.Error = fmt.Sprintf("Failed to get repository info: %v", err)
+			return response, err
+		}
+	}
+	response.Branch = branch
+
+	err := applyCommitStatus(ctx, &response, owner, repo, branch, opts)
+	return response, err
+}
+
+// applyCommitStatus fetches the combined status of ref (a branch, tag or
+// commit SHA) and fills in the state fields of response. Statuses are
+// served from the cache when one is configured. With a lookback, an
+// unknown ref falls back to the most recent earlier commit with a status.
+// With required_only, only the contexts branch protection requires count.
+// With include_commit, the commit the status belongs to is described.
+func applyCommitStatus(ctx context.Context, response *BuildStatusResponse, owner, repo, ref string, opts StatusOptions) error {
+	status, err := fetchCommitStatus(ctx, canonicalName(owner), canonicalName(repo), ref)
+	if err != nil {
+		response.Error = fmt.Sprintf("Failed to get commit status: %v", err)
+		return err
+	}
+
+	if opts.Lookback > 0 && rollupState(status) == "unknown" {
+		result, err := lookbackStatus(ctx, canonicalName(owner), canonicalName(repo), ref, opts.Lookback)
+		if err != nil {
+			response.Error = fmt.Sprintf("Failed to look back for commit status: %v", err)
+			return err
+		}
+		if result != nil {
+			status = result.status
+			response.SHA = result.sha
+			response.CommitsBack = result.distance
+		}
+	}
+
+	if opts.RequiredOnly && response.Branch != "" {
+		status, err = requiredStatuses(ctx, canonicalName(owner), canonicalName(repo), response.Branch, status)
+		if err != nil {
+			response.Error = fmt.Sprintf("Failed to get required contexts: %v", err)
+			return err
+		}
+	}
+
+	state := rollupState(status)
+	if config.NoCIState != "" && status.TotalCount == 0 && len(status.Statuses) == 0 {
+		state = config.NoCIState
+	}
+	if config.PendingGrace > 0 {
+		state = grace.smooth(cacheKey(canonicalName(owner), canonicalName(repo), ref), state, config.PendingGrace, time.Now())
+	}
+	if config.MaxStatusAge > 0 && isStale(status.Statuses, config.MaxStatusAge, time.Now()) {
+		response.Stale = true
+		// Stale results are at best a warning; worse states are kept as they are
+		if config.StaleDowngrade && stateSeverity(state) > stateSeverity("warning") {
+			state = "warning"
+		}
+	}
+	response.State = state
+	if !status.FetchedAt.IsZero() {
+		response.FetchedAt = status.FetchedAt.UTC().Format(time.RFC3339)
+	}
+	response.Ok = isOk(state)
+	if opts.Raw {
+		response.Raw = status.Raw
+	}
+	response.Symbol = mapStateToSymbol(state)
+	response.Color = mapStateToColor(state)
+	response.StateCode = mapStateToCode(state)
+	if opts.Detail {
+		latest := latestByContext(status.Statuses)
+		applyDetail(response, latest, opts.Format)
+		response.Summary = summarize(response.Branch, state, latest)
+		response.BuildDuration = durationString(buildDuration(status.Statuses))
+	}
+	if opts.IncludeCommit {
+		return applyCommitSummary(ctx, response, owner, repo, ref, status)
+	}
+	return nil
+}
+
+// fetchCommitStatus returns the combined status of ref, consulting the
+// cache first when CACHE_TTL is set. Cached statuses keep the time they
+// were originally fetched.
+func fetchCommitStatus(ctx context.Context, owner, repo, ref string) (*StatusResponse, error) {
+	if config.CacheTTL <= 0 {
+		status, err := service.withContext(ctx).GetCommitStatus(owner, repo, ref)
+		if err != nil {
+			return nil, err
+		}
+		status.FetchedAt = time.Now()
+		return status, nil
+	}
+
+	key := cacheKey(owner, repo, ref)
+	recordCacheKey(ctx, key)
+	if status, ok := cache.Get(ctx, key); ok {
+		return status, nil
+	}
+
+	status, err := service.withContext(ctx).GetCommitStatus(owner, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	status.FetchedAt = time.Now()
+	cache.Set(ctx, key, status, config.CacheTTL)
+	return status, nil
+}
+
 // statusHandler handles the /status endpoint
 func statusHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// Get query parameters
+	// Wrap the body for JSONP consumers when a callback is given
+	callback := r.URL.Query().Get("callback")
+	if callback != "" && !isValidCallback(callback) {
+		writeError(w, http.StatusBadRequest, "Invalid 'callback' parameter")
+		return
+	}
+	// Text and badge consumers share the URL with JSON clients
+	w.Header().Set("Vary", "Accept")
+	media := negotiateMedia(r.Header.Get("Accept"), statusMediaTypes)
+	if r.URL.Query().Get("format") == formatProm {
+		media = mediaPrometheus
+	}
+	write := func(code int, v any) {
+		response, full := v.(BuildStatusResponse)
+		if full && response.Error != "" && response.Retryable == nil {
+			response.Retryable = retryableCode(code)
+			v = response
+		}
+		if full {
+			annotateTrace(r, response)
+		}
+		logBody("response", r, v)
+		switch {
+		case callback != "":
+			writeJSONP(w, callback, v)
+		case full && media != mediaJSON:
+			writeRepresentation(w, code, media, response)
+		default:
+			writeJSON(w, code, v)
+		}
+	}
+
+	// Get query parameters, falling back to the configured owner
 	owner := r.URL.Query().Get("owner")
+	if owner == "" {
+		owner = config.DefaultOwner
+	}
 	repo := r.URL.Query().Get("repo")
 
-	if owner == "" || repo == "" {
-		response := BuildStatusResponse{
-			Error: "Both 'owner' and 'repo' query parameters are required",
+	// A repository ID takes the place of owner and repo, surviving renames
+	if rawID := r.URL.Query().Get("id"); rawID != "" {
+		id, ok := parseRepoID(rawID)
+		if !ok {
+			write(http.StatusBadRequest, BuildStatusResponse{
+				Error: "The 'id' parameter must be a positive integer",
+			})
+			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			log.Printf("Error encoding JSON response: %v", err)
+
+		var err error
+		owner, repo, err = service.withContext(r.Context()).GetRepositoryByID(id)
+		if err != nil {
+			code := errorHTTPCode(w, err)
+			if isUpstreamStatus(err, http.StatusNotFound) {
+				code = http.StatusNotFound
+			}
+			write(code, BuildStatusResponse{
+				Error:     quietError(fmt.Sprintf("Failed to get repository %d: %v", id, err), err),
+				Retryable: retryableFlag(err),
+			})
+			return
 		}
+	}
+
+	if owner == "" || repo == "" {
+		write(http.StatusBadRequest, BuildStatusResponse{
+			Error: "Both 'owner' and 'repo' query parameters are required",
+		})
 		return
 	}
 
-	// Get default branch
-	branch, err := getDefaultBranch(owner, repo)
-	if err != nil {
-		response := BuildStatusResponse{
+	if msg := validateParamLengths(owner, repo); msg != "" {
+		write(http.StatusBadRequest, BuildStatusResponse{Error: msg})
+		return
+	}
+
+	if !repoAllowed(owner, repo) {
+		write(http.StatusForbidden, BuildStatusResponse{
 			Owner:      owner,
 			Repository: repo,
-			Error:      fmt.Sprintf("Failed to get repository info: %v", err),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			log.Printf("Error encoding JSON response: %v", err)
+			Error:      notAllowedMessage(owner, repo),
+		})
+		return
+	}
+
+	opts := StatusOptions{
+		Detail:        queryBool(r, "detail"),
+		Format:        r.URL.Query().Get("format"),
+		IncludeRepo:   queryBool(r, "include_repo"),
+		IncludeCommit: queryBool(r, "include_commit"),
+		RequiredOnly:  queryBool(r, "required_only"),
+	}
+	// Pending contexts are read from the per-check statuses
+	pendingOnly := queryBool(r, "pending_only")
+	if pendingOnly {
+		opts.Detail = true
+		opts.Format = ""
+	}
+	if rawLookback := r.URL.Query().Get("lookback"); rawLookback != "" {
+		lookback, err := strconv.Atoi(rawLookback)
+		if err != nil || lookback < 0 || lookback > config.MaxLookback {
+			write(http.StatusBadRequest, BuildStatusResponse{
+				Error: fmt.Sprintf("The 'lookback' parameter must be between 0 and %d", config.MaxLookback),
+			})
+			return
 		}
+		opts.Lookback = lookback
+	}
+
+	symbolEncoding := r.URL.Query().Get("symbol_encoding")
+	if symbolEncoding != "" && symbolEncoding != symbolEncodingUnicode && symbolEncoding != symbolEncodingEntity {
+		write(http.StatusBadRequest, BuildStatusResponse{
+			Error: "The 'symbol_encoding' parameter must be 'unicode' or 'entity'",
+		})
 		return
 	}
 
-	// Get commit status
-	status, err := getCommitStatus(owner, repo, branch)
-	if err != nil {
-		response := BuildStatusResponse{
+	// Raw upstream output is only ever exposed when the operator enables it
+	if debug := r.URL.Query().Get("debug"); debug != "" {
+		if debug != debugRaw {
+			write(http.StatusBadRequest, BuildStatusResponse{
+				Error: "The 'debug' parameter must be 'raw'",
+			})
+			return
+		}
+		if !config.DebugRawUpstream {
+			write(http.StatusForbidden, BuildStatusResponse{
+				Error: "The 'debug=raw' mode is disabled; set DEBUG_RAW_UPSTREAM to enable it",
+			})
+			return
+		}
+		opts.Raw = true
+	}
+
+	// Collect the cache keys the lookup uses for the X-Cache-Key header
+	var keys *cacheKeyRecorder
+	if config.DebugCacheKeys {
+		keys = &cacheKeyRecorder{}
+		r = r.WithContext(context.WithValue(r.Context(), cacheKeyRecorderKey{}, keys))
+	}
+
+	var resolve func(ctx context.Context) (BuildStatusResponse, error)
+	if ref := r.URL.Query().Get("ref"); ref != "" {
+		refType, ok := refTypeParam(r)
+		if !ok {
+			write(http.StatusBadRequest, BuildStatusResponse{Error: invalidRefTypeMessage})
+			return
+		}
+		if refType == refTypeCommit && !isCommitSHA(ref) {
+			write(http.StatusBadRequest, BuildStatusResponse{
+				Error: fmt.Sprintf("The 'ref' parameter must be a commit SHA of %d to %d hex characters", minShortSHALength, fullSHALength),
+			})
+			return
+		}
+		if msg := validateParamLengths(ref); msg != "" {
+			write(http.StatusBadRequest, BuildStatusResponse{Error: msg})
+			return
+		}
+		resolve = func(ctx context.Context) (BuildStatusResponse, error) {
+			return resolveRefStatus(ctx, owner, repo, ref, refType, opts)
+		}
+	} else if rawRefs := r.URL.Query().Get("refs"); rawRefs != "" {
+		refs := splitList(rawRefs)
+		refType, ok := refTypeParam(r)
+		if !ok {
+			write(http.StatusBadRequest, BuildStatusResponse{Error: invalidRefTypeMessage})
+			return
+		}
+		if len(refs) > config.MaxBatchSize {
+			write(http.StatusBadRequest, BuildStatusResponse{
+				Error: fmt.Sprintf("At most %d refs are allowed", config.MaxBatchSize),
+			})
+			return
+		}
+		for _, ref := range refs {
+			if refType == refTypeCommit && !isCommitSHA(ref) {
+				write(http.StatusBadRequest, BuildStatusResponse{
+					Error: fmt.Sprintf("Every 'refs' entry must be a commit SHA of %d to %d hex characters", minShortSHALength, fullSHALength),
+				})
+				return
+			}
+		}
+		if msg := validateParamLengths(refs...); msg != "" {
+			write(http.StatusBadRequest, BuildStatusResponse{Error: msg})
+			return
+		}
+		resolve = func(ctx context.Context) (BuildStatusResponse, error) {
+			return resolveRefStates(ctx, owner, repo, refs, refType, opts)
+		}
+	} else if search := r.URL.Query().Get("commit_search"); search != "" {
+		if msg := validateParamLengths(search); msg != "" {
+			write(http.StatusBadRequest, BuildStatusResponse{Error: msg})
+			return
+		}
+		resolve = func(ctx context.Context) (BuildStatusResponse, error) {
+			return resolveCommitSearch(ctx, owner, repo, search, opts)
+		}
+	} else if rawBranches := r.URL.Query().Get("branches"); rawBranches != "" {
+		branches := splitList(rawBranches)
+		if len(branches) > config.MaxBatchSize {
+			write(http.StatusBadRequest, BuildStatusResponse{
+				Error: fmt.Sprintf("At most %d branches are allowed", config.MaxBatchSize),
+			})
+			return
+		}
+		if msg := validateParamLengths(branches...); msg != "" {
+			write(http.StatusBadRequest, BuildStatusResponse{Error: msg})
+			return
+		}
+		resolve = func(ctx context.Context) (BuildStatusResponse, error) {
+			return resolveWorstBranch(ctx, owner, repo, branches, opts)
+		}
+	} else {
+		resolve = func(ctx context.Context) (BuildStatusResponse, error) {
+			return resolveBuildStatus(ctx, owner, repo, "", opts)
+		}
+	}
+	if wait := throttleWait(r, owner, repo); wait > 0 {
+		w.Header().Set("Retry-After", retryAfterSeconds(wait))
+		write(http.StatusTooManyRequests, BuildStatusResponse{
 			Owner:      owner,
 			Repository: repo,
-			Branch:     branch,
-			Error:      fmt.Sprintf("Failed to get commit status: %v", err),
+			Error:      fmt.Sprintf("Repository '%s/%s' may be queried at most once every %s", owner, repo, config.MinRepoInterval),
+		})
+		return
+	}
+
+	fetch := func() (BuildStatusResponse, error) {
+		// Take turns with other repositories when MAX_CONCURRENT_LOOKUPS is reached
+		release, err := lookups.acquire(r.Context(), repoKey(owner, repo), config.MaxConcurrentLookups)
+		if err != nil {
+			return BuildStatusResponse{
+				Owner:      owner,
+				Repository: repo,
+				Error:      fmt.Sprintf("Gave up waiting for a lookup slot: %v", err),
+			}, err
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			log.Printf("Error encoding JSON response: %v", err)
+		defer release()
+
+		response, err := resolve(r.Context())
+		if err == nil && opts.IncludeRepo && response.Repo == nil {
+			response.Repo, err = service.withContext(r.Context()).GetRepository(canonicalName(owner), canonicalName(repo))
+			if err != nil {
+				response.Error = fmt.Sprintf("Failed to get repository info: %v", err)
+			}
 		}
-		return
+		if err == nil && queryBool(r, "include_links") {
+			if link, linkErr := repoURL(response.Owner, response.Repository); linkErr == nil {
+				response.RepoURL = link
+			}
+		}
+		if err == nil && queryBool(r, "include_server_version") {
+			applyServerVersion(r.Context(), &response)
+		}
+		if err == nil && symbolEncoding == symbolEncodingEntity {
+			encodeSymbolEntities(&response)
+		}
+		return response, err
 	}
 
-	// Build response
-	response := BuildStatusResponse{
-		Owner:      owner,
-		Repository: repo,
-		Branch:     branch,
-		State:      status.State,
-		Symbol:     mapStateToSymbol(status.State),
+	// A detailed ETag covers the payload actually sent, so pending_only
+	// clients are not woken by changes they cannot see
+	minimal := queryBool(r, "minimal")
+	payload := func(response BuildStatusResponse) any {
+		switch {
+		case media != mediaJSON && callback == "":
+			return response
+		case pendingOnly:
+			return PendingResponse{
+				Owner:           response.Owner,
+				Repository:      response.Repository,
+				Branch:          response.Branch,
+				State:           response.State,
+				PendingContexts: pendingContexts(response.Statuses),
+			}
+		case minimal:
+			return MinimalResponse{State: response.State, Symbol: response.Symbol}
+		}
+		return response
+	}
+	etagOf := func(response BuildStatusResponse) string {
+		if !opts.Detail {
+			// Without detail the tag follows only the overall state, so
+			// changes to individual checks do not invalidate caches
+			return weakETag([]any{media, response.Owner, response.Repository, response.Branch, response.State})
+		}
+		// The fetch time changes on every refresh without the status changing
+		response.FetchedAt = ""
+		if media != mediaJSON && callback == "" {
+			// Each representation needs its own tag
+			return responseETag([]any{media, response})
+		}
+		return responseETag(payload(response))
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(mapStateToHTTPCode(status.State))
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
+	var response BuildStatusResponse
+	var err error
+	if key := dedupKey(r); key != "" {
+		response, err = statusFlights.do(r.Context(), key, fetch)
+	} else {
+		response, err = fetch()
+	}
+	if err == nil && queryBool(r, "wait_for_change") {
+		response, err = waitForChange(r.Context(), owner, repo, r.Header.Get("If-None-Match"), response, fetch, etagOf)
+	}
+	if keys != nil {
+		if used := keys.get(); len(used) > 0 {
+			w.Header().Set(cacheKeyHeader, strings.Join(used, ", "))
+		}
+	}
+	if err != nil {
+		if clientGone(r, err) {
+			log.Printf("Client went away before %s/%s was resolved", owner, repo)
+			return
+		}
+		response.Error = withPermissionHint(quietError(response.Error, err), err)
+		response.Retryable = retryableFlag(err)
+		write(errorHTTPCode(w, err), response)
+		return
+	}
+
+	injectDelay(r.Context(), response.State)
+	etag := etagOf(response)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		annotateTrace(r, response)
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
+	body := payload(response)
+	if _, full := body.(BuildStatusResponse); !full {
+		annotateTrace(r, response)
+	}
+	code := mapStateToHTTPCode(response.State)
+	if media == mediaPrometheus {
+		// The state is in the sample, and a 204 for unknown would drop it
+		code = http.StatusOK
+	}
+	write(code, body)
 }
 
 // healthHandler provides a simple health check endpoint
 func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "ok"}); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
+	if queryBool(r, "detail") {
+		writeDetailedHealth(w, r)
+		return
 	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
 func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/status", statusHandler)
+	mux.HandleFunc("/status/batch", batchStatusHandler)
+	mux.HandleFunc("/diff", diffHandler)
+	mux.HandleFunc("/badge.svg", badgeHandler)
+	mux.HandleFunc("/org", orgHandler)
+	mux.HandleFunc("/watched", watchedHandler)
+	mux.HandleFunc("/history", historyHandler)
 	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/config", configHandler)
+	mux.HandleFunc("/debug/requests", debugRequestsHandler)
+	mux.HandleFunc("/webhook", webhookHandler)
+	mux.HandleFunc("/events", eventsHandler)
+	mux.HandleFunc("/maintenance", maintenanceHandler)
+	mux.HandleFunc("/", notFoundHandler)
 
-	// Log middleware
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		mux.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
-	})
+	guarded := traceRequests(reportMirror(reportAnonymous(withEnvelope(prettyJSON(limitQueryLength(withRequestDeadline(withMaintenance(mux))))))))
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	handler := logRequests(guarded)
 
-	log.Printf("Starting server on port %s", port)
 	log.Printf("Gitea URL: %s", giteaURL)
+	if config.TestMode {
+		log.Printf("WARNING: test mode is enabled; INJECT_DELAY latency will be applied")
+	}
+	if config.MaintenanceMode {
+		log.Printf("Maintenance mode is on; data endpoints answer 503")
+	}
+	if len(mirrors) > 0 {
+		log.Printf("Gitea mirrors: %s", strings.Join(mirrors, ", "))
+	}
+	if config.StartupCheck {
+		if err := checkStartup(service); err != nil {
+			log.Fatalf("Startup check failed: %v", err)
+		}
+	}
+
+	var listener net.Listener
+	if socketPath := os.Getenv("UNIX_SOCKET"); socketPath != "" {
+		var err error
+		if listener, err = listenUnix(socketPath); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Starting server on unix socket %s", socketPath)
+	} else {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+
+		var err error
+		if listener, err = net.Listen("tcp", ":"+port); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Starting server on port %s", port)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := http.ListenAndServe(":"+port, handler); err != nil {
+	if err := serve(ctx, newServer(handler), listener); err != nil {
 		log.Fatal(err)
 	}
 }
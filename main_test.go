@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 // MockHTTPClient implements HTTPClient interface for testing
@@ -38,6 +41,7 @@ func TestMapStateToSymbol(t *testing.T) {
 		{"error", "✗"},
 		{"pending", "●"},
 		{"warning", "⚠"},
+		{"skipped", "⊘"},
 		{"unknown", "○"},
 		{"invalid", "?"},
 		{"", "?"},
@@ -53,6 +57,122 @@ func TestMapStateToSymbol(t *testing.T) {
 	}
 }
 
+func TestMapStateToColor(t *testing.T) {
+	tests := []struct {
+		state    string
+		expected string
+	}{
+		{"success", "green"},
+		{"failure", "red"},
+		{"error", "red"},
+		{"pending", "yellow"},
+		{"warning", "orange"},
+		{"skipped", "grey"},
+		{"unknown", "grey"},
+		{"invalid", "grey"},
+		{"", "grey"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("state_%s", tt.state), func(t *testing.T) {
+			result := mapStateToColor(tt.state)
+			if result != tt.expected {
+				t.Errorf("mapStateToColor(%s) = %s, want %s", tt.state, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSkippedState(t *testing.T) {
+	tests := []struct {
+		name           string
+		symbols        map[string]string
+		codes          map[string]int
+		expectedSymbol string
+		expectedCode   int
+	}{
+		{"defaults", nil, nil, "⊘", http.StatusOK},
+		{"custom symbol", map[string]string{"skipped": "-"}, nil, "-", http.StatusOK},
+		{"no content code", nil, map[string]int{"skipped": http.StatusNoContent}, "⊘", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.StateSymbols = tt.symbols
+			config.StateHTTPCodes = tt.codes
+			defer func() { config = originalConfig }()
+
+			if symbol := mapStateToSymbol("skipped"); symbol != tt.expectedSymbol {
+				t.Errorf("mapStateToSymbol(skipped) = %s, want %s", symbol, tt.expectedSymbol)
+			}
+			if code := mapStateToHTTPCode("skipped"); code != tt.expectedCode {
+				t.Errorf("mapStateToHTTPCode(skipped) = %d, want %d", code, tt.expectedCode)
+			}
+		})
+	}
+
+	if state := worstState("skipped", "success"); state != "success" {
+		t.Errorf("Expected success to outrank skipped, got %s", state)
+	}
+	if state := worstState("skipped", "unknown"); state != "skipped" {
+		t.Errorf("Expected skipped to outrank unknown, got %s", state)
+	}
+}
+
+func TestMapStateToCode(t *testing.T) {
+	tests := []struct {
+		state    string
+		expected int
+	}{
+		{"success", 0},
+		{"pending", 1},
+		{"warning", 2},
+		{"failure", 3},
+		{"error", 4},
+		{"unknown", 5},
+		{"skipped", 6},
+		{"invalid", 5},
+		{"", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("state_%s", tt.state), func(t *testing.T) {
+			result := mapStateToCode(tt.state)
+			if result == nil || *result != tt.expected {
+				t.Errorf("mapStateToCode(%s) = %v, want %d", tt.state, result, tt.expected)
+			}
+		})
+	}
+
+	for _, state := range statePrecedence {
+		if _, ok := stateCodes[state]; !ok {
+			t.Errorf("Canonical state %q has no numeric code", state)
+		}
+	}
+}
+
+func TestMapStateToColor_PaletteOverride(t *testing.T) {
+	originalConfig := config
+	config.StateColors = map[string]string{"success": "#00ff00", "unknown": "silver"}
+	defer func() { config = originalConfig }()
+
+	tests := []struct {
+		state    string
+		expected string
+	}{
+		{"success", "#00ff00"},
+		{"unknown", "silver"},
+		{"failure", "red"},
+	}
+
+	for _, tt := range tests {
+		if result := mapStateToColor(tt.state); result != tt.expected {
+			t.Errorf("mapStateToColor(%s) = %s, want %s", tt.state, result, tt.expected)
+		}
+	}
+}
+
 func TestMapStateToHTTPCode(t *testing.T) {
 	tests := []struct {
 		state    string
@@ -63,6 +183,7 @@ func TestMapStateToHTTPCode(t *testing.T) {
 		{"error", http.StatusInternalServerError},
 		{"pending", http.StatusAccepted},
 		{"warning", http.StatusOK},
+		{"skipped", http.StatusOK},
 		{"unknown", http.StatusNoContent},
 		{"invalid", http.StatusOK},
 		{"", http.StatusOK},
@@ -208,7 +329,7 @@ func TestGiteaService_GetCommitStatus(t *testing.T) {
 			mockError: nil,
 			expectedStatus: &StatusResponse{
 				State:      "success",
-				Statuses:   []any{},
+				Statuses:   []CommitStatus{},
 				TotalCount: 1,
 			},
 			expectedError: "",
@@ -240,7 +361,7 @@ func TestGiteaService_GetCommitStatus(t *testing.T) {
 			mockError: nil,
 			expectedStatus: &StatusResponse{
 				State:      "pending",
-				Statuses:   []any{map[string]any{"state": "pending", "context": "ci/test"}},
+				Statuses:   []CommitStatus{{State: "pending", Context: "ci/test"}},
 				TotalCount: 1,
 			},
 			expectedError: "",
@@ -492,6 +613,7 @@ func TestStatusHandler(t *testing.T) {
 				HTTPClient: mockClient,
 			}
 			defer func() { service = originalService }()
+			resetBranchCache(t)
 
 			url := "/status"
 			if tt.queryParams != "" {
@@ -609,10 +731,19 @@ func TestStatusHandler_IntegrationFlow(t *testing.T) {
 		Repository: "testrepo",
 		Branch:     "main",
 		State:      "success",
+		StateCode:  mapStateToCode("success"),
+		Ok:         true,
 		Symbol:     "✓",
+		Color:      "green",
+	}
+
+	// The fetch time varies between runs
+	if response.FetchedAt == "" {
+		t.Error("Expected fetched_at to be set")
 	}
+	response.FetchedAt = ""
 
-	if response != expected {
+	if !reflect.DeepEqual(response, expected) {
 		t.Errorf("Expected response %+v, got %+v", expected, response)
 	}
 }
@@ -972,3 +1103,393 @@ func TestStatusHandler_JSONEncodeErrors(t *testing.T) {
 		})
 	}
 }
+
+// setMockService replaces the global service with one backed by doFunc for the duration of the test
+func setMockService(t *testing.T, doFunc func(req *http.Request) (*http.Response, error)) {
+	t.Helper()
+
+	originalService := service
+	service = &GiteaService{
+		BaseURL:    "https://git.example.com",
+		Token:      "test-token",
+		HTTPClient: &MockHTTPClient{DoFunc: doFunc},
+	}
+	t.Cleanup(func() { service = originalService })
+	resetBranchCache(t)
+}
+
+func TestNewHTTPClient_DialTimeout(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.DialTimeout = 250 * time.Millisecond
+
+	if dialer := newDialer(cfg); dialer.Timeout != cfg.DialTimeout {
+		t.Errorf("Expected dialer timeout %v, got %v", cfg.DialTimeout, dialer.Timeout)
+	}
+
+	client := newHTTPClient(cfg)
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected *http.Transport, got %T", client.Transport)
+	}
+	if transport.DialContext == nil {
+		t.Error("Expected transport to use the configured dialer")
+	}
+	if client.Timeout != 10*time.Second {
+		t.Errorf("Expected overall client timeout 10s, got %v", client.Timeout)
+	}
+}
+
+func TestStatusHandler_DefaultOwner(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultOwner   string
+		queryParams    string
+		expectedStatus int
+		expectedOwner  string
+	}{
+		{"default applied", "defaultorg", "repo=testrepo", http.StatusOK, "defaultorg"},
+		{"explicit owner overrides", "defaultorg", "owner=otherorg&repo=testrepo", http.StatusOK, "otherorg"},
+		{"owner required without default", "", "repo=testrepo", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.DefaultOwner = tt.defaultOwner
+			defer func() { config = originalConfig }()
+
+			var requestedPaths []string
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				requestedPaths = append(requestedPaths, req.URL.Path)
+				if !strings.Contains(req.URL.Path, "/commits/") {
+					return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+				}
+				return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?"+tt.queryParams, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.Owner != tt.expectedOwner {
+				t.Errorf("Expected owner '%s', got '%s'", tt.expectedOwner, response.Owner)
+			}
+			if tt.expectedOwner != "" && !strings.HasPrefix(requestedPaths[0], "/api/v1/repos/"+tt.expectedOwner+"/") {
+				t.Errorf("Expected upstream request for owner '%s', got %s", tt.expectedOwner, requestedPaths[0])
+			}
+		})
+	}
+}
+
+func TestGiteaService_SubpathBaseURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		call     func(g *GiteaService) error
+		expected string
+	}{
+		{
+			name:    "default branch under subpath",
+			baseURL: "https://host.example.com/git",
+			call: func(g *GiteaService) error {
+				_, err := g.GetDefaultBranch("testowner", "testrepo")
+				return err
+			},
+			expected: "https://host.example.com/git/api/v1/repos/testowner/testrepo",
+		},
+		{
+			name:    "commit status under subpath with trailing slash",
+			baseURL: "https://host.example.com/git/",
+			call: func(g *GiteaService) error {
+				_, err := g.GetCommitStatus("testowner", "testrepo", "main")
+				return err
+			},
+			expected: "https://host.example.com/git/api/v1/repos/testowner/testrepo/commits/main/status",
+		},
+		{
+			name:    "root with trailing slash",
+			baseURL: "https://git.example.com/",
+			call: func(g *GiteaService) error {
+				_, err := g.GetCommitStatus("testowner", "testrepo", "main")
+				return err
+			},
+			expected: "https://git.example.com/api/v1/repos/testowner/testrepo/commits/main/status",
+		},
+		{
+			name:    "repeated trailing slashes",
+			baseURL: "https://git.example.com//",
+			call: func(g *GiteaService) error {
+				_, err := g.GetDefaultBranch("testowner", "testrepo")
+				return err
+			},
+			expected: "https://git.example.com/api/v1/repos/testowner/testrepo",
+		},
+		{
+			name:    "branch with slashes",
+			baseURL: "https://host.example.com/git",
+			call: func(g *GiteaService) error {
+				_, err := g.GetCommitStatus("testowner", "testrepo", "feature/login")
+				return err
+			},
+			expected: "https://host.example.com/git/api/v1/repos/testowner/testrepo/commits/feature/login/status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested string
+			service := &GiteaService{
+				BaseURL: tt.baseURL,
+				Token:   "test-token",
+				HTTPClient: &MockHTTPClient{
+					DoFunc: func(req *http.Request) (*http.Response, error) {
+						requested = req.URL.String()
+						return createHTTPResponse(200, `{"default_branch": "main", "state": "success"}`), nil
+					},
+				},
+			}
+
+			if err := tt.call(service); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if requested != tt.expected {
+				t.Errorf("Expected URL %s, got %s", tt.expected, requested)
+			}
+			if strings.Contains(strings.TrimPrefix(requested, "https://"), "//") {
+				t.Errorf("Expected no doubled slashes, got %s", requested)
+			}
+		})
+	}
+}
+
+func TestRepoTokenOverride(t *testing.T) {
+	originalConfig := config
+	config.RepoTokens = map[string]string{"acme/private": "private-token"}
+	defer func() { config = originalConfig }()
+
+	tests := []struct {
+		owner, repo   string
+		expectedToken string
+	}{
+		{"acme", "private", "private-token"},
+		{"Acme", "Private", "private-token"},
+		{"acme", "public", "test-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.owner+"/"+tt.repo, func(t *testing.T) {
+			var tokens []string
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				tokens = append(tokens, req.Header.Get("Authorization"))
+				if strings.HasSuffix(req.URL.Path, "/status") {
+					return createHTTPResponse(200, `{"state": "success"}`), nil
+				}
+				return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+			})
+
+			if _, err := resolveBuildStatus(context.Background(), tt.owner, tt.repo, "", StatusOptions{}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, got := range tokens {
+				if got != "token "+tt.expectedToken {
+					t.Errorf("Expected Authorization 'token %s', got '%s'", tt.expectedToken, got)
+				}
+			}
+			if len(tokens) != 2 {
+				t.Errorf("Expected 2 upstream requests, got %d", len(tokens))
+			}
+		})
+	}
+}
+
+func TestStatusHandler_IncludeRepo(t *testing.T) {
+	var repoLookups int
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/status") {
+			return createHTTPResponse(200, `{"state": "success"}`), nil
+		}
+		if strings.Contains(req.URL.Path, "/branches/") {
+			return createHTTPResponse(200, `{"commit": {"id": "abc123"}}`), nil
+		}
+		repoLookups++
+		return createHTTPResponse(200, `{
+            "default_branch": "main",
+            "description": "Build tooling",
+            "private": true,
+            "stars_count": 42,
+            "full_name": "testowner/testrepo"
+        }`), nil
+	})
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedRepo *Repository
+		lookups      int
+	}{
+		{"omitted", "owner=testowner&repo=testrepo", nil, 1},
+		{"default branch", "owner=testowner&repo=testrepo&include_repo=true",
+			&Repository{DefaultBranch: "main", Description: "Build tooling", Private: true, Stars: 42}, 1},
+		{"explicit ref", "owner=testowner&repo=testrepo&ref=main&include_repo=true",
+			&Repository{DefaultBranch: "main", Description: "Build tooling", Private: true, Stars: 42}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoLookups = 0
+			req := httptest.NewRequest("GET", "/status?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if !reflect.DeepEqual(response.Repo, tt.expectedRepo) {
+				t.Errorf("Expected repo %+v, got %+v", tt.expectedRepo, response.Repo)
+			}
+			if repoLookups != tt.lookups {
+				t.Errorf("Expected %d repository lookups, got %d", tt.lookups, repoLookups)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_Minimal(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/status") {
+			return createHTTPResponse(200, `{"state": "pending"}`), nil
+		}
+		return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&minimal=true", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("Expected status %d, got %d", http.StatusAccepted, rr.Code)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	expected := map[string]any{"state": "pending", "symbol": "●"}
+	if !reflect.DeepEqual(payload, expected) {
+		t.Errorf("Expected minimal payload %v, got %v", expected, payload)
+	}
+}
+
+func TestIsOk(t *testing.T) {
+	states := []string{"success", "warning", "pending", "failure", "error", "skipped", "neutral", "unknown", "bogus"}
+	tests := []struct {
+		includeWarning bool
+		ok             map[string]bool
+	}{
+		{false, map[string]bool{"success": true}},
+		{true, map[string]bool{"success": true, "warning": true}},
+	}
+
+	for _, tt := range tests {
+		originalConfig := config
+		config.OkIncludesWarning = tt.includeWarning
+		for _, state := range states {
+			if got := isOk(state); got != tt.ok[state] {
+				t.Errorf("OK_INCLUDES_WARNING=%v: isOk(%q) = %v, want %v", tt.includeWarning, state, got, tt.ok[state])
+			}
+		}
+		config = originalConfig
+	}
+}
+
+func TestStatusHandler_Ok(t *testing.T) {
+	for _, includeWarning := range []bool{false, true} {
+		for _, state := range []string{"success", "warning", "failure"} {
+			t.Run(fmt.Sprintf("%s/include_warning=%v", state, includeWarning), func(t *testing.T) {
+				originalConfig := config
+				defer func() { config = originalConfig }()
+				config.OkIncludesWarning = includeWarning
+
+				setMockService(t, func(req *http.Request) (*http.Response, error) {
+					return createHTTPResponse(200, fmt.Sprintf(`{"state": %q, "statuses": [], "total_count": 1}`, state)), nil
+				})
+
+				req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+				rr := httptest.NewRecorder()
+				statusHandler(rr, req)
+
+				var response BuildStatusResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Could not parse response JSON: %v", err)
+				}
+				expected := state == "success" || (includeWarning && state == "warning")
+				if response.Ok != expected {
+					t.Errorf("Expected ok %v, got %v", expected, response.Ok)
+				}
+			})
+		}
+	}
+}
+
+func TestErrorResponses_AreJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		code    int
+		message string
+	}{
+		{"status wrong method", statusHandler, "POST", "/status?owner=o&repo=r", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"batch wrong method", batchStatusHandler, "GET", "/status/batch", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"org wrong method", orgHandler, "DELETE", "/org?org=o", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"config wrong method", configHandler, "POST", "/config", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"webhook wrong method", webhookHandler, "GET", "/webhook", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"badge missing repo", badgeHandler, "GET", "/badge.svg?owner=o", http.StatusBadRequest, "At least one 'repo' query parameter is required"},
+		{"unknown path", notFoundHandler, "GET", "/nope", http.StatusNotFound, "Not found"},
+		{"missing API key", configHandler, "GET", "/config", http.StatusUnauthorized, "A valid API key is required"},
+		{"query too long", limitQueryLength(http.HandlerFunc(statusHandler)).ServeHTTP, "GET", "/status?owner=" + strings.Repeat("o", 64), http.StatusRequestURITooLong, "Query string exceeds 32 characters"},
+		{"events missing repo", eventsHandler, "GET", "/events?owner=o", http.StatusBadRequest, "Both 'owner' and 'repo' query parameters are required"},
+		{"webhook without secret", webhookHandler, "POST", "/webhook", http.StatusForbidden, "Set WEBHOOK_SECRET to accept webhooks"},
+	}
+
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.APIKey = "s3cret"
+	config.MaxQueryLength = 32
+	config.WebhookSecret = ""
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			rr := httptest.NewRecorder()
+			tt.handler(rr, req)
+
+			if rr.Code != tt.code {
+				t.Errorf("Expected status %d, got %d", tt.code, rr.Code)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Expected Content-Type application/json, got %q", contentType)
+			}
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Expected a JSON body, got %q: %v", rr.Body.String(), err)
+			}
+			if response.Error != tt.message {
+				t.Errorf("Expected error %q, got %q", tt.message, response.Error)
+			}
+			if response.Retryable == nil || *response.Retryable {
+				t.Errorf("Expected the error to be flagged as not retryable, got %s", rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// maintenanceMessage explains the 503 data endpoints answer during maintenance
+const maintenanceMessage = "The service is in maintenance mode; build statuses are unavailable until it ends"
+
+// dataEndpoints are the paths that query Gitea and are therefore paused
+// during maintenance. Health, configuration, history and webhook delivery
+// keep working.
+var dataEndpoints = map[string]bool{
+	"/status":       true,
+	"/status/batch": true,
+	"/diff":         true,
+	"/badge.svg":    true,
+	"/org":          true,
+	"/watched":      true,
+}
+
+// maintenance is on while Gitea is being worked on. It starts from
+// MAINTENANCE_MODE and can be toggled at runtime through /maintenance.
+var maintenance atomic.Bool
+
+// MaintenanceResponse reports whether maintenance mode is on, and is the
+// body data endpoints answer with while it is
+type MaintenanceResponse struct {
+	Maintenance bool   `json:"maintenance"`
+	Error       string `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the maintenance mode using the configured field naming style
+func (m MaintenanceResponse) MarshalJSON() ([]byte, error) {
+	type plain MaintenanceResponse
+	return marshalWithNaming(plain(m))
+}
+
+// withMaintenance answers data endpoints with a 503 while maintenance mode
+// is on, rather than letting lookups fail one by one against Gitea
+func withMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if maintenance.Load() && dataEndpoints[r.URL.Path] {
+			writeJSON(w, http.StatusServiceUnavailable, MaintenanceResponse{
+				Maintenance: true,
+				Error:       maintenanceMessage,
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// maintenanceHandler handles the /maintenance endpoint. GET reports the
+// current mode and POST with enabled=true or enabled=false switches it.
+// Switching requires API_KEY to be configured, so that the data endpoints
+// cannot be turned off by anyone who can reach the service.
+func maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, MaintenanceResponse{Maintenance: maintenance.Load()})
+	case http.MethodPost:
+		if config.APIKey == "" {
+			writeJSON(w, http.StatusForbidden, MaintenanceResponse{
+				Maintenance: maintenance.Load(),
+				Error:       "Set API_KEY to switch maintenance mode at runtime",
+			})
+			return
+		}
+		if !requireAPIKey(w, r) {
+			return
+		}
+
+		enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, MaintenanceResponse{
+				Maintenance: maintenance.Load(),
+				Error:       "The 'enabled' parameter must be 'true' or 'false'",
+			})
+			return
+		}
+		maintenance.Store(enabled)
+		writeJSON(w, http.StatusOK, MaintenanceResponse{Maintenance: enabled})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setMaintenance switches maintenance mode for the duration of the test
+func setMaintenance(t *testing.T, enabled bool) {
+	t.Helper()
+
+	original := maintenance.Load()
+	maintenance.Store(enabled)
+	t.Cleanup(func() { maintenance.Store(original) })
+}
+
+// maintenanceMux routes every endpoint the way main does
+func maintenanceMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", statusHandler)
+	mux.HandleFunc("/status/batch", batchStatusHandler)
+	mux.HandleFunc("/diff", diffHandler)
+	mux.HandleFunc("/badge.svg", badgeHandler)
+	mux.HandleFunc("/org", orgHandler)
+	mux.HandleFunc("/history", historyHandler)
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/maintenance", maintenanceHandler)
+	return withMaintenance(mux)
+}
+
+func TestWithMaintenance_DataEndpoints(t *testing.T) {
+	setMaintenance(t, true)
+	upstreamCalls := 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		upstreamCalls++
+		return createHTTPResponse(200, `{"state": "success"}`), nil
+	})
+
+	for _, target := range []string{
+		"/status?owner=myorg&repo=myrepo",
+		"/status/batch",
+		"/diff?owner=myorg&repo=myrepo&base=main&head=dev",
+		"/badge.svg?owner=myorg&repo=myrepo",
+		"/org?owner=myorg",
+	} {
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+		maintenanceMux().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected status 503, got %d", target, rr.Code)
+		}
+		var response MaintenanceResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: could not parse response JSON: %v", target, err)
+		}
+		if !response.Maintenance || response.Error != maintenanceMessage {
+			t.Errorf("%s: expected the maintenance message, got %+v", target, response)
+		}
+	}
+	if upstreamCalls != 0 {
+		t.Errorf("Expected no upstream calls during maintenance, got %d", upstreamCalls)
+	}
+}
+
+func TestWithMaintenance_HealthStaysOk(t *testing.T) {
+	setMaintenance(t, true)
+
+	for _, target := range []string{"/health", "/history?owner=myorg&repo=myrepo&branch=main"} {
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+		maintenanceMux().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200 during maintenance, got %d: %s", target, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestWithMaintenance_Off(t *testing.T) {
+	setMaintenance(t, false)
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+	rr := httptest.NewRecorder()
+	maintenanceMux().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
+
+func TestMaintenanceHandler_Toggle(t *testing.T) {
+	setMaintenance(t, false)
+
+	tests := []struct {
+		name         string
+		apiKey       string
+		sentKey      string
+		method       string
+		query        string
+		expectedCode int
+		expectedMode bool
+	}{
+		{"report mode", "secret", "", "GET", "", http.StatusOK, false},
+		{"switch on", "secret", "secret", "POST", "enabled=true", http.StatusOK, true},
+		{"wrong key", "secret", "guess", "POST", "enabled=false", http.StatusUnauthorized, true},
+		{"no api key configured", "", "", "POST", "enabled=false", http.StatusForbidden, true},
+		{"invalid value", "secret", "secret", "POST", "enabled=maybe", http.StatusBadRequest, true},
+		{"switch off", "secret", "secret", "POST", "enabled=false", http.StatusOK, false},
+	}
+
+	// The cases run in order, each starting from the mode the last one left
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.APIKey = tt.apiKey
+			defer func() { config = originalConfig }()
+
+			req := httptest.NewRequest(tt.method, "/maintenance?"+tt.query, nil)
+			if tt.sentKey != "" {
+				req.Header.Set("X-API-Key", tt.sentKey)
+			}
+			rr := httptest.NewRecorder()
+			maintenanceHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if maintenance.Load() != tt.expectedMode {
+				t.Errorf("Expected maintenance mode %v, got %v", tt.expectedMode, maintenance.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// limitQueryLength rejects requests whose raw query string exceeds the
+// configured maximum before they reach any handler
+func limitQueryLength(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.RawQuery) > config.MaxQueryLength {
+			writeError(w, http.StatusRequestURITooLong, fmt.Sprintf("Query string exceeds %d characters", config.MaxQueryLength))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// prettyWriter marks a response whose JSON should be indented for reading
+type prettyWriter struct {
+	http.ResponseWriter
+}
+
+// Flush passes flushes through for streaming responses
+func (p prettyWriter) Flush() {
+	if flusher, ok := p.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (p prettyWriter) Unwrap() http.ResponseWriter {
+	return p.ResponseWriter
+}
+
+// isPretty reports whether JSON written to w should be indented
+func isPretty(w http.ResponseWriter) bool {
+	_, ok := w.(prettyWriter)
+	return ok
+}
+
+// prettyJSON indents the JSON of every endpoint when the request has
+// pretty=true, for reading responses with curl
+func prettyJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if queryBool(r, "pretty") {
+			w = prettyWriter{w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validateParamLengths returns an error message when any of the given
+// request values is longer than the configured maximum, or "" when all fit
+func validateParamLengths(values ...string) string {
+	for _, value := range values {
+		if len(value) > config.MaxParamLength {
+			return fmt.Sprintf("Parameter values must be at most %d characters", config.MaxParamLength)
+		}
+	}
+	return ""
+}
+
+// endpointDeadline returns the deadline for requests to path: the
+// endpoint's own STATUS_DEADLINE, BATCH_DEADLINE or ORG_DEADLINE when set,
+// since batch and org lookups legitimately take longer, and
+// REQUEST_DEADLINE otherwise
+func endpointDeadline(path string) time.Duration {
+	overrides := map[string]time.Duration{
+		"/status":       config.StatusDeadline,
+		"/status/batch": config.BatchDeadline,
+		"/org":          config.OrgDeadline,
+	}
+	if deadline := overrides[path]; deadline > 0 {
+		return deadline
+	}
+	return config.RequestDeadline
+}
+
+// longLived reports whether a request is meant to stay open: event streams
+// and long polls, which LONG_POLL_TIMEOUT and the client bound instead
+func longLived(r *http.Request) bool {
+	return r.URL.Path == "/events" || queryBool(r, "wait_for_change")
+}
+
+// withRequestDeadline bounds each request, including its upstream calls, by
+// the deadline for its endpoint and announces it in the X-Max-Duration
+// header so clients can avoid timing out before the service does
+func withRequestDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline := endpointDeadline(r.URL.Path)
+		if deadline <= 0 || longLived(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), deadline)
+		defer cancel()
+
+		w.Header().Set("X-Max-Duration", strconv.FormatFloat(deadline.Seconds(), 'f', -1, 64))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// injectDelay sleeps for the delay configured for state in INJECT_DELAY,
+// returning early if the request is cancelled. It does nothing unless
+// TEST_MODE is enabled, so a stray INJECT_DELAY cannot slow production.
+func injectDelay(ctx context.Context, state string) {
+	if !config.TestMode {
+		return
+	}
+	delay, ok := config.InjectDelay[state]
+	if !ok {
+		return
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// logRequests logs each request with its status and duration. Requests
+// answered with a 4xx or 5xx are always logged; others are logged at the
+// fraction set by LOG_SAMPLE_RATE.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status < http.StatusBadRequest && rand.Float64() >= config.LogSampleRate {
+			return
+		}
+		log.Printf("%s %s %d %s", r.Method, r.URL.Path, recorder.status, time.Since(start))
+	})
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitQueryLength(t *testing.T) {
+	originalConfig := config
+	config.MaxQueryLength = 32
+	defer func() { config = originalConfig }()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{"within limit", "owner=testowner&repo=testrepo", http.StatusOK},
+		{"over limit", "owner=testowner&repo=" + strings.Repeat("r", 32), http.StatusRequestURITooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			limitQueryLength(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_ParamLengthGuard(t *testing.T) {
+	upstreamCalls := 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		upstreamCalls++
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	tests := []struct {
+		name           string
+		owner          string
+		expectedStatus int
+		expectedCalls  int
+	}{
+		{"normal owner", "testowner", http.StatusOK, 2},
+		{"over-length owner", strings.Repeat("o", config.MaxParamLength+1), http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstreamCalls = 0
+			req := httptest.NewRequest("GET", "/status?owner="+tt.owner+"&repo=testrepo", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if upstreamCalls != tt.expectedCalls {
+				t.Errorf("Expected %d upstream calls, got %d", tt.expectedCalls, upstreamCalls)
+			}
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if tt.expectedStatus == http.StatusBadRequest && !strings.Contains(response.Error, "at most") {
+				t.Errorf("Expected length error, got '%s'", response.Error)
+			}
+		})
+	}
+}
+
+func TestWithRequestDeadline(t *testing.T) {
+	tests := []struct {
+		name           string
+		deadline       time.Duration
+		expectedHeader string
+	}{
+		{"no deadline configured", 0, ""},
+		{"whole seconds", 5 * time.Second, "5"},
+		{"fractional seconds", 2500 * time.Millisecond, "2.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.RequestDeadline = tt.deadline
+			defer func() { config = originalConfig }()
+
+			var hasDeadline bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, hasDeadline = r.Context().Deadline()
+			})
+
+			req := httptest.NewRequest("GET", "/status", nil)
+			rr := httptest.NewRecorder()
+			withRequestDeadline(next).ServeHTTP(rr, req)
+
+			if header := rr.Header().Get("X-Max-Duration"); header != tt.expectedHeader {
+				t.Errorf("Expected X-Max-Duration '%s', got '%s'", tt.expectedHeader, header)
+			}
+			if hasDeadline != (tt.deadline > 0) {
+				t.Errorf("Expected request deadline set = %v, got %v", tt.deadline > 0, hasDeadline)
+			}
+		})
+	}
+}
+
+func TestWithRequestDeadline_PerEndpoint(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectedHeader string
+	}{
+		{"status override", "/status", "2"},
+		{"batch override", "/status/batch", "30"},
+		{"org override", "/org", "60"},
+		{"other endpoints use the request deadline", "/badge", "8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.RequestDeadline = 8 * time.Second
+			config.StatusDeadline = 2 * time.Second
+			config.BatchDeadline = 30 * time.Second
+			config.OrgDeadline = time.Minute
+			defer func() { config = originalConfig }()
+
+			var remaining time.Duration
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, _ := r.Context().Deadline()
+				remaining = time.Until(deadline)
+			})
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+			withRequestDeadline(next).ServeHTTP(rr, req)
+
+			if header := rr.Header().Get("X-Max-Duration"); header != tt.expectedHeader {
+				t.Errorf("Expected X-Max-Duration '%s', got '%s'", tt.expectedHeader, header)
+			}
+			expected, _ := time.ParseDuration(tt.expectedHeader + "s")
+			if remaining > expected || remaining < expected-time.Second {
+				t.Errorf("Expected a deadline of about %v, got %v remaining", expected, remaining)
+			}
+		})
+	}
+}
+
+func TestWithRequestDeadline_EndpointOverrideWithoutRequestDeadline(t *testing.T) {
+	originalConfig := config
+	config.RequestDeadline = 0
+	config.BatchDeadline = 30 * time.Second
+	defer func() { config = originalConfig }()
+
+	for path, expected := range map[string]string{"/status/batch": "30", "/status": ""} {
+		req := httptest.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		withRequestDeadline(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(rr, req)
+
+		if header := rr.Header().Get("X-Max-Duration"); header != expected {
+			t.Errorf("%s: expected X-Max-Duration '%s', got '%s'", path, expected, header)
+		}
+	}
+}
+
+func TestWithRequestDeadline_BoundsUpstreamCalls(t *testing.T) {
+	originalConfig := config
+	config.RequestDeadline = time.Second
+	defer func() { config = originalConfig }()
+
+	var upstreamDeadline bool
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		_, upstreamDeadline = req.Context().Deadline()
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo", nil)
+	rr := httptest.NewRecorder()
+	withRequestDeadline(http.HandlerFunc(statusHandler)).ServeHTTP(rr, req)
+
+	if !upstreamDeadline {
+		t.Error("Expected upstream request to carry the request deadline")
+	}
+}
+
+func TestLogRequests_Sampling(t *testing.T) {
+	originalConfig := config
+	config.LogSampleRate = 0.1
+	defer func() { config = originalConfig }()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	handler := logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	const calls = 5000
+	for i := 0; i < calls; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	}
+
+	failures := strings.Count(logs.String(), "GET /fail 500")
+	successes := strings.Count(logs.String(), "GET /ok 200")
+	if failures != calls {
+		t.Errorf("Expected every failing request to be logged, got %d of %d", failures, calls)
+	}
+	// 10% of 5000 is 500; allow generous slack so the test is not flaky
+	if successes < 350 || successes > 650 {
+		t.Errorf("Expected about 10%% of successful requests to be logged, got %d of %d", successes, calls)
+	}
+}
+
+func TestStatusHandler_InjectDelay(t *testing.T) {
+	setMockService(t, branchStatesMock(map[string]string{"main": "pending", "release": "success"}))
+
+	const delay = 100 * time.Millisecond
+	tests := []struct {
+		name      string
+		testMode  bool
+		branch    string
+		expectLag bool
+	}{
+		{"test mode, configured state", true, "main", true},
+		{"test mode, other state", true, "release", false},
+		{"production, configured state", false, "main", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.TestMode = tt.testMode
+			config.InjectDelay = map[string]time.Duration{"pending": delay}
+			defer func() { config = originalConfig }()
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branches="+tt.branch, nil)
+			rr := httptest.NewRecorder()
+			start := time.Now()
+			statusHandler(rr, req)
+			elapsed := time.Since(start)
+
+			if lagged := elapsed >= delay; lagged != tt.expectLag {
+				t.Errorf("Expected delay applied=%v, took %v", tt.expectLag, elapsed)
+			}
+		})
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	handler := prettyJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, BuildStatusResponse{Owner: "testowner", State: "success"})
+	}))
+
+	tests := []struct {
+		query  string
+		pretty bool
+	}{
+		{"", false},
+		{"?pretty=false", false},
+		{"?pretty=true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/status"+tt.query, nil))
+
+			body := strings.TrimSuffix(rr.Body.String(), "\n")
+			indented := strings.Contains(body, "\n  \"owner\": \"testowner\",")
+			if indented != tt.pretty || strings.Contains(body, "\n") != tt.pretty {
+				t.Errorf("Expected pretty=%v output, got %q", tt.pretty, body)
+			}
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || response.State != "success" {
+				t.Errorf("Expected valid JSON either way, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPrettyJSON_JSONP(t *testing.T) {
+	handler := prettyJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONP(w, "cb", map[string]string{"state": "success"})
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/status?pretty=true", nil))
+
+	if expected := "/**/cb({\n  \"state\": \"success\"\n});"; rr.Body.String() != expected {
+		t.Errorf("Expected indented JSONP %q, got %q", expected, rr.Body.String())
+	}
+}
+
+func TestWithRequestDeadline_ExemptsLongLivedRequests(t *testing.T) {
+	originalConfig := config
+	config.RequestDeadline = 50 * time.Millisecond
+	config.StatusDeadline = 50 * time.Millisecond
+	defer func() { config = originalConfig }()
+
+	for _, target := range []string{"/events?owner=o&repo=r", "/status?owner=o&repo=r&wait_for_change=true"} {
+		var hasDeadline bool
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, hasDeadline = r.Context().Deadline()
+		})
+
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+		withRequestDeadline(next).ServeHTTP(rr, req)
+
+		if hasDeadline || rr.Header().Get("X-Max-Duration") != "" {
+			t.Errorf("%s: expected no deadline for a long-lived request", target)
+		}
+	}
+}
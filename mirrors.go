@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// mirrorHeader names the response header reporting which Gitea served a request
+const mirrorHeader = "X-Gitea-Upstream"
+
+// mirrorRecorder remembers which Gitea most recently served an upstream
+// call made on behalf of a request, as reported by mirrorLabel
+type mirrorRecorder struct {
+	mu     sync.Mutex
+	served string
+}
+
+func (m *mirrorRecorder) record(served string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.served = served
+}
+
+func (m *mirrorRecorder) get() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.served
+}
+
+type mirrorRecorderKey struct{}
+
+// mirrorWriter adds the mirror header just before the response headers are sent
+type mirrorWriter struct {
+	http.ResponseWriter
+	recorder    *mirrorRecorder
+	wroteHeader bool
+}
+
+func (m *mirrorWriter) WriteHeader(code int) {
+	if !m.wroteHeader {
+		m.wroteHeader = true
+		if served := m.recorder.get(); served != "" {
+			m.Header().Set(mirrorHeader, served)
+		}
+	}
+	m.ResponseWriter.WriteHeader(code)
+}
+
+func (m *mirrorWriter) Write(b []byte) (int, error) {
+	if !m.wroteHeader {
+		m.WriteHeader(http.StatusOK)
+	}
+	return m.ResponseWriter.Write(b)
+}
+
+// Flush passes through to the underlying writer so streaming endpoints keep working
+func (m *mirrorWriter) Flush() {
+	if flusher, ok := m.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (m *mirrorWriter) Unwrap() http.ResponseWriter {
+	return m.ResponseWriter
+}
+
+// reportMirror reports in the X-Gitea-Upstream header which Gitea served
+// the upstream calls of each request
+func reportMirror(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &mirrorRecorder{}
+		ctx := context.WithValue(r.Context(), mirrorRecorderKey{}, recorder)
+		next.ServeHTTP(&mirrorWriter{ResponseWriter: w, recorder: recorder}, r.WithContext(ctx))
+	})
+}
+
+// mirrorLabel names the Gitea at index in the primary-then-mirrors order
+// for the mirror header: its base URL, or with QUIET_ERRORS set only the
+// index, 0 being the primary, so internal hostnames are not exposed
+func mirrorLabel(index int, baseURL string) string {
+	if config.QuietErrors {
+		return strconv.Itoa(index)
+	}
+	return baseURL
+}
+
+// apiRoot returns the API root under a Gitea base URL
+func apiRoot(baseURL string) (string, error) {
+	return url.JoinPath(baseURL, "api", "v1")
+}
+
+// shouldFailover reports whether a failed upstream call should be tried on
+// the next mirror: transport failures and server-side errors, but not
+// cancellations or answers Gitea gave deliberately
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// getJSONWithFailover fetches endpoint from the primary Gitea, falling back
+// through the configured mirrors in order when it fails. The base URL that
+// answered is recorded for the mirror header.
+func (g *GiteaService) getJSONWithFailover(endpoint, operation string, v any) error {
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	primaryRoot, err := apiRoot(g.BaseURL)
+	if err != nil || len(g.Mirrors) == 0 || !strings.HasPrefix(endpoint, primaryRoot) {
+		return g.getJSONOnce(endpoint, operation, v)
+	}
+	suffix := strings.TrimPrefix(endpoint, primaryRoot)
+
+	for i, baseURL := range append([]string{g.BaseURL}, g.Mirrors...) {
+		root, rootErr := apiRoot(baseURL)
+		if rootErr != nil {
+			err = rootErr
+			continue
+		}
+
+		err = g.getJSONOnce(root+suffix, operation, v)
+		if err == nil {
+			if recorder, ok := ctx.Value(mirrorRecorderKey{}).(*mirrorRecorder); ok {
+				recorder.record(mirrorLabel(i, baseURL))
+			}
+			return nil
+		}
+		if !shouldFailover(ctx, err) {
+			return err
+		}
+	}
+	return err
+}
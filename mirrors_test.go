@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReportMirror_FailsOverToSecondary(t *testing.T) {
+	tests := []struct {
+		name           string
+		primary        func(req *http.Request) (*http.Response, error)
+		expectedCode   int
+		expectedMirror string
+		expectedCalls  int
+	}{
+		{
+			name: "primary healthy",
+			primary: func(req *http.Request) (*http.Response, error) {
+				return nil, nil
+			},
+			expectedCode:   http.StatusOK,
+			expectedMirror: "https://git.example.com",
+			expectedCalls:  0,
+		},
+		{
+			name: "primary unreachable",
+			primary: func(req *http.Request) (*http.Response, error) {
+				return nil, connectionRefused(req.URL.String())
+			},
+			expectedCode:   http.StatusOK,
+			expectedMirror: "https://replica.example.com/gitea",
+			expectedCalls:  2,
+		},
+		{
+			name: "primary erroring",
+			primary: func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(502, `bad gateway`), nil
+			},
+			expectedCode:   http.StatusOK,
+			expectedMirror: "https://replica.example.com/gitea",
+			expectedCalls:  2,
+		},
+		{
+			name: "primary answers not found",
+			primary: func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+			},
+			expectedCode:  http.StatusInternalServerError,
+			expectedCalls: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secondaryCalls := 0
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				if req.URL.Host == "git.example.com" {
+					if resp, err := tt.primary(req); resp != nil || err != nil {
+						return resp, err
+					}
+				} else {
+					secondaryCalls++
+					if !strings.HasPrefix(req.URL.Path, "/gitea/api/v1/") {
+						t.Errorf("Expected mirror subpath to be kept, got %s", req.URL.Path)
+					}
+				}
+				if strings.HasSuffix(req.URL.Path, "/status") {
+					return createHTTPResponse(200, `{"state": "success"}`), nil
+				}
+				return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+			})
+			service.Mirrors = []string{"https://replica.example.com/gitea"}
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo", nil)
+			rr := httptest.NewRecorder()
+			reportMirror(http.HandlerFunc(statusHandler)).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get(mirrorHeader); got != tt.expectedMirror {
+				t.Errorf("Expected %s %q, got %q", mirrorHeader, tt.expectedMirror, got)
+			}
+			if secondaryCalls != tt.expectedCalls {
+				t.Errorf("Expected %d calls to the mirror, got %d", tt.expectedCalls, secondaryCalls)
+			}
+		})
+	}
+}
+
+func TestGetJSONWithFailover_TrailingSlashBaseURLs(t *testing.T) {
+	var requested []string
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		if req.URL.Host == "git.example.com" {
+			return nil, connectionRefused(req.URL.String())
+		}
+		return createHTTPResponse(200, `{"state": "success"}`), nil
+	})
+	service.BaseURL = "https://git.example.com/"
+	service.Mirrors = []string{"https://replica.example.com/gitea/"}
+
+	if _, err := service.GetCommitStatus("testowner", "testrepo", "main"); err != nil {
+		t.Fatalf("Expected the mirror to answer, got %v", err)
+	}
+
+	expected := []string{
+		"https://git.example.com/api/v1/repos/testowner/testrepo/commits/main/status",
+		"https://replica.example.com/gitea/api/v1/repos/testowner/testrepo/commits/main/status",
+	}
+	if strings.Join(requested, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected requests %v, got %v", expected, requested)
+	}
+}
+
+func TestReportMirror_QuietErrorsHidesBaseURL(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.QuietErrors = true
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "git.example.com" {
+			return nil, connectionRefused(req.URL.String())
+		}
+		if strings.HasSuffix(req.URL.Path, "/status") {
+			return createHTTPResponse(200, `{"state": "success"}`), nil
+		}
+		return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+	})
+	service.Mirrors = []string{"https://replica.example.com/gitea"}
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo", nil)
+	rr := httptest.NewRecorder()
+	reportMirror(http.HandlerFunc(statusHandler)).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get(mirrorHeader); got != "1" {
+		t.Errorf("Expected %s to report the mirror index 1, got %q", mirrorHeader, got)
+	}
+}
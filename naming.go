@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Field naming styles for JSON responses
+const (
+	namingSnake = "snake"
+	namingCamel = "camel"
+)
+
+// How the error field of /status responses is written when there is no error
+const (
+	errorFieldOmit   = "omit"
+	errorFieldAlways = "always"
+)
+
+// snakeToCamel converts a snake_case key such as "total_count" to "totalCount"
+func snakeToCamel(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// isEmptyValue mirrors encoding/json's omitempty rules
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// marshalWithNaming encodes a struct using its json tags, renaming keys to
+// the configured field naming style. Keys keep their declaration order,
+// embedded structs are inlined as encoding/json does, and nested response
+// types apply the same style through their own marshallers. Every type
+// written in a response body has such a marshaller, except embedded ones
+// like RepositoryResults, whose MarshalJSON would be promoted to and
+// replace that of the type embedding them.
+func marshalWithNaming(v any) ([]byte, error) {
+	if config.FieldNaming != namingCamel {
+		return json.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	if err := writeNamedFields(&buf, reflect.ValueOf(v), &first); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// writeNamedFields writes the fields of a struct value as renamed keys,
+// setting first to false once a field has been written
+func writeNamedFields(buf *bytes.Buffer, value reflect.Value, first *bool) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			if err := writeNamedFields(buf, value.Field(i), first); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(options, "omitempty") && isEmptyValue(value.Field(i)) {
+			continue
+		}
+
+		encoded, err := json.Marshal(value.Field(i).Interface())
+		if err != nil {
+			return err
+		}
+		key, err := json.Marshal(snakeToCamel(name))
+		if err != nil {
+			return err
+		}
+
+		if !*first {
+			buf.WriteByte(',')
+		}
+		*first = false
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the response using the configured field naming
+// style. With ERROR_FIELD=always, an empty error is written rather than
+// omitted, for clients whose schemas require the field.
+func (b BuildStatusResponse) MarshalJSON() ([]byte, error) {
+	type plain BuildStatusResponse
+	encoded, err := marshalWithNaming(plain(b))
+	if err != nil || b.Error != "" || config.ErrorField != errorFieldAlways {
+		return encoded, err
+	}
+	// Error is the last field, so it goes where omitempty left it out
+	return append(encoded[:len(encoded)-1], `,"error":""}`...), nil
+}
+
+// MarshalJSON encodes the pending checks using the configured field naming style
+func (p PendingResponse) MarshalJSON() ([]byte, error) {
+	type plain PendingResponse
+	return marshalWithNaming(plain(p))
+}
+
+// MarshalJSON encodes the repository metadata using the configured field naming style
+func (r Repository) MarshalJSON() ([]byte, error) {
+	type plain Repository
+	return marshalWithNaming(plain(r))
+}
+
+// MarshalJSON encodes the check using the configured field naming style
+func (c ContextStatus) MarshalJSON() ([]byte, error) {
+	type plain ContextStatus
+	return marshalWithNaming(plain(c))
+}
+
+// MarshalJSON encodes the minimal response using the configured field naming style
+func (m MinimalResponse) MarshalJSON() ([]byte, error) {
+	type plain MinimalResponse
+	return marshalWithNaming(plain(m))
+}
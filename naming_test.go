@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSnakeToCamel(t *testing.T) {
+	tests := map[string]string{
+		"owner":       "owner",
+		"total_count": "totalCount",
+		"target_url":  "targetUrl",
+		"a__b":        "aB",
+	}
+	for input, expected := range tests {
+		if result := snakeToCamel(input); result != expected {
+			t.Errorf("snakeToCamel(%s) = %s, want %s", input, result, expected)
+		}
+	}
+}
+
+func TestBuildStatusResponse_FieldNaming(t *testing.T) {
+	response := BuildStatusResponse{
+		Owner:      "testowner",
+		Repository: "testrepo",
+		Branch:     "main",
+		State:      "failure",
+		Symbol:     "✗",
+		Color:      "red",
+		Statuses: []ContextStatus{
+			{Context: "ci/test", State: "failure", TargetURL: "https://ci.example.com/1"},
+		},
+		Groups: map[string]GroupStatus{"build_tools": {State: "failure", Count: 1}},
+	}
+
+	tests := []struct {
+		naming   string
+		expected string
+	}{
+		{
+			naming:   namingSnake,
+			expected: `{"owner":"testowner","repository":"testrepo","branch":"main","state":"failure","ok":false,"symbol":"✗","color":"red","statuses":[{"context":"ci/test","state":"failure","target_url":"https://ci.example.com/1"}],"groups":{"build_tools":{"state":"failure","count":1}}}`,
+		},
+		{
+			naming:   namingCamel,
+			expected: `{"owner":"testowner","repository":"testrepo","branch":"main","state":"failure","ok":false,"symbol":"✗","color":"red","statuses":[{"context":"ci/test","state":"failure","targetUrl":"https://ci.example.com/1"}],"groups":{"build_tools":{"state":"failure","count":1}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.naming, func(t *testing.T) {
+			originalConfig := config
+			config.FieldNaming = tt.naming
+			defer func() { config = originalConfig }()
+
+			body, err := json.Marshal(response)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if string(body) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, body)
+			}
+		})
+	}
+}
+
+// snakeKeys collects the object keys in a decoded JSON value that contain an underscore
+func snakeKeys(v any) []string {
+	var keys []string
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if strings.Contains(key, "_") {
+				keys = append(keys, key)
+			}
+			keys = append(keys, snakeKeys(value)...)
+		}
+	case []any:
+		for _, item := range v {
+			keys = append(keys, snakeKeys(item)...)
+		}
+	}
+	return keys
+}
+
+func TestResponseTypes_CamelNaming(t *testing.T) {
+	originalConfig := config
+	config.FieldNaming = namingCamel
+	defer func() { config = originalConfig }()
+
+	result := BuildStatusResponse{
+		Owner: "myorg", Repository: "api", State: "success", BuildDuration: "1m",
+		Matrix: []MatrixCell{{Context: "ci", State: "success", Symbol: "✓"}},
+	}
+	results := RepositoryResults{State: "pending", Results: []BuildStatusResponse{result}, TimedOut: 1}
+	responses := map[string]any{
+		"batch":       BatchResponse{Results: []BuildStatusResponse{result}, Succeeded: 1},
+		"org":         OrgResponse{Org: "myorg", RepositoryResults: results},
+		"watched":     WatchedResponse{RepositoryResults: results},
+		"diff":        DiffResponse{Owner: "myorg", Base: BranchState{Branch: "main"}, Head: BranchState{Branch: "dev"}},
+		"health":      HealthReport{Status: healthOK, Checks: []HealthCheck{{Name: "gitea", Status: healthOK}}},
+		"history":     HistoryResponse{Owner: "myorg", Transitions: []Transition{{State: "failure", PreviousState: "success"}}},
+		"maintenance": MaintenanceResponse{Maintenance: true, Error: "Down for maintenance"},
+		"trace":       RequestTrace{Method: "GET", StatusCode: 200},
+		"pending":     PendingResponse{PendingContexts: []string{"ci"}},
+		"minimal":     MinimalResponse{State: "success"},
+		"event":       StatusEvent{TargetURL: "https://ci.example.com/1"},
+		"config":      effectiveConfig(originalConfig),
+		"envelope":    Envelope{Data: result, Meta: EnvelopeMeta{RequestID: "abc"}},
+	}
+
+	for name, response := range responses {
+		t.Run(name, func(t *testing.T) {
+			body, err := json.Marshal(response)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var decoded any
+			if err := json.Unmarshal(body, &decoded); err != nil {
+				t.Fatalf("Could not parse %s: %v", body, err)
+			}
+			if keys := snakeKeys(decoded); len(keys) > 0 {
+				t.Errorf("Expected only camelCase keys, got %v in %s", keys, body)
+			}
+		})
+	}
+}
+
+func TestOrgResponse_CamelInlinesResults(t *testing.T) {
+	originalConfig := config
+	config.FieldNaming = namingCamel
+	defer func() { config = originalConfig }()
+
+	body, err := json.Marshal(OrgResponse{Org: "myorg", RepositoryResults: RepositoryResults{State: "pending", TimedOut: 2}, Error: "partial"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := `{"org":"myorg","state":"pending","symbol":"","color":"","results":null,"succeeded":0,"failed":0,"timedOut":2,"error":"partial"}`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+}
+
+func TestBuildStatusResponse_CamelOmitsEmptyFields(t *testing.T) {
+	originalConfig := config
+	config.FieldNaming = namingCamel
+	defer func() { config = originalConfig }()
+
+	body, err := json.Marshal(BuildStatusResponse{Error: "Something failed"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := `{"owner":"","repository":"","branch":"","state":"","ok":false,"symbol":"","color":"","error":"Something failed"}`
+	if string(body) != expected {
+		t.Errorf("Expected %s, got %s", expected, body)
+	}
+}
+
+func TestBuildStatusResponse_ErrorField(t *testing.T) {
+	tests := []struct {
+		errorField string
+		naming     string
+		response   BuildStatusResponse
+		expected   string
+	}{
+		{errorFieldOmit, namingSnake, BuildStatusResponse{State: "success"}, `{"owner":"","repository":"","branch":"","state":"success","ok":false,"symbol":"","color":""}`},
+		{errorFieldAlways, namingSnake, BuildStatusResponse{State: "success"}, `{"owner":"","repository":"","branch":"","state":"success","ok":false,"symbol":"","color":"","error":""}`},
+		{errorFieldAlways, namingCamel, BuildStatusResponse{State: "success", CommitsBack: 2}, `{"owner":"","repository":"","branch":"","state":"success","ok":false,"symbol":"","color":"","commitsBack":2,"error":""}`},
+		{errorFieldAlways, namingSnake, BuildStatusResponse{Error: "Something failed"}, `{"owner":"","repository":"","branch":"","state":"","ok":false,"symbol":"","color":"","error":"Something failed"}`},
+		{errorFieldOmit, namingSnake, BuildStatusResponse{Error: "Something failed"}, `{"owner":"","repository":"","branch":"","state":"","ok":false,"symbol":"","color":"","error":"Something failed"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.errorField+"/"+tt.naming+"/"+tt.response.Error, func(t *testing.T) {
+			originalConfig := config
+			config.ErrorField = tt.errorField
+			config.FieldNaming = tt.naming
+			defer func() { config = originalConfig }()
+
+			body, err := json.Marshal(tt.response)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if string(body) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, body)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_ErrorField(t *testing.T) {
+	t.Setenv("ERROR_FIELD", "always")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.ErrorField != errorFieldAlways {
+		t.Errorf("Expected error field '%s', got '%s'", errorFieldAlways, cfg.ErrorField)
+	}
+
+	t.Setenv("ERROR_FIELD", "null")
+	if _, err := loadConfig(); err == nil {
+		t.Error("Expected error for unsupported error field behavior, got nil")
+	}
+}
+
+func TestLoadConfig_FieldNaming(t *testing.T) {
+	t.Setenv("FIELD_NAMING", "camel")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.FieldNaming != namingCamel {
+		t.Errorf("Expected field naming '%s', got '%s'", namingCamel, cfg.FieldNaming)
+	}
+
+	t.Setenv("FIELD_NAMING", "kebab")
+	if _, err := loadConfig(); err == nil {
+		t.Error("Expected error for unsupported naming style, got nil")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Representations /status can produce, chosen from the Accept header
+const (
+	mediaJSON = "application/json"
+	mediaText = "text/plain"
+	mediaSVG  = "image/svg+xml"
+)
+
+// statusMediaTypes lists the /status representations in order of
+// preference when a client rates several equally
+var statusMediaTypes = []string{mediaJSON, mediaText, mediaSVG}
+
+// acceptQuality returns the quality accept gives to media, taken from the
+// most specific matching range, or -1 when no range matches
+func acceptQuality(accept, media string) float64 {
+	mediaType, mediaSubtype, _ := strings.Cut(media, "/")
+
+	quality, specificity := -1.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		rangeType, rangeSubtype, _ := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+
+		var rangeSpecificity int
+		switch {
+		case rangeType == mediaType && rangeSubtype == mediaSubtype:
+			rangeSpecificity = 2
+		case rangeType == mediaType && rangeSubtype == "*":
+			rangeSpecificity = 1
+		case rangeType == "*" && rangeSubtype == "*":
+			rangeSpecificity = 0
+		default:
+			continue
+		}
+		if rangeSpecificity <= specificity {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		quality, specificity = q, rangeSpecificity
+	}
+	return quality
+}
+
+// negotiateMedia picks the offer the Accept header rates highest, falling
+// back to the first offer when the header is missing or accepts none of them
+func negotiateMedia(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	best, bestQuality := offers[0], 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(accept, offer); q > bestQuality {
+			best, bestQuality = offer, q
+		}
+	}
+	return best
+}
+
+// statusText renders response as a single line for plain-text consumers
+func statusText(response BuildStatusResponse) string {
+	switch {
+	case response.Error != "":
+		return "error: " + response.Error
+	case response.Summary != "":
+		return response.Summary
+	}
+	return response.Symbol + " " + response.State
+}
+
+// writeRepresentation writes response as plain text, an SVG badge or a
+// Prometheus sample
+func writeRepresentation(w http.ResponseWriter, code int, media string, response BuildStatusResponse) {
+	var body string
+	switch media {
+	case mediaPrometheus:
+		body = statusMetric(response) + "\n"
+	case mediaSVG:
+		state := response.State
+		if response.Error != "" {
+			state = "error"
+		}
+		body = renderBadge("build", state, state)
+		w.Header().Set("Cache-Control", "no-cache")
+	default:
+		body = statusText(response) + "\n"
+	}
+
+	w.Header().Set("Content-Type", media+"; charset=utf-8")
+	w.WriteHeader(code)
+	fmt.Fprint(w, body)
+}
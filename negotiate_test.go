@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateMedia(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", mediaJSON},
+		{"application/json", mediaJSON},
+		{"text/plain", mediaText},
+		{"image/svg+xml", mediaSVG},
+		{"*/*", mediaJSON},
+		{"text/*", mediaText},
+		{"image/*;q=0.8, text/plain;q=0.5", mediaSVG},
+		{"text/plain;q=0.9, application/json;q=0.5", mediaText},
+		{"application/json;q=0.1, text/plain;q=0.1", mediaJSON},
+		{"*/*;q=0.1, text/plain;q=0", mediaJSON},
+		{"text/*;q=1, text/plain;q=0, image/svg+xml;q=0.5", mediaSVG},
+		{"text/html", mediaJSON},
+		{"TEXT/PLAIN", mediaText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := negotiateMedia(tt.accept, statusMediaTypes); got != tt.expected {
+				t.Errorf("Expected %s for Accept %q, got %s", tt.expected, tt.accept, got)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_ContentNegotiation(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "failure", "statuses": [], "total_count": 1}`), nil
+	})
+
+	tests := []struct {
+		accept       string
+		contentType  string
+		bodyContains string
+	}{
+		{"", "application/json", `"state":"failure"`},
+		{"application/json", "application/json", `"state":"failure"`},
+		{"text/plain", "text/plain; charset=utf-8", "✗ failure"},
+		{"image/svg+xml", "image/svg+xml; charset=utf-8", "<svg"},
+		{"text/plain;q=0.2, image/svg+xml;q=0.9", "image/svg+xml; charset=utf-8", ">failure</text>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != http.StatusExpectationFailed {
+				t.Errorf("Expected the failure status code 417 for every representation, got %d", rr.Code)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != tt.contentType {
+				t.Errorf("Expected Content-Type %s, got %s", tt.contentType, contentType)
+			}
+			if !strings.Contains(rr.Body.String(), tt.bodyContains) {
+				t.Errorf("Expected body to contain %q, got %s", tt.bodyContains, rr.Body.String())
+			}
+			if vary := rr.Header().Get("Vary"); vary != "Accept" {
+				t.Errorf("Expected Vary: Accept, got %q", vary)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_PlainTextError(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return nil, connectionRefused(req.URL.String())
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+	req.Header.Set("Accept", "text/plain")
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rr.Code)
+	}
+	if !strings.HasPrefix(rr.Body.String(), "error: ") {
+		t.Errorf("Expected a plain-text error, got %s", rr.Body.String())
+	}
+}
+
+func TestStatusHandler_NegotiatedETagsDiffer(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	etags := make(map[string]string)
+	for _, accept := range []string{"application/json", "text/plain", "image/svg+xml"} {
+		req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+		etag := rr.Header().Get("ETag")
+		if other, ok := etags[etag]; ok {
+			t.Errorf("Expected %s and %s to have different ETags, both got %s", accept, other, etag)
+		}
+		etags[etag] = accept
+	}
+}
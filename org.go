@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// orgPageSize is how many repositories are requested per page when listing an organization
+const orgPageSize = 50
+
+// maxListingPages bounds how many pages a repository listing follows
+const maxListingPages = 100
+
+// stateTimeout marks /org results still unresolved when ORG_TIMEOUT passed
+const stateTimeout = "timeout"
+
+// OrgRepository is the subset of an organization's repository listing used for aggregation
+type OrgRepository struct {
+	Name          string `json:"name"`
+	DefaultBranch string `json:"default_branch"`
+	Archived      bool   `json:"archived"`
+}
+
+// RepositoryResults aggregates the default branch statuses of a set of
+// repositories: the worst state along with each repository's result. It is
+// only used embedded, and named by the marshaller of the embedding type.
+type RepositoryResults struct {
+	State     string                `json:"state"`
+	Symbol    string                `json:"symbol"`
+	Color     string                `json:"color"`
+	Results   []BuildStatusResponse `json:"results"`
+	Succeeded int                   `json:"succeeded"`
+	Failed    int                   `json:"failed"`
+	TimedOut  int                   `json:"timed_out,omitempty"`
+}
+
+// OrgResponse represents the /org response: the aggregated statuses of an
+// organization's repositories
+type OrgResponse struct {
+	Org string `json:"org"`
+	RepositoryResults
+	Error string `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the organization results using the configured field naming style
+func (o OrgResponse) MarshalJSON() ([]byte, error) {
+	type plain OrgResponse
+	return marshalWithNaming(plain(o))
+}
+
+// GetOrgRepositories lists every repository of an organization
+func (g *GiteaService) GetOrgRepositories(org string) ([]OrgRepository, error) {
+	endpoint, err := g.apiURL("orgs", org, "repos")
+	if err != nil {
+		return nil, err
+	}
+	return getPaged[OrgRepository](g, endpoint, "list organization repositories")
+}
+
+// getPaged fetches every page of a listing endpoint, following pagination
+// until an empty page. Gitea may clamp limit to its MAX_RESPONSE_ITEMS, so a
+// short page is not necessarily the last one. An upstream that ignores page
+// would never return an empty one, so listings longer than maxListingPages
+// fail instead.
+func getPaged[T any](g *GiteaService, endpoint, operation string) ([]T, error) {
+	var items []T
+	for page := 1; page <= maxListingPages; page++ {
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(orgPageSize))
+
+		var batch []T
+		if err := g.getJSON(endpoint+"?"+query.Encode(), operation, &batch); err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			return items, nil
+		}
+		items = append(items, batch...)
+	}
+	return nil, fmt.Errorf("%s: more than %d pages", operation, maxListingPages)
+}
+
+// resolveOrg fetches the default branch status of every active repository in
+// org. Archived repositories and those outside REPO_ALLOWLIST are left out.
+func resolveOrg(ctx context.Context, org string) (OrgResponse, error) {
+	response := OrgResponse{Org: org}
+
+	repos, err := service.withContext(ctx).GetOrgRepositories(canonicalName(org))
+	if err != nil {
+		response.Error = fmt.Sprintf("Failed to list repositories of %s: %v", org, err)
+		return response, err
+	}
+
+	var entries []BatchEntry
+	for _, repo := range repos {
+		if repo.Archived || !repoAllowed(org, repo.Name) {
+			continue
+		}
+		entries = append(entries, BatchEntry{Owner: org, Repo: repo.Name, Branch: repo.DefaultBranch})
+	}
+
+	response.RepositoryResults = resolveRepositories(ctx, entries)
+	return response, nil
+}
+
+// resolveRepositories fetches the status of every entry, bounded by
+// ORG_CONCURRENCY. With ORG_TIMEOUT, lookups still running when it passes
+// are abandoned and reported with the timeout state, so slow repositories
+// do not hold up the rest.
+func resolveRepositories(ctx context.Context, entries []BatchEntry) RepositoryResults {
+	lookupCtx := ctx
+	if config.OrgTimeout > 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, config.OrgTimeout)
+		defer cancel()
+	}
+
+	aggregate := RepositoryResults{Results: make([]BuildStatusResponse, len(entries))}
+	forEachBatchEntry(lookupCtx, entries, config.OrgConcurrency, func(i int, result BuildStatusResponse, err error) {
+		// Only lookups cut off by ORG_TIMEOUT count as timed out; others
+		// failing around the same time keep their own error
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil && lookupCtx.Err() != nil {
+			aggregate.Results[i] = BuildStatusResponse{
+				Owner:      entries[i].Owner,
+				Repository: entries[i].Repo,
+				Branch:     entries[i].Branch,
+				State:      stateTimeout,
+				Symbol:     mapStateToSymbol(stateTimeout),
+				Color:      mapStateToColor(stateTimeout),
+			}
+			aggregate.TimedOut++
+			return
+		}
+		aggregate.Results[i] = result
+		if err != nil {
+			aggregate.Failed++
+		} else {
+			aggregate.Succeeded++
+		}
+	})
+
+	states := make([]string, len(aggregate.Results))
+	for i, result := range aggregate.Results {
+		states[i] = result.State
+		switch {
+		case result.Error != "":
+			states[i] = "error"
+		case result.State == stateTimeout:
+			// Unfinished lookups keep partial results from passing as green
+			states[i] = "pending"
+		}
+	}
+	aggregate.State = worstState(states...)
+	aggregate.Symbol = mapStateToSymbol(aggregate.State)
+	aggregate.Color = mapStateToColor(aggregate.State)
+	return aggregate
+}
+
+// orgHandler handles the /org endpoint
+func orgHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	org := r.URL.Query().Get("org")
+	if org == "" {
+		org = config.DefaultOwner
+	}
+	if org == "" {
+		writeJSON(w, http.StatusBadRequest, OrgResponse{Error: "The 'org' query parameter is required"})
+		return
+	}
+
+	if msg := validateParamLengths(org); msg != "" {
+		writeJSON(w, http.StatusBadRequest, OrgResponse{Error: msg})
+		return
+	}
+
+	response, err := resolveOrg(r.Context(), org)
+	if err != nil {
+		response.Error = quietError(response.Error, err)
+		writeJSON(w, errorHTTPCode(w, err), response)
+		return
+	}
+	writeJSON(w, mapStateToHTTPCode(response.State), response)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// orgListing builds a page of n repositories named repo-<offset+i>
+func orgListing(offset, n int) string {
+	repos := make([]string, n)
+	for i := range repos {
+		repos[i] = fmt.Sprintf(`{"name": "repo-%d", "default_branch": "main"}`, offset+i)
+	}
+	return "[" + strings.Join(repos, ",") + "]"
+}
+
+// pagedListing serves the given pages of a listing in turn, then empty pages
+func pagedListing(req *http.Request, pages ...string) *http.Response {
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+	if page < 1 || page > len(pages) {
+		return createHTTPResponse(200, `[]`)
+	}
+	return createHTTPResponse(200, pages[page-1])
+}
+
+func TestOrgHandler_RespectsOrgConcurrency(t *testing.T) {
+	originalConfig := config
+	config.OrgConcurrency = 3
+	config.BatchConcurrency = 10
+	defer func() { config = originalConfig }()
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/orgs/myorg/repos") {
+			return pagedListing(req, orgListing(0, orgPageSize), orgListing(orgPageSize, 10)), nil
+		}
+
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		time.Sleep(2 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/org?org=myorg", nil)
+	rr := httptest.NewRecorder()
+	orgHandler(rr, req)
+
+	var response OrgResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if rr.Code != http.StatusOK || response.State != "success" {
+		t.Errorf("Expected success with 200, got %q with %d", response.State, rr.Code)
+	}
+	if response.Succeeded != orgPageSize+10 {
+		t.Errorf("Expected every page to be resolved, got %d results", response.Succeeded)
+	}
+	if maxInFlight > config.OrgConcurrency {
+		t.Errorf("Expected at most %d concurrent lookups, got %d", config.OrgConcurrency, maxInFlight)
+	}
+}
+
+func TestOrgHandler_WorstStateSkippingArchived(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/orgs/myorg/repos"):
+			return pagedListing(req, `[
+                {"name": "api", "default_branch": "main"},
+                {"name": "web", "default_branch": "develop"},
+                {"name": "old", "default_branch": "main", "archived": true}
+            ]`), nil
+		case strings.Contains(req.URL.Path, "/repos/myorg/web/commits/develop/"):
+			return createHTTPResponse(200, `{"state": "failure", "statuses": [], "total_count": 1}`), nil
+		case strings.Contains(req.URL.Path, "/repos/myorg/old/"):
+			t.Error("Expected archived repository to be skipped")
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/org?org=myorg", nil)
+	rr := httptest.NewRecorder()
+	orgHandler(rr, req)
+
+	var response OrgResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if response.State != "failure" || len(response.Results) != 2 {
+		t.Errorf("Expected failure across 2 repositories, got %q across %d", response.State, len(response.Results))
+	}
+}
+
+func TestOrgHandler_ListingFails(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(404, `{"message": "not found"}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/org?org=missing", nil)
+	rr := httptest.NewRecorder()
+	orgHandler(rr, req)
+
+	if rr.Code != http.StatusInternalServerError || !strings.Contains(rr.Body.String(), "Failed to list repositories") {
+		t.Errorf("Expected listing failure to be reported, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestOrgHandler_TimeoutReturnsPartialResults(t *testing.T) {
+	originalConfig := config
+	config.OrgTimeout = 50 * time.Millisecond
+	config.OrgConcurrency = 4
+	defer func() { config = originalConfig }()
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/orgs/myorg/repos"):
+			return pagedListing(req, `[
+                {"name": "fast", "default_branch": "main"},
+                {"name": "slow", "default_branch": "main"},
+                {"name": "broken", "default_branch": "main"},
+                {"name": "stuck", "default_branch": "main"}
+            ]`), nil
+		case strings.Contains(req.URL.Path, "/repos/myorg/slow/"), strings.Contains(req.URL.Path, "/repos/myorg/stuck/"):
+			// Hang until the aggregation gives up on the repository
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		case strings.Contains(req.URL.Path, "/repos/myorg/broken/"):
+			return createHTTPResponse(200, `{"state": "failure", "statuses": [], "total_count": 1}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	start := time.Now()
+	req := httptest.NewRequest("GET", "/org?org=myorg", nil)
+	rr := httptest.NewRecorder()
+	orgHandler(rr, req)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the response shortly after ORG_TIMEOUT, took %s", elapsed)
+	}
+
+	var response OrgResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	expected := map[string]string{"fast": "success", "slow": stateTimeout, "broken": "failure", "stuck": stateTimeout}
+	for _, result := range response.Results {
+		if result.State != expected[result.Repository] {
+			t.Errorf("Expected %s to be %q, got %q (%s)", result.Repository, expected[result.Repository], result.State, result.Error)
+		}
+	}
+	if response.Succeeded != 2 || response.Failed != 0 || response.TimedOut != 2 {
+		t.Errorf("Expected 2 succeeded and 2 timed out, got %d succeeded, %d failed, %d timed out", response.Succeeded, response.Failed, response.TimedOut)
+	}
+	if response.State != "failure" || rr.Code != http.StatusExpectationFailed {
+		t.Errorf("Expected the finished failure to set the overall state, got %q with %d", response.State, rr.Code)
+	}
+}
+
+func TestOrgHandler_TimeoutIsNotGreen(t *testing.T) {
+	originalConfig := config
+	config.OrgTimeout = 50 * time.Millisecond
+	defer func() { config = originalConfig }()
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/orgs/myorg/repos"):
+			return pagedListing(req, `[
+                {"name": "fast", "default_branch": "main"},
+                {"name": "stuck", "default_branch": "main"}
+            ]`), nil
+		case strings.Contains(req.URL.Path, "/repos/myorg/stuck/"):
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/org?org=myorg", nil)
+	rr := httptest.NewRecorder()
+	orgHandler(rr, req)
+
+	var response OrgResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if response.TimedOut != 1 {
+		t.Fatalf("Expected one timed out repository, got %d", response.TimedOut)
+	}
+	if response.State != "pending" || rr.Code != mapStateToHTTPCode("pending") {
+		t.Errorf("Expected partial results to be pending, got %q with %d", response.State, rr.Code)
+	}
+}
+
+func TestOrgHandler_LateFailureIsNotATimeout(t *testing.T) {
+	originalConfig := config
+	config.OrgTimeout = 50 * time.Millisecond
+	defer func() { config = originalConfig }()
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/orgs/myorg/repos"):
+			return pagedListing(req, `[{"name": "gone", "default_branch": "main"}]`), nil
+		case strings.Contains(req.URL.Path, "/repos/myorg/gone/"):
+			// Gitea refuses access just as ORG_TIMEOUT passes
+			<-req.Context().Done()
+			return createHTTPResponse(403, `{"message": "Forbidden"}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/org?org=myorg", nil)
+	rr := httptest.NewRecorder()
+	orgHandler(rr, req)
+
+	var response OrgResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if response.TimedOut != 0 || response.Failed != 1 {
+		t.Fatalf("Expected the refusal to be reported as a failure, got %d failed, %d timed out: %s", response.Failed, response.TimedOut, rr.Body.String())
+	}
+	if result := response.Results[0]; result.State == stateTimeout || result.Error == "" {
+		t.Errorf("Expected the repository's own error, got %+v", result)
+	}
+}
+
+func TestOrgHandler_FollowsShortPages(t *testing.T) {
+	// Gitea clamps limit to its MAX_RESPONSE_ITEMS, here 2, so every page is short
+	var pages []string
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/orgs/myorg/repos") {
+			pages = append(pages, req.URL.Query().Get("page"))
+			return pagedListing(req, orgListing(0, 2), orgListing(2, 2), orgListing(4, 1)), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/org?org=myorg", nil)
+	rr := httptest.NewRecorder()
+	orgHandler(rr, req)
+
+	var response OrgResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if len(response.Results) != 5 {
+		t.Errorf("Expected the repositories of every page, got %d results over pages %v", len(response.Results), pages)
+	}
+}
+
+func TestOrgHandler_StopsWhenPageIsIgnored(t *testing.T) {
+	// An upstream ignoring page returns the same listing forever
+	calls := 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/orgs/myorg/repos") {
+			calls++
+			return createHTTPResponse(200, orgListing(0, 2)), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/org?org=myorg", nil)
+	rr := httptest.NewRecorder()
+	orgHandler(rr, req)
+
+	var response OrgResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if calls != maxListingPages {
+		t.Errorf("Expected the listing to stop after %d pages, got %d", maxListingPages, calls)
+	}
+	if rr.Code < http.StatusBadRequest || !strings.Contains(response.Error, "more than") {
+		t.Errorf("Expected the listing to fail, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
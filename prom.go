@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mediaPrometheus is the Prometheus text exposition format served with
+// format=prom, as read by node_exporter's textfile collector
+const mediaPrometheus = "text/plain; version=0.0.4"
+
+// promMetricName names the sample format=prom reports
+const promMetricName = "gitea_build_state"
+
+// promLabelEscaper escapes label values as the exposition format requires
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// statusMetric renders response as a single sample of its state code,
+// e.g. gitea_build_state{owner="x",repo="y",branch="main"} 0. Failed
+// lookups become a comment so collectors skip them rather than record a
+// misleading value.
+func statusMetric(response BuildStatusResponse) string {
+	if response.Error != "" {
+		return "# error: " + strings.ReplaceAll(response.Error, "\n", " ")
+	}
+
+	code := response.StateCode
+	if code == nil {
+		code = mapStateToCode(response.State)
+	}
+	return fmt.Sprintf(`%s{owner="%s",repo="%s",branch="%s"} %d`,
+		promMetricName,
+		promLabelEscaper.Replace(response.Owner),
+		promLabelEscaper.Replace(response.Repository),
+		promLabelEscaper.Replace(response.Branch),
+		*code)
+}
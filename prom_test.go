@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusHandler_PromFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"success", `{"state": "success", "statuses": [], "total_count": 1}`, `gitea_build_state{owner="myorg",repo="myrepo",branch="main"} 0` + "\n"},
+		{"failure", `{"state": "failure", "statuses": [], "total_count": 1}`, `gitea_build_state{owner="myorg",repo="myrepo",branch="main"} 3` + "\n"},
+		{"unknown", `{"state": "", "statuses": [], "total_count": 0}`, `gitea_build_state{owner="myorg",repo="myrepo",branch="main"} 5` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(200, tt.body), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main&format=prom", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", rr.Code)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != "text/plain; version=0.0.4; charset=utf-8" {
+				t.Errorf("Expected the exposition content type, got '%s'", contentType)
+			}
+			if rr.Body.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestStatusMetric(t *testing.T) {
+	tests := []struct {
+		name     string
+		response BuildStatusResponse
+		expected string
+	}{
+		{
+			"labels are escaped",
+			BuildStatusResponse{Owner: "my\"org", Repository: `back\slash`, Branch: "line\nbreak", State: "pending"},
+			`gitea_build_state{owner="my\"org",repo="back\\slash",branch="line\nbreak"} 1`,
+		},
+		{
+			"errors become a comment",
+			BuildStatusResponse{Owner: "myorg", Repository: "myrepo", Error: "Failed to get commit status:\nrefused"},
+			"# error: Failed to get commit status: refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusMetric(tt.response); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
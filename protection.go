@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path"
+)
+
+// BranchProtection represents the parts of a Gitea branch protection rule used for gating
+type BranchProtection struct {
+	EnableStatusCheck   bool     `json:"enable_status_check"`
+	StatusCheckContexts []string `json:"status_check_contexts"`
+}
+
+// GetRequiredContexts fetches the status contexts branch protection requires
+// on a branch. A branch without protection, or whose protection does not
+// check statuses, has no required contexts.
+func (g *GiteaService) GetRequiredContexts(owner, repo, branch string) ([]string, error) {
+	endpoint, err := g.apiURL("repos", owner, repo, "branch_protections", branch)
+	if err != nil {
+		return nil, err
+	}
+
+	var protection BranchProtection
+	if err := g.forRepo(owner, repo).getJSON(endpoint, "get branch protection", &protection); err != nil {
+		if isUpstreamStatus(err, http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !protection.EnableStatusCheck {
+		return nil, nil
+	}
+	return protection.StatusCheckContexts, nil
+}
+
+// matchesContext reports whether context matches any of the required
+// patterns, which Gitea allows to be globs
+func matchesContext(context string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == context {
+			return true
+		}
+		if matched, err := path.Match(pattern, context); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredStatuses narrows status to the contexts branch protection requires
+// on branch. Without required contexts the status is returned unchanged.
+// When no required check has reported yet the result is pending, matching
+// how Gitea gates merges.
+func requiredStatuses(ctx context.Context, owner, repo, branch string, status *StatusResponse) (*StatusResponse, error) {
+	required, err := service.withContext(ctx).GetRequiredContexts(owner, repo, branch)
+	if err != nil || len(required) == 0 {
+		return status, err
+	}
+
+	filtered := &StatusResponse{State: "pending", SHA: status.SHA, FetchedAt: status.FetchedAt, Raw: status.Raw}
+	for _, s := range latestByContext(status.Statuses) {
+		if matchesContext(s.Context, required) {
+			filtered.Statuses = append(filtered.Statuses, s)
+		}
+	}
+	filtered.TotalCount = len(filtered.Statuses)
+	return filtered, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatusHandler_RequiredOnly(t *testing.T) {
+	const statuses = `{
+        "state": "failure",
+        "statuses": [
+            {"status": "success", "context": "ci/build"},
+            {"status": "success", "context": "ci/test"},
+            {"status": "failure", "context": "coverage"}
+        ],
+        "total_count": 3
+    }`
+
+	tests := []struct {
+		name          string
+		query         string
+		protection    func() *http.Response
+		expectedState string
+		expectedCount int
+	}{
+		{
+			name:  "required contexts pass",
+			query: "&required_only=true",
+			protection: func() *http.Response {
+				return createHTTPResponse(200, `{"enable_status_check": true, "status_check_contexts": ["ci/*"]}`)
+			},
+			expectedState: "success",
+			expectedCount: 2,
+		},
+		{
+			name:  "required check not reported",
+			query: "&required_only=true",
+			protection: func() *http.Response {
+				return createHTTPResponse(200, `{"enable_status_check": true, "status_check_contexts": ["security"]}`)
+			},
+			expectedState: "pending",
+			expectedCount: 0,
+		},
+		{
+			name:  "status checks disabled",
+			query: "&required_only=true",
+			protection: func() *http.Response {
+				return createHTTPResponse(200, `{"enable_status_check": false, "status_check_contexts": ["ci/build"]}`)
+			},
+			expectedState: "failure",
+			expectedCount: 3,
+		},
+		{
+			name:  "no protection",
+			query: "&required_only=true",
+			protection: func() *http.Response {
+				return createHTTPResponse(404, `{"message": "Not Found"}`)
+			},
+			expectedState: "failure",
+			expectedCount: 3,
+		},
+		{
+			name:          "without required_only",
+			expectedState: "failure",
+			expectedCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				switch {
+				case strings.Contains(req.URL.Path, "/branch_protections/main"):
+					if tt.protection == nil {
+						t.Error("Unexpected branch protection lookup")
+						return createHTTPResponse(404, `{}`), nil
+					}
+					return tt.protection(), nil
+				case strings.HasSuffix(req.URL.Path, "/status"):
+					return createHTTPResponse(200, statuses), nil
+				}
+				return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&detail=true"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.State != tt.expectedState {
+				t.Errorf("Expected state %q, got %q", tt.expectedState, response.State)
+			}
+			if len(response.Statuses) != tt.expectedCount {
+				t.Errorf("Expected %d statuses, got %+v", tt.expectedCount, response.Statuses)
+			}
+		})
+	}
+}
+
+func TestMatchesContext(t *testing.T) {
+	patterns := []string{"ci/*", "lint"}
+	for context, expected := range map[string]bool{
+		"ci/build":    true,
+		"lint":        true,
+		"lint/extra":  false,
+		"deploy/prod": false,
+	} {
+		if got := matchesContext(context, patterns); got != expected {
+			t.Errorf("matchesContext(%q) = %v, want %v", context, got, expected)
+		}
+	}
+}
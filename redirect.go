@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// errRedirectLoop reports that Gitea, or a proxy in front of it, kept redirecting
+var errRedirectLoop = errors.New("redirect loop detected")
+
+// sameSite reports whether a redirect from one URL to another stays on the
+// same Gitea host. Ports are ignored so http to https upgrades qualify, but
+// downgrades from https to http do not.
+func sameSite(from, to *url.URL) bool {
+	if from.Scheme == "https" && to.Scheme != "https" {
+		return false
+	}
+	return from.Hostname() == to.Hostname()
+}
+
+// checkRedirect stops redirect loops and re-attaches the Authorization
+// header, which net/http drops whenever the host or port changes, on
+// redirects that stay on the same site. A redirect back to a URL already
+// visited, or past MAX_REDIRECTS, is reported as a loop naming the last URL.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			return fmt.Errorf("%w: %s redirected back to itself", errRedirectLoop, req.URL.Redacted())
+		}
+	}
+	if len(via) > config.MaxRedirects {
+		return fmt.Errorf("%w: gave up after %d redirects, last at %s", errRedirectLoop, config.MaxRedirects, req.URL.Redacted())
+	}
+
+	original := via[0]
+	auth := original.Header.Get("Authorization")
+	if auth != "" && req.Header.Get("Authorization") == "" && sameSite(original.URL, req.URL) {
+		req.Header.Set("Authorization", auth)
+	}
+
+	// The signature covers the old path, so it is redone for the new one
+	if config.SigningSecret != "" {
+		req.Header.Del(config.SigningHeader)
+		if sameSite(original.URL, req.URL) {
+			signRequest(req)
+		}
+	}
+	return nil
+}
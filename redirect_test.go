@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCheckRedirect_PreservesAuthorizationOnSameSite(t *testing.T) {
+	var receivedAuth string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedAuth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"version": "1.21.4"}`))
+	}))
+	defer target.Close()
+
+	// A different port on the same host, as with an http to https redirect
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+r.URL.Path, http.StatusMovedPermanently)
+	}))
+	defer redirector.Close()
+
+	g := &GiteaService{BaseURL: redirector.URL, Token: "test-token", HTTPClient: newHTTPClient(defaultConfig())}
+	version, err := g.GetVersion()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if version != "1.21.4" {
+		t.Errorf("Expected version '1.21.4', got %q", version)
+	}
+	if receivedAuth != "token test-token" {
+		t.Errorf("Expected Authorization to survive the redirect, got %q", receivedAuth)
+	}
+}
+
+func TestCheckRedirect_DropsAuthorizationCrossSite(t *testing.T) {
+	original, _ := http.NewRequest("GET", "http://git.example.com/api/v1/version", nil)
+	original.Header.Set("Authorization", "token test-token")
+
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{"https://git.example.com/api/v1/version", "token test-token"},
+		{"http://git.example.com:3000/api/v1/version", "token test-token"},
+		{"https://evil.example.net/api/v1/version", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.target, nil)
+			if err := checkRedirect(req, []*http.Request{original}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if auth := req.Header.Get("Authorization"); auth != tt.expected {
+				t.Errorf("Expected Authorization %q, got %q", tt.expected, auth)
+			}
+		})
+	}
+}
+
+func TestSameSite_RejectsDowngrade(t *testing.T) {
+	from, _ := url.Parse("https://git.example.com/")
+	to, _ := url.Parse("http://git.example.com/")
+	if sameSite(from, to) {
+		t.Error("Expected an https to http redirect not to count as same-site")
+	}
+}
+
+func TestCheckRedirect_DetectsLoops(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/version" {
+			// Bounce straight back to the same URL
+			http.Redirect(w, r, server.URL+r.URL.Path, http.StatusFound)
+			return
+		}
+		// Hop through ever-new URLs
+		hop, _ := strconv.Atoi(r.URL.Query().Get("hop"))
+		http.Redirect(w, r, fmt.Sprintf("%s%s?hop=%d", server.URL, r.URL.Path, hop+1), http.StatusFound)
+	}))
+	defer server.Close()
+
+	originalConfig := config
+	config.MaxRedirects = 3
+	defer func() { config = originalConfig }()
+
+	g := &GiteaService{BaseURL: server.URL, Token: "test-token", HTTPClient: newHTTPClient(config)}
+
+	_, err := g.GetVersion()
+	if !errors.Is(err, errRedirectLoop) || !strings.Contains(err.Error(), "redirected back to itself") {
+		t.Errorf("Expected a self-redirect to be reported as a loop, got %v", err)
+	}
+
+	_, err = g.GetRepository("testowner", "testrepo")
+	if !errors.Is(err, errRedirectLoop) {
+		t.Fatalf("Expected endless redirects to be reported as a loop, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "gave up after 3 redirects, last at "+server.URL+"/api/v1/repos/testowner/testrepo?hop=4") {
+		t.Errorf("Expected the error to name the redirect limit and final URL, got %v", err)
+	}
+}
+
+func TestStatusHandler_RedirectLoop(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL+r.URL.Path, http.StatusMovedPermanently)
+	}))
+	defer server.Close()
+
+	originalService := service
+	service = &GiteaService{BaseURL: server.URL, Token: "test-token", HTTPClient: newHTTPClient(config)}
+	defer func() { service = originalService }()
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if rr.Code != http.StatusBadGateway || !strings.Contains(rr.Body.String(), "redirect loop detected") {
+		t.Errorf("Expected a 502 naming the redirect loop, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
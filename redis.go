@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache backends selected with CACHE_BACKEND
+const (
+	cacheBackendMemory = "memory"
+	cacheBackendRedis  = "redis"
+)
+
+// redisKeyPrefix namespaces the service's keys in a shared Redis database
+const redisKeyPrefix = "gitea-check:status:"
+
+// redisTimeout bounds each Redis command, including connecting, so a slow
+// cache never holds up a lookup for long. A sooner deadline of the request
+// the command runs for takes precedence.
+const redisTimeout = 2 * time.Second
+
+// redisRetryAfter is how long Redis is skipped after it could not be
+// reached, so that every lookup does not wait on it in turn
+const redisRetryAfter = 5 * time.Second
+
+// errRedisUnavailable is returned without contacting Redis while it is
+// being skipped after a failure
+var errRedisUnavailable = errors.New("redis: skipped after a recent connection failure")
+
+// redisMaxIdle is how many idle connections are kept for reuse
+const redisMaxIdle = 8
+
+// newCache builds the status cache selected by CACHE_BACKEND
+func newCache(cfg Config) Cache {
+	if cfg.CacheBackend == cacheBackendRedis {
+		c, err := newRedisCache(cfg.RedisURL)
+		if err != nil {
+			// loadConfig has already validated the URL
+			log.Fatalf("Invalid REDIS_URL: %v", err)
+		}
+		return c
+	}
+	return newStatusCache()
+}
+
+// redisOptions holds the connection settings parsed from REDIS_URL
+type redisOptions struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      bool
+}
+
+// parseRedisURL reads redis://[[user]:password@]host[:port][/db], or
+// rediss:// for TLS
+func parseRedisURL(raw string) (redisOptions, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redisOptions{}, err
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return redisOptions{}, fmt.Errorf("scheme must be redis or rediss, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return redisOptions{}, errors.New("missing host")
+	}
+
+	opts := redisOptions{addr: u.Host, tls: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		opts.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		opts.username = u.User.Username()
+		opts.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if opts.db, err = strconv.Atoi(db); err != nil || opts.db < 0 {
+			return redisOptions{}, fmt.Errorf("database must be a non-negative number, got %q", db)
+		}
+	}
+	return opts, nil
+}
+
+// redactRedisURL hides the password of a REDIS_URL for display
+func redactRedisURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redact(raw)
+	}
+	return u.Redacted()
+}
+
+// redisCache keeps commit statuses in Redis so that replicas share them.
+// Cache failures are logged and treated as misses, so an unavailable
+// Redis never fails lookups. After a connection failure Redis is skipped
+// for redisRetryAfter, so it does not slow them down either.
+type redisCache struct {
+	opts redisOptions
+
+	mu        sync.Mutex
+	idle      []*redisConn
+	downUntil time.Time
+}
+
+// newRedisCache creates a cache backed by the Redis server at rawURL.
+// Connections are made on first use.
+func newRedisCache(rawURL string) (*redisCache, error) {
+	opts, err := parseRedisURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &redisCache{opts: opts}, nil
+}
+
+// redisEntry is the stored form of a status, keeping the fields that are
+// not part of its JSON encoding
+type redisEntry struct {
+	Status    *StatusResponse `json:"status"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	Raw       json.RawMessage `json:"raw,omitempty"`
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) (*StatusResponse, bool) {
+	reply, err := c.do(ctx, "GET", redisKeyPrefix+key)
+	if errors.Is(err, errRedisUnavailable) {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error reading cached status for %s from Redis: %v", key, err)
+		return nil, false
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false
+	}
+
+	var entry redisEntry
+	if err := json.Unmarshal(value, &entry); err != nil || entry.Status == nil {
+		log.Printf("Error decoding cached status for %s from Redis: %v", key, err)
+		return nil, false
+	}
+	entry.Status.FetchedAt = entry.FetchedAt
+	entry.Status.Raw = entry.Raw
+	return entry.Status, true
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, status *StatusResponse, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	value, err := json.Marshal(redisEntry{Status: status, FetchedAt: status.FetchedAt, Raw: status.Raw})
+	if err != nil {
+		log.Printf("Error encoding cached status for %s: %v", key, err)
+		return
+	}
+	ms := max(ttl.Milliseconds(), 1)
+	if _, err := c.do(ctx, "SET", redisKeyPrefix+key, string(value), "PX", strconv.FormatInt(ms, 10)); err != nil && !errors.Is(err, errRedisUnavailable) {
+		log.Printf("Error caching status for %s in Redis: %v", key, err)
+	}
+}
+
+func (c *redisCache) Delete(ctx context.Context, prefix string) {
+	pattern := redisKeyPrefix + escapeRedisPattern(prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := c.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if errors.Is(err, errRedisUnavailable) {
+			return
+		}
+		if err != nil {
+			log.Printf("Error finding cached statuses under %s in Redis: %v", prefix, err)
+			return
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			log.Printf("Unexpected Redis SCAN reply: %v", reply)
+			return
+		}
+		next, _ := page[0].([]byte)
+		keys, _ := page[1].([]any)
+
+		if len(keys) > 0 {
+			args := make([]string, 0, len(keys)+1)
+			args = append(args, "DEL")
+			for _, key := range keys {
+				if key, ok := key.([]byte); ok {
+					args = append(args, string(key))
+				}
+			}
+			if _, err := c.do(ctx, args...); err != nil {
+				log.Printf("Error deleting cached statuses under %s from Redis: %v", prefix, err)
+				return
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return
+		}
+	}
+}
+
+func (c *redisCache) Describe(ctx context.Context) (string, error) {
+	if _, err := c.do(ctx, "PING"); err != nil {
+		return fmt.Sprintf("redis at %s", c.opts.addr), err
+	}
+	return fmt.Sprintf("redis at %s, reachable", c.opts.addr), nil
+}
+
+// escapeRedisPattern escapes the glob characters of a SCAN MATCH pattern
+func escapeRedisPattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// do runs a single command on a pooled connection. Connections that fail
+// are discarded rather than returned to the pool, and a failure to connect
+// makes Redis be skipped for redisRetryAfter.
+func (c *redisCache) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := c.conn(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.markDown(err)
+		}
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.Close()
+		return nil, err
+	}
+	c.release(conn)
+	return reply, err
+}
+
+// markDown skips Redis for redisRetryAfter after it could not be reached
+func (c *redisCache) markDown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.downUntil = time.Now().Add(redisRetryAfter)
+	log.Printf("Redis at %s unreachable, skipping the cache for %v: %v", c.opts.addr, redisRetryAfter, err)
+}
+
+// conn takes an idle connection or dials a new one, failing fast while
+// Redis is being skipped
+func (c *redisCache) conn(ctx context.Context) (*redisConn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	if time.Now().Before(c.downUntil) {
+		c.mu.Unlock()
+		return nil, errRedisUnavailable
+	}
+	c.mu.Unlock()
+
+	return dialRedis(ctx, c.opts)
+}
+
+// release returns a healthy connection to the pool
+func (c *redisCache) release(conn *redisConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.idle) >= redisMaxIdle {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// redisError is an error reply sent by the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisConn speaks the Redis protocol (RESP) over a single connection
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// dialRedis connects, authenticates and selects the configured database
+func dialRedis(ctx context.Context, opts redisOptions) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if opts.tls {
+		conn, err = (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", opts.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", opts.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{Conn: conn, reader: bufio.NewReader(conn)}
+
+	if opts.password != "" {
+		args := []string{"AUTH", opts.password}
+		if opts.username != "" {
+			args = []string{"AUTH", opts.username, opts.password}
+		}
+		if _, err := rc.do(ctx, args...); err != nil {
+			rc.Close()
+			return nil, err
+		}
+	}
+	if opts.db != 0 {
+		if _, err := rc.do(ctx, "SELECT", strconv.Itoa(opts.db)); err != nil {
+			rc.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// do sends a command and reads its reply: a string for simple replies,
+// []byte for bulk strings, int64 for integers, []any for arrays and nil
+// for missing values. Cancelling ctx interrupts the command.
+func (c *redisConn) do(ctx context.Context, args ...string) (any, error) {
+	deadline := time.Now().Add(redisTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { c.SetDeadline(time.Now()) })
+	defer stop()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c, b.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.reader)
+}
+
+// readRedisReply parses one RESP reply
+func readRedisReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readRedisReply(r); err != nil {
+				var replyErr redisError
+				if !errors.As(err, &replyErr) {
+					return nil, err
+				}
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
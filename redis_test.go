@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a minimal in-process Redis server supporting the commands
+// the cache uses
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+	calls   []string
+}
+
+// startFakeRedis serves the fake on a local port until the test ends
+func startFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	f := &fakeRedis{
+		listener: listener,
+		password: password,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) url() string {
+	if f.password != "" {
+		return fmt.Sprintf("redis://:%s@%s/2", f.password, f.listener.Addr())
+	}
+	return "redis://" + f.listener.Addr().String()
+}
+
+func (f *fakeRedis) commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		name := strings.ToUpper(args[0])
+
+		f.mu.Lock()
+		f.calls = append(f.calls, name)
+		var reply string
+		switch {
+		case name == "AUTH":
+			authed = args[len(args)-1] == f.password
+			reply = "+OK\r\n"
+			if !authed {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case name == "PING":
+			reply = "+PONG\r\n"
+		case name == "SELECT":
+			reply = "+OK\r\n"
+		case name == "GET":
+			value, ok := f.values[args[1]]
+			if !ok || time.Now().After(f.expires[args[1]]) {
+				reply = "$-1\r\n"
+			} else {
+				reply = bulk(value)
+			}
+		case name == "SET":
+			ms, _ := strconv.Atoi(args[4])
+			f.values[args[1]] = args[2]
+			f.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			reply = "+OK\r\n"
+		case name == "DEL":
+			for _, key := range args[1:] {
+				delete(f.values, key)
+			}
+			reply = fmt.Sprintf(":%d\r\n", len(args)-1)
+		case name == "SCAN":
+			// Every match is returned in a single page
+			var keys []string
+			for key := range f.values {
+				if ok, _ := path.Match(args[3], key); ok {
+					keys = append(keys, key)
+				}
+			}
+			reply = fmt.Sprintf("*2\r\n%s*%d\r\n", bulk("0"), len(keys))
+			for _, key := range keys {
+				reply += bulk(key)
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// readCommand reads a command sent as a RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	reply, err := readRedisReply(r)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("unexpected command %v", reply)
+	}
+	args := make([]string, len(items))
+	for i, item := range items {
+		b, _ := item.([]byte)
+		args[i] = string(b)
+	}
+	return args, nil
+}
+
+// testCacheBehaviour checks the behaviour every Cache implementation shares
+func testCacheBehaviour(t *testing.T, c Cache) {
+	t.Helper()
+
+	fetched := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c.Set(context.Background(), cacheKey("myorg", "myrepo", "main"), &StatusResponse{
+		State:      "success",
+		TotalCount: 1,
+		Statuses:   []CommitStatus{{Context: "ci/build", State: "success"}},
+		FetchedAt:  fetched,
+		Raw:        []byte(`{"state":"success"}`),
+	}, time.Minute)
+	c.Set(context.Background(), cacheKey("myorg", "myrepo", "dev"), &StatusResponse{State: "pending"}, time.Minute)
+	c.Set(context.Background(), cacheKey("myorg", "myrepo-two", "main"), &StatusResponse{State: "failure"}, time.Minute)
+	c.Set(context.Background(), cacheKey("myorg", "other", "main"), &StatusResponse{State: "failure"}, time.Minute)
+	c.Set(context.Background(), cacheKey("myorg", "expired", "main"), &StatusResponse{State: "failure"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	status, ok := c.Get(context.Background(), cacheKey("myorg", "myrepo", "main"))
+	if !ok {
+		t.Fatal("Expected a cache hit")
+	}
+	if status.State != "success" || len(status.Statuses) != 1 || status.Statuses[0].Context != "ci/build" {
+		t.Errorf("Expected the stored status back, got %+v", status)
+	}
+	if !status.FetchedAt.Equal(fetched) {
+		t.Errorf("Expected fetch time %v, got %v", fetched, status.FetchedAt)
+	}
+	if string(status.Raw) != `{"state":"success"}` {
+		t.Errorf("Expected the raw upstream body back, got %s", status.Raw)
+	}
+	if _, ok := c.Get(context.Background(), cacheKey("myorg", "expired", "main")); ok {
+		t.Error("Expected an expired entry to be a miss")
+	}
+	if _, ok := c.Get(context.Background(), cacheKey("myorg", "missing", "main")); ok {
+		t.Error("Expected an unknown key to be a miss")
+	}
+
+	c.Delete(context.Background(), cacheKey("myorg", "myrepo", ""))
+	for _, repo := range []string{"myrepo/main", "myrepo/dev"} {
+		if _, ok := c.Get(context.Background(), "myorg/"+repo); ok {
+			t.Errorf("Expected %s to be deleted", repo)
+		}
+	}
+	for _, repo := range []string{"myrepo-two", "other"} {
+		if _, ok := c.Get(context.Background(), cacheKey("myorg", repo, "main")); !ok {
+			t.Errorf("Expected %s to survive deleting another repository", repo)
+		}
+	}
+
+	if _, err := c.Describe(context.Background()); err != nil {
+		t.Errorf("Expected the cache to be healthy, got %v", err)
+	}
+}
+
+func TestStatusCache_Behaviour(t *testing.T) {
+	testCacheBehaviour(t, newStatusCache())
+}
+
+func TestRedisCache_Behaviour(t *testing.T) {
+	server := startFakeRedis(t, "")
+	c, err := newRedisCache(server.url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCacheBehaviour(t, c)
+}
+
+func TestRedisCache_AuthenticatesAndSelectsDatabase(t *testing.T) {
+	server := startFakeRedis(t, "s3cret")
+	c, err := newRedisCache(server.url())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Set(context.Background(), "myorg/myrepo/main", &StatusResponse{State: "success"}, time.Minute)
+	if _, ok := c.Get(context.Background(), "myorg/myrepo/main"); !ok {
+		t.Fatal("Expected a cache hit")
+	}
+
+	// One connection is dialed and reused for both commands
+	expected := []string{"AUTH", "SELECT", "SET", "GET"}
+	if got := server.commands(); strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected commands %v, got %v", expected, got)
+	}
+}
+
+func TestRedisCache_UnreachableIsAMiss(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	c, err := newRedisCache("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set(context.Background(), "myorg/myrepo/main", &StatusResponse{State: "success"}, time.Minute)
+	if _, ok := c.Get(context.Background(), "myorg/myrepo/main"); ok {
+		t.Error("Expected an unreachable cache to miss")
+	}
+	if _, err := c.Describe(context.Background()); err == nil {
+		t.Error("Expected an unreachable cache to be reported")
+	}
+	// After the failed dial, Redis is skipped rather than dialed again
+	if _, err := c.do(context.Background(), "PING"); !errors.Is(err, errRedisUnavailable) {
+		t.Errorf("Expected Redis to be skipped after a connection failure, got %v", err)
+	}
+
+	c.downUntil = time.Now().Add(-time.Second)
+	if _, err := c.do(context.Background(), "PING"); errors.Is(err, errRedisUnavailable) {
+		t.Error("Expected Redis to be tried again once redisRetryAfter has passed")
+	}
+}
+
+func TestRedisCache_GivesUpWithTheRequest(t *testing.T) {
+	// A server that accepts connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+
+	c, err := newRedisCache("redis://" + listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, ok := c.Get(ctx, "myorg/myrepo/main"); ok {
+		t.Error("Expected a hung cache to miss")
+	}
+	if elapsed := time.Since(start); elapsed > redisTimeout/2 {
+		t.Errorf("Expected the lookup to end with the request, took %v", elapsed)
+	}
+}
+
+func TestStatusHandler_SharesRedisCacheAcrossReplicas(t *testing.T) {
+	server := startFakeRedis(t, "")
+	enableCache(t, time.Minute)
+
+	statusCalls := 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		statusCalls++
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	// Each request goes through its own cache client, as separate replicas would
+	for range 2 {
+		replica, err := newRedisCache(server.url())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cache = replica
+
+		req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	if statusCalls != 1 {
+		t.Errorf("Expected the second replica to be served from the shared cache, got %d status calls", statusCalls)
+	}
+}
+
+func TestParseRedisURL(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected redisOptions
+		wantErr  bool
+	}{
+		{"redis://localhost", redisOptions{addr: "localhost:6379"}, false},
+		{"redis://:pw@cache:6380/3", redisOptions{addr: "cache:6380", password: "pw", db: 3}, false},
+		{"rediss://user:pw@cache", redisOptions{addr: "cache:6379", username: "user", password: "pw", tls: true}, false},
+		{"http://cache", redisOptions{}, true},
+		{"redis://cache/main", redisOptions{}, true},
+		{"redis://", redisOptions{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			opts, err := parseRedisURL(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if opts != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, opts)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_CacheBackend(t *testing.T) {
+	t.Setenv("CACHE_BACKEND", "redis")
+	if _, err := loadConfig(); err == nil {
+		t.Error("Expected an error for redis without REDIS_URL")
+	}
+
+	t.Setenv("REDIS_URL", "redis://:pw@cache:6379")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.CacheBackend != cacheBackendRedis {
+		t.Errorf("Expected backend %q, got %q", cacheBackendRedis, cfg.CacheBackend)
+	}
+	if shown := effectiveConfig(cfg).RedisURL; strings.Contains(shown, "pw") {
+		t.Errorf("Expected the Redis password to be redacted, got %q", shown)
+	}
+
+	t.Setenv("CACHE_BACKEND", "memcached")
+	if _, err := loadConfig(); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Reference kinds accepted by the ref_type parameter
+const (
+	refTypeBranch = "branch"
+	refTypeTag    = "tag"
+	refTypeCommit = "commit"
+)
+
+// invalidRefTypeMessage rejects ref_type values other than the kinds above
+const invalidRefTypeMessage = "The 'ref_type' parameter must be 'branch', 'tag' or 'commit'"
+
+// refTypeParam reads the ref_type parameter, defaulting to branch, and
+// reports whether it names a known kind
+func refTypeParam(r *http.Request) (string, bool) {
+	refType := r.URL.Query().Get("ref_type")
+	if refType == "" {
+		return refTypeBranch, true
+	}
+	return refType, refType == refTypeBranch || refType == refTypeTag || refType == refTypeCommit
+}
+
+// Commit SHA lengths: abbreviations shorter than minShortSHALength are
+// rejected, since they rarely identify a single commit and too easily
+// collide with hex-looking branch names, and full SHA-1 object names are
+// fullSHALength long
+const (
+	minShortSHALength = 7
+	fullSHALength     = 40
+)
+
+// isCommitSHA reports whether ref looks like a full or abbreviated commit SHA
+func isCommitSHA(ref string) bool {
+	if len(ref) < minShortSHALength || len(ref) > fullSHALength {
+		return false
+	}
+	for _, c := range ref {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// Branch represents the parts of a Gitea branch used to resolve its head commit
+type Branch struct {
+	Name   string `json:"name"`
+	Commit struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+// Tag represents the parts of a Gitea tag used to resolve its commit
+type Tag struct {
+	Name   string `json:"name"`
+	Commit struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+}
+
+// GetBranchCommit fetches the SHA of the head commit of a branch
+func (g *GiteaService) GetBranchCommit(owner, repo, branch string) (string, error) {
+	endpoint, err := g.apiURL("repos", owner, repo, "branches", branch)
+	if err != nil {
+		return "", err
+	}
+
+	var b Branch
+	if err := g.forRepo(owner, repo).getJSON(endpoint, "get branch", &b); err != nil {
+		return "", err
+	}
+	return b.Commit.ID, nil
+}
+
+// GetTagCommit fetches the SHA of the commit a tag points at
+func (g *GiteaService) GetTagCommit(owner, repo, tag string) (string, error) {
+	endpoint, err := g.apiURL("repos", owner, repo, "tags", tag)
+	if err != nil {
+		return "", err
+	}
+
+	var t Tag
+	if err := g.forRepo(owner, repo).getJSON(endpoint, "get tag", &t); err != nil {
+		return "", err
+	}
+	return t.Commit.SHA, nil
+}
+
+// GetCommitSHA expands a possibly abbreviated commit SHA to the full SHA
+func (g *GiteaService) GetCommitSHA(owner, repo, sha string) (string, error) {
+	c, err := g.GetCommit(owner, repo, sha)
+	if err != nil {
+		return "", err
+	}
+	return c.SHA, nil
+}
+
+// resolveRefStatus resolves a branch or tag to its commit explicitly before
+// fetching the status, avoiding ambiguity when a branch and a tag share a name.
+// Short commit SHAs are expanded first, since some Gitea versions only report
+// statuses for full SHAs. A branch ref that names no branch but looks like a
+// commit SHA is resolved as a commit, so pasted SHAs work without ref_type.
+func resolveRefStatus(ctx context.Context, owner, repo, ref, refType string, opts StatusOptions) (BuildStatusResponse, error) {
+	response := BuildStatusResponse{
+		Owner:      owner,
+		Repository: repo,
+		Ref:        ref,
+		RefType:    refType,
+	}
+	if refType == refTypeBranch {
+		response.Branch = ref
+	}
+
+	svc := service.withContext(ctx)
+	var sha string
+	var err error
+	switch {
+	case refType == refTypeTag:
+		sha, err = svc.GetTagCommit(canonicalName(owner), canonicalName(repo), ref)
+	case refType == refTypeCommit && len(ref) == fullSHALength:
+		sha = ref
+	case refType == refTypeCommit:
+		sha, err = svc.GetCommitSHA(canonicalName(owner), canonicalName(repo), ref)
+	default:
+		sha, err = svc.GetBranchCommit(canonicalName(owner), canonicalName(repo), ref)
+		if isUpstreamStatus(err, http.StatusNotFound) && isCommitSHA(ref) {
+			refType = refTypeCommit
+			response.RefType = refTypeCommit
+			response.Branch = ""
+			sha, err = ref, nil
+			if len(ref) < fullSHALength {
+				sha, err = svc.GetCommitSHA(canonicalName(owner), canonicalName(repo), ref)
+			}
+		}
+	}
+	if err != nil {
+		response.Error = fmt.Sprintf("Failed to resolve %s '%s': %v", refType, ref, err)
+		return response, err
+	}
+	response.SHA = sha
+
+	err = applyCommitStatus(ctx, &response, owner, repo, sha, opts)
+	return response, err
+}
+
+// resolveRefStates fetches the status of several refs of one repository
+// concurrently and reports each ref's state in refs, rolled up into the
+// worst state. As with branches, any ref failing to resolve fails the
+// whole lookup, since the rollup would otherwise under-report.
+func resolveRefStates(ctx context.Context, owner, repo string, refs []string, refType string, opts StatusOptions) (BuildStatusResponse, error) {
+	results := make([]BuildStatusResponse, len(refs))
+	errs := make([]error, len(refs))
+
+	sem := make(chan struct{}, config.BatchConcurrency)
+	var wg sync.WaitGroup
+	for i, ref := range refs {
+		wg.Add(1)
+		go func(i int, ref string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i], errs[i] = resolveRefStatus(ctx, owner, repo, ref, refType, opts)
+		}(i, ref)
+	}
+	wg.Wait()
+
+	response := BuildStatusResponse{
+		Owner:      owner,
+		Repository: repo,
+		RefType:    refType,
+		Refs:       make(map[string]string, len(refs)),
+	}
+
+	states := make([]string, len(results))
+	for i, result := range results {
+		if errs[i] != nil {
+			response.Refs = nil
+			response.Error = fmt.Sprintf("Ref '%s': %s", refs[i], result.Error)
+			return response, errs[i]
+		}
+		response.Refs[refs[i]] = result.State
+		states[i] = result.State
+
+		// Report the oldest fetch so clients never overestimate freshness
+		if result.FetchedAt != "" && (response.FetchedAt == "" || result.FetchedAt < response.FetchedAt) {
+			response.FetchedAt = result.FetchedAt
+		}
+	}
+
+	response.State = worstState(states...)
+	response.Ok = isOk(response.State)
+	response.Symbol = mapStateToSymbol(response.State)
+	response.Color = mapStateToColor(response.State)
+	response.StateCode = mapStateToCode(response.State)
+	return response, nil
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// refMock serves a branch and a tag both named "release", pointing at different commits
+func refMock(req *http.Request) (*http.Response, error) {
+	switch req.URL.Path {
+	case "/api/v1/repos/testowner/testrepo/branches/release":
+		return createHTTPResponse(200, `{"name": "release", "commit": {"id": "branchsha"}}`), nil
+	case "/api/v1/repos/testowner/testrepo/tags/release":
+		return createHTTPResponse(200, `{"name": "release", "commit": {"sha": "tagsha"}}`), nil
+	case "/api/v1/repos/testowner/testrepo/commits/branchsha/status":
+		return createHTTPResponse(200, `{"state": "pending", "statuses": [], "total_count": 1}`), nil
+	case "/api/v1/repos/testowner/testrepo/commits/tagsha/status":
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	}
+	return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+}
+
+func TestStatusHandler_RefResolution(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedSHA    string
+		expectedState  string
+		expectedBranch string
+	}{
+		{"branch ref", "ref=release&ref_type=branch", http.StatusAccepted, "branchsha", "pending", "release"},
+		{"tag ref", "ref=release&ref_type=tag", http.StatusOK, "tagsha", "success", ""},
+		{"defaults to branch", "ref=release", http.StatusAccepted, "branchsha", "pending", "release"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, refMock)
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.SHA != tt.expectedSHA {
+				t.Errorf("Expected sha '%s', got '%s'", tt.expectedSHA, response.SHA)
+			}
+			if response.State != tt.expectedState {
+				t.Errorf("Expected state '%s', got '%s'", tt.expectedState, response.State)
+			}
+			if response.Branch != tt.expectedBranch {
+				t.Errorf("Expected branch '%s', got '%s'", tt.expectedBranch, response.Branch)
+			}
+			if response.Ref != "release" {
+				t.Errorf("Expected ref 'release', got '%s'", response.Ref)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_RefErrors(t *testing.T) {
+	setMockService(t, refMock)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"invalid ref type", "ref=release&ref_type=sha", http.StatusBadRequest, "ref_type"},
+		{"commit ref that is not a SHA", "ref=release&ref_type=commit", http.StatusBadRequest, "commit SHA"},
+		{"commit ref too short", "ref=abc&ref_type=commit", http.StatusBadRequest, "commit SHA"},
+		{"missing tag", "ref=v9&ref_type=tag", http.StatusInternalServerError, "Failed to resolve tag 'v9'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if !strings.Contains(response.Error, tt.expectedError) {
+				t.Errorf("Expected error containing '%s', got '%s'", tt.expectedError, response.Error)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_CommitRef(t *testing.T) {
+	const fullSHA = "a1b2c3d4e5f60718293a4b5c6d7e8f9012345678"
+
+	tests := []struct {
+		name               string
+		ref                string
+		expectedExpansions int
+	}{
+		{"short SHA is expanded", "a1b2c3d", 1},
+		{"full SHA passes through", fullSHA, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expansions := 0
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				switch req.URL.Path {
+				case "/api/v1/repos/testowner/testrepo/git/commits/a1b2c3d":
+					expansions++
+					return createHTTPResponse(200, `{"sha": "`+fullSHA+`"}`), nil
+				case "/api/v1/repos/testowner/testrepo/commits/" + fullSHA + "/status":
+					return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+				}
+				t.Errorf("Unexpected upstream request %s", req.URL.Path)
+				return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&ref_type=commit&ref="+tt.ref, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.SHA != fullSHA || response.Ref != tt.ref || response.RefType != refTypeCommit {
+				t.Errorf("Expected sha %s for ref %s, got sha %s, ref %s, ref_type %s", fullSHA, tt.ref, response.SHA, response.Ref, response.RefType)
+			}
+			if expansions != tt.expectedExpansions {
+				t.Errorf("Expected %d SHA expansions, got %d", tt.expectedExpansions, expansions)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_DetectsCommitRef(t *testing.T) {
+	const fullSHA = "a1b2c3d4e5f60718293a4b5c6d7e8f9012345678"
+
+	tests := []struct {
+		name            string
+		ref             string
+		expectedSHA     string
+		expectedRefType string
+		expectedBranch  string
+	}{
+		{"short SHA without a branch", "a1b2c3d", fullSHA, refTypeCommit, ""},
+		{"full SHA without a branch", fullSHA, fullSHA, refTypeCommit, ""},
+		{"hex branch wins over a commit", "deadbeef", fullSHA, refTypeBranch, "deadbeef"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				switch req.URL.Path {
+				case "/api/v1/repos/testowner/testrepo/branches/deadbeef":
+					return createHTTPResponse(200, `{"name": "deadbeef", "commit": {"id": "`+fullSHA+`"}}`), nil
+				case "/api/v1/repos/testowner/testrepo/git/commits/a1b2c3d":
+					return createHTTPResponse(200, `{"sha": "`+fullSHA+`"}`), nil
+				case "/api/v1/repos/testowner/testrepo/commits/" + fullSHA + "/status":
+					return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+				}
+				return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&ref="+tt.ref, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.SHA != tt.expectedSHA || response.RefType != tt.expectedRefType || response.Branch != tt.expectedBranch {
+				t.Errorf("Expected sha %s, ref_type %s, branch '%s', got sha %s, ref_type %s, branch '%s'",
+					tt.expectedSHA, tt.expectedRefType, tt.expectedBranch, response.SHA, response.RefType, response.Branch)
+			}
+		})
+	}
+}
+
+func TestIsCommitSHA(t *testing.T) {
+	tests := map[string]bool{
+		"a1b2":    false,
+		"a1b2c3":  false,
+		"A1B2C3D": true,
+		"a1b2c3d4e5f60718293a4b5c6d7e8f9012345678":  true,
+		"a1b2c3d4e5f60718293a4b5c6d7e8f90123456789": false,
+		"abc":     false,
+		"release": false,
+		"":        false,
+	}
+
+	for ref, expected := range tests {
+		if got := isCommitSHA(ref); got != expected {
+			t.Errorf("isCommitSHA(%q) = %v, want %v", ref, got, expected)
+		}
+	}
+}
+
+// tagsMock serves three release tags with differing states
+func tagsMock(req *http.Request) (*http.Response, error) {
+	states := map[string]string{"v1.0": "success", "v1.1": "failure", "v1.2": "pending"}
+	if tag, ok := strings.CutPrefix(req.URL.Path, "/api/v1/repos/testowner/testrepo/tags/"); ok {
+		if _, known := states[tag]; known {
+			return createHTTPResponse(200, `{"name": "`+tag+`", "commit": {"sha": "sha-`+tag+`"}}`), nil
+		}
+	}
+	if sha, ok := strings.CutPrefix(req.URL.Path, "/api/v1/repos/testowner/testrepo/commits/sha-"); ok {
+		tag := strings.TrimSuffix(sha, "/status")
+		return createHTTPResponse(200, `{"state": "`+states[tag]+`", "statuses": [], "total_count": 1}`), nil
+	}
+	return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+}
+
+func TestStatusHandler_Refs(t *testing.T) {
+	tests := []struct {
+		name           string
+		refs           string
+		expectedStatus int
+		expectedState  string
+		expectedRefs   map[string]string
+	}{
+		{"worst of all refs", "v1.0,v1.1,v1.2", http.StatusExpectationFailed, "failure", map[string]string{"v1.0": "success", "v1.1": "failure", "v1.2": "pending"}},
+		{"pending outranks success", "v1.0,v1.2", http.StatusAccepted, "pending", map[string]string{"v1.0": "success", "v1.2": "pending"}},
+		{"single ref", "v1.0", http.StatusOK, "success", map[string]string{"v1.0": "success"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, tagsMock)
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&ref_type=tag&refs="+tt.refs, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.State != tt.expectedState {
+				t.Errorf("Expected state '%s', got '%s'", tt.expectedState, response.State)
+			}
+			if !reflect.DeepEqual(response.Refs, tt.expectedRefs) {
+				t.Errorf("Expected refs %v, got %v", tt.expectedRefs, response.Refs)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_RefsErrors(t *testing.T) {
+	setMockService(t, tagsMock)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"unknown ref fails the rollup", "refs=v1.0,v9.9&ref_type=tag", http.StatusInternalServerError, "Ref 'v9.9': Failed to resolve tag 'v9.9'"},
+		{"invalid ref_type", "refs=v1.0&ref_type=label", http.StatusBadRequest, invalidRefTypeMessage},
+		{"commit refs must be SHAs", "refs=abc1234,v1.0&ref_type=commit", http.StatusBadRequest, "Every 'refs' entry must be a commit SHA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if !strings.HasPrefix(response.Error, tt.expectedError) {
+				t.Errorf("Expected error starting '%s', got '%s'", tt.expectedError, response.Error)
+			}
+			if response.Refs != nil {
+				t.Errorf("Expected no per-ref states on failure, got %v", response.Refs)
+			}
+		})
+	}
+}
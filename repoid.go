@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strconv"
+)
+
+// RepositoryRef represents the owner and name of a repository looked up by ID
+type RepositoryRef struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+// GetRepositoryByID fetches the current owner and name of a repository from
+// its numeric ID, which survives renames and transfers
+func (g *GiteaService) GetRepositoryByID(id int64) (owner, repo string, err error) {
+	endpoint, err := g.apiURL("repositories", strconv.FormatInt(id, 10))
+	if err != nil {
+		return "", "", err
+	}
+
+	var ref RepositoryRef
+	if err := g.getJSON(endpoint, "get repository by id", &ref); err != nil {
+		return "", "", err
+	}
+	return ref.Owner.Login, ref.Name, nil
+}
+
+// parseRepoID validates the id query parameter as a positive repository ID
+func parseRepoID(value string) (int64, bool) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	return id, err == nil && id > 0
+}
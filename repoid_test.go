@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatusHandler_RepoID(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.URL.Path == "/api/v1/repositories/42":
+			return createHTTPResponse(200, `{"id": 42, "name": "renamed", "owner": {"login": "neworg"}}`), nil
+		case strings.HasPrefix(req.URL.Path, "/api/v1/repositories/"):
+			return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+		case req.URL.Path == "/api/v1/repos/neworg/renamed":
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		case req.URL.Path == "/api/v1/repos/neworg/renamed/commits/main/status":
+			return createHTTPResponse(200, `{"state": "success"}`), nil
+		}
+		t.Errorf("Unexpected request to %s", req.URL.Path)
+		return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+	})
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expected     BuildStatusResponse
+	}{
+		{
+			name:         "resolves owner and name",
+			query:        "id=42",
+			expectedCode: http.StatusOK,
+			expected:     BuildStatusResponse{Owner: "neworg", Repository: "renamed", Branch: "main", State: "success"},
+		},
+		{
+			name:         "unknown id",
+			query:        "id=7",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "non-numeric id",
+			query:        "id=abc",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "negative id",
+			query:        "id=-1",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if tt.expected.State == "" {
+				return
+			}
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.Owner != tt.expected.Owner || response.Repository != tt.expected.Repository ||
+				response.Branch != tt.expected.Branch || response.State != tt.expected.State {
+				t.Errorf("Expected %+v, got %+v", tt.expected, response)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// isRetryable reports whether a failed upstream call may succeed if repeated:
+// Gitea being unreachable, overloaded or answering with a server error
+func isRetryable(err error) bool {
+	if isUpstreamUnavailable(err) {
+		return true
+	}
+
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.StatusCode >= http.StatusInternalServerError ||
+			upstreamErr.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// retry runs attempt up to UPSTREAM_RETRIES extra times while it fails with
+// a retryable error, doubling the wait from RETRY_BACKOFF each time. When
+// RETRY_BUDGET is set, every attempt runs under a context ending when the
+// budget, measured from the first attempt, is spent, and no retry is started
+// that would wait past it; the last error is returned instead.
+func (g *GiteaService) retry(attempt func(ctx context.Context) error) error {
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+	if config.RetryBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, start.Add(config.RetryBudget))
+		defer cancel()
+	}
+
+	backoff := config.RetryBackoff
+	err := attempt(ctx)
+	for retries := 0; err != nil && retries < config.UpstreamRetries && isRetryable(err); retries++ {
+		if config.RetryBudget > 0 && time.Since(start)+backoff > config.RetryBudget {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		err = attempt(ctx)
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// flappingMock fails with a 502 until it has been called failures times
+func flappingMock(t *testing.T, failures int) *int {
+	t.Helper()
+
+	calls := 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls <= failures {
+			return createHTTPResponse(502, `bad gateway`), nil
+		}
+		return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+	})
+	return &calls
+}
+
+func TestGetJSON_Retries(t *testing.T) {
+	originalConfig := config
+	config.UpstreamRetries = 3
+	config.RetryBackoff = time.Millisecond
+	defer func() { config = originalConfig }()
+
+	calls := flappingMock(t, 2)
+	branch, err := service.GetDefaultBranch("testowner", "testrepo")
+	if err != nil || branch != "main" {
+		t.Fatalf("Expected main after retries, got %q, %v", branch, err)
+	}
+	if *calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", *calls)
+	}
+}
+
+func TestGetJSON_RetryBudget(t *testing.T) {
+	originalConfig := config
+	config.UpstreamRetries = 10
+	config.RetryBackoff = 10 * time.Millisecond
+	config.RetryBudget = 35 * time.Millisecond
+	defer func() { config = originalConfig }()
+
+	calls := flappingMock(t, 100)
+	start := time.Now()
+	_, err := service.GetDefaultBranch("testowner", "testrepo")
+	elapsed := time.Since(start)
+
+	if !isUpstreamStatus(err, http.StatusBadGateway) {
+		t.Errorf("Expected the last 502 to be returned, got %v", err)
+	}
+	// Waits of 10ms and 20ms fit the budget; the following 40ms wait does not
+	if *calls < 2 || *calls > 3 {
+		t.Errorf("Expected retries to stop within the budget after 2-3 attempts, got %d", *calls)
+	}
+	// Timers may overshoot a little, but waiting out the 40ms retry would
+	// take the call well past the budget
+	if elapsed > config.RetryBudget+20*time.Millisecond {
+		t.Errorf("Expected to give up within the %v budget, took %v", config.RetryBudget, elapsed)
+	}
+}
+
+func TestGetJSON_RetryBudgetBoundsSlowAttempts(t *testing.T) {
+	originalConfig := config
+	config.UpstreamRetries = 3
+	config.RetryBackoff = time.Millisecond
+	config.RetryBudget = 50 * time.Millisecond
+	defer func() { config = originalConfig }()
+
+	// Gitea hangs far longer than the budget on every attempt
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(5 * time.Second):
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+	})
+
+	start := time.Now()
+	_, err := service.GetDefaultBranch("testowner", "testrepo")
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the attempt to be cut off by the budget, got %v", err)
+	}
+	if elapsed > config.RetryBudget+250*time.Millisecond {
+		t.Errorf("Expected to give up around the %v budget, took %v", config.RetryBudget, elapsed)
+	}
+}
+
+func TestGetJSON_NoRetryOnClientError(t *testing.T) {
+	originalConfig := config
+	config.UpstreamRetries = 3
+	config.RetryBackoff = time.Millisecond
+	defer func() { config = originalConfig }()
+
+	calls := 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		calls++
+		return createHTTPResponse(401, `unauthorized`), nil
+	})
+
+	if _, err := service.GetDefaultBranch("testowner", "testrepo"); err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single attempt for a 401, got %d", calls)
+	}
+}
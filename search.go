@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// commitSearchDepth is how many recent commits on the default branch commit_search scans
+const commitSearchDepth = 50
+
+var (
+	errNoCommitMatch        = errors.New("no commit matches the search")
+	errAmbiguousCommitMatch = errors.New("more than one commit matches the search")
+)
+
+// findCommitByMessage returns the single commit among commits whose message
+// contains query, ignoring case
+func findCommitByMessage(commits []Commit, query string) (Commit, error) {
+	query = strings.ToLower(query)
+
+	var matches []Commit
+	for _, commit := range commits {
+		if strings.Contains(strings.ToLower(commit.Commit.Message), query) {
+			matches = append(matches, commit)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Commit{}, errNoCommitMatch
+	case 1:
+		return matches[0], nil
+	default:
+		return Commit{}, fmt.Errorf("%w (%d matches)", errAmbiguousCommitMatch, len(matches))
+	}
+}
+
+// resolveCommitSearch finds a commit on the default branch by a substring of
+// its message and reports its status. The search is best-effort: only the
+// most recent commitSearchDepth commits are considered.
+func resolveCommitSearch(ctx context.Context, owner, repo, query string, opts StatusOptions) (BuildStatusResponse, error) {
+	response := BuildStatusResponse{
+		Owner:      owner,
+		Repository: repo,
+	}
+
+	branch, err := fetchDefaultBranch(ctx, canonicalName(owner), canonicalName(repo))
+	if err != nil {
+		response.Error = fmt.Sprintf("Failed to get repository info: %v", err)
+		return response, err
+	}
+	response.Branch = branch
+
+	commits, err := service.withContext(ctx).GetRecentCommits(canonicalName(owner), canonicalName(repo), branch, commitSearchDepth)
+	if err != nil {
+		response.Error = fmt.Sprintf("Failed to list commits: %v", err)
+		return response, err
+	}
+
+	commit, err := findCommitByMessage(commits, query)
+	if err != nil {
+		response.Error = fmt.Sprintf("Commit search for '%s' in the last %d commits of '%s': %v", query, commitSearchDepth, branch, err)
+		return response, err
+	}
+	response.SHA = commit.SHA
+
+	err = applyCommitStatus(ctx, &response, owner, repo, commit.SHA, opts)
+	return response, err
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusHandler_CommitSearch(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/commits"):
+			return createHTTPResponse(200, `[
+                {"sha": "c3", "commit": {"message": "Bump version to 1.2.0"}},
+                {"sha": "c2", "commit": {"message": "Fix flaky deploy test"}},
+                {"sha": "c1", "commit": {"message": "Fix login redirect"}}
+            ]`), nil
+		case strings.Contains(req.URL.Path, "/commits/c2/"):
+			return createHTTPResponse(200, `{"state": "failure", "statuses": [], "total_count": 1}`), nil
+		case strings.HasSuffix(req.URL.Path, "/testrepo"):
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		t.Errorf("Unexpected request to %s", req.URL.Path)
+		return createHTTPResponse(404, `{"message": "Not Found"}`), nil
+	})
+
+	tests := []struct {
+		name          string
+		search        string
+		expectedCode  int
+		expectedSHA   string
+		expectedError string
+	}{
+		{"single match", "flaky DEPLOY", http.StatusExpectationFailed, "c2", ""},
+		{"no match", "rollback", http.StatusNotFound, "", "no commit matches"},
+		{"multiple matches", "fix", http.StatusConflict, "", "more than one commit matches the search (2 matches)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&commit_search="+strings.ReplaceAll(tt.search, " ", "+"), nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.SHA != tt.expectedSHA {
+				t.Errorf("Expected sha %q, got %q", tt.expectedSHA, response.SHA)
+			}
+			if !strings.Contains(response.Error, tt.expectedError) {
+				t.Errorf("Expected error containing %q, got %q", tt.expectedError, response.Error)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_CommitSearchUsesBranchCache(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.BranchCacheTTL = 5 * time.Minute
+
+	repoCalls := 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/commits"):
+			return createHTTPResponse(200, `[{"sha": "c1", "commit": {"message": "Fix login redirect"}}]`), nil
+		case strings.Contains(req.URL.Path, "/commits/c1/"):
+			return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+		}
+		repoCalls++
+		return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+	})
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&commit_search=login", nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected status 200, got %d: %s", i, rr.Code, rr.Body.String())
+		}
+	}
+	if repoCalls != 1 {
+		t.Errorf("Expected the default branch to be looked up once, got %d repository calls", repoCalls)
+	}
+}
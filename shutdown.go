@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+)
+
+// serve runs server on listener until ctx is cancelled, then shuts down
+// gracefully: the listener stops accepting connections, event streams and
+// long polls are ended and open requests get up to SHUTDOWN_TIMEOUT to
+// finish.
+func serve(ctx context.Context, server *http.Server, listener net.Listener) error {
+	server.RegisterOnShutdown(broker.close)
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.Serve(listener) }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down, waiting up to %s for open requests", config.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
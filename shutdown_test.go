@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventBroker_Close(t *testing.T) {
+	b := newEventBroker()
+	events, unsubscribe := b.subscribe("owner", "repo")
+	defer unsubscribe()
+
+	b.close()
+	if _, ok := <-events; ok {
+		t.Error("Expected the subscription to be closed")
+	}
+	if b.count() != 0 {
+		t.Errorf("Expected no subscriptions after close, got %d", b.count())
+	}
+
+	late, _ := b.subscribe("owner", "repo")
+	if _, ok := <-late; ok {
+		t.Error("Expected subscriptions after close to be closed immediately")
+	}
+}
+
+func TestServe_ShutdownClosesEventStreams(t *testing.T) {
+	originalBroker := broker
+	broker = newEventBroker()
+	defer func() { broker = originalBroker }()
+
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.ShutdownTimeout = 2 * time.Second
+	config.SSEKeepAlive = time.Hour
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", eventsHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- serve(ctx, &http.Server{Handler: mux}, listener) }()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/events?owner=owner&repo=repo")
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+	for broker.count() == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(config.ShutdownTimeout + time.Second):
+		t.Fatal("Timed out waiting for the server to shut down")
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read the rest of the stream: %v", err)
+	}
+	if !strings.Contains(string(body), ": server shutting down") {
+		t.Errorf("Expected the stream to end with a shutdown comment, got %q", body)
+	}
+	if broker.count() != 0 {
+		t.Errorf("Expected every subscriber to be closed, got %d", broker.count())
+	}
+}
+
+func TestWaitForChange_EndsOnShutdown(t *testing.T) {
+	originalBroker := broker
+	broker = newEventBroker()
+	defer func() { broker = originalBroker }()
+	setLongPoll(t, time.Minute, time.Minute)
+
+	current := BuildStatusResponse{State: "pending"}
+	etagOf := func(response BuildStatusResponse) string { return responseETag(response) }
+	fetch := func() (BuildStatusResponse, error) { return current, nil }
+
+	done := make(chan BuildStatusResponse, 1)
+	go func() {
+		response, _ := waitForChange(context.Background(), "owner", "repo", etagOf(current), current, fetch, etagOf)
+		done <- response
+	}()
+	for broker.count() == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	broker.close()
+	select {
+	case response := <-done:
+		if response.State != "pending" {
+			t.Errorf("Expected the unchanged response, got %q", response.State)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the long poll to end on shutdown")
+	}
+}
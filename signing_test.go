@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestSignature(t *testing.T) {
+	const expected = "4cfb81459618dc114e8bb5f21596063e80b061cefc56980ad7813895f133fd54"
+
+	for i := 0; i < 2; i++ {
+		if got := requestSignature("GET", "/api/v1/repos/myorg/myrepo", "s3cr3t"); got != expected {
+			t.Errorf("Expected signature %s, got %s", expected, got)
+		}
+	}
+	if requestSignature("GET", "/api/v1/repos/myorg/other", "s3cr3t") == expected {
+		t.Error("Expected a different path to change the signature")
+	}
+	if requestSignature("GET", "/api/v1/repos/myorg/myrepo", "other") == expected {
+		t.Error("Expected a different secret to change the signature")
+	}
+}
+
+func TestNewRequest_Signing(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		header   string
+		expected string
+	}{
+		{"unsigned by default", "", defaultSigningHeader, ""},
+		{"default header", "s3cr3t", defaultSigningHeader, "4cfb81459618dc114e8bb5f21596063e80b061cefc56980ad7813895f133fd54"},
+		{"custom header", "s3cr3t", "X-Proxy-Signature", "4cfb81459618dc114e8bb5f21596063e80b061cefc56980ad7813895f133fd54"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			defer func() { config = originalConfig }()
+			config.SigningSecret = tt.secret
+			config.SigningHeader = tt.header
+
+			g := &GiteaService{BaseURL: "https://git.example.com", Token: "test-token"}
+			req, err := g.newRequest("https://git.example.com/api/v1/repos/myorg/myrepo?limit=5")
+			if err != nil {
+				t.Fatalf("Failed to build request: %v", err)
+			}
+			if got := req.Header.Get(tt.header); got != tt.expected {
+				t.Errorf("Expected %s %q, got %q", tt.header, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSigning_ResignedAfterRedirect(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.SigningSecret = "s3cr3t"
+	config.SigningHeader = defaultSigningHeader
+
+	signatures := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatures[r.URL.Path] = r.Header.Get(defaultSigningHeader)
+		if r.URL.Path == "/api/v1/repos/old/repo" {
+			http.Redirect(w, r, "/api/v1/repos/new/repo", http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte(`{"default_branch": "main"}`))
+	}))
+	defer server.Close()
+
+	g := &GiteaService{BaseURL: server.URL, Token: "test-token", HTTPClient: newHTTPClient(config)}
+	if _, err := g.GetRepository("old", "repo"); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	for _, path := range []string{"/api/v1/repos/old/repo", "/api/v1/repos/new/repo"} {
+		if expected := requestSignature("GET", path, "s3cr3t"); signatures[path] != expected {
+			t.Errorf("Expected %s to be signed with %s, got %q", path, expected, signatures[path])
+		}
+	}
+}
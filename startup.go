@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// checkStartup confirms with an authenticated version call that Gitea can
+// be reached and accepts the token, for STARTUP_CHECK to fail fast at boot
+// rather than fail every request later
+func checkStartup(g *GiteaService) error {
+	version, err := g.GetVersion()
+	switch {
+	case isUpstreamStatus(err, http.StatusUnauthorized), isUpstreamStatus(err, http.StatusForbidden):
+		return fmt.Errorf("gitea at %s rejected the token; check TOKEN: %w", g.BaseURL, err)
+	case err != nil:
+		return fmt.Errorf("gitea at %s could not be reached: %w", g.BaseURL, err)
+	}
+
+	log.Printf("Startup check passed: Gitea %s", version)
+	return nil
+}
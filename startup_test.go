@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckStartup(t *testing.T) {
+	tests := []struct {
+		name          string
+		upstream      func(req *http.Request) (*http.Response, error)
+		expectedError string
+	}{
+		{
+			"gitea reachable",
+			func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(200, `{"version": "1.21.0"}`), nil
+			},
+			"",
+		},
+		{
+			"invalid token",
+			func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(401, `{"message": "token is invalid"}`), nil
+			},
+			"rejected the token; check TOKEN",
+		},
+		{
+			"gitea unreachable",
+			func(req *http.Request) (*http.Response, error) {
+				return nil, connectionRefused(req.URL.String())
+			},
+			"could not be reached",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var authorization string
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				authorization = req.Header.Get("Authorization")
+				if req.URL.Path != "/api/v1/version" {
+					t.Errorf("Expected a version call, got %s", req.URL.Path)
+				}
+				return tt.upstream(req)
+			})
+
+			err := checkStartup(service)
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected the check to pass, got %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expectedError, err)
+			}
+			if authorization != "token test-token" {
+				t.Errorf("Expected an authenticated call, got Authorization %q", authorization)
+			}
+		})
+	}
+}
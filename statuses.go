@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CommitStatus represents a single status reported against a commit
+type CommitStatus struct {
+	ID          int64     `json:"id"`
+	State       string    `json:"state"`
+	Context     string    `json:"context"`
+	TargetURL   string    `json:"target_url"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UnmarshalJSON accepts the state under either "state" or Gitea's "status" key
+func (c *CommitStatus) UnmarshalJSON(data []byte) error {
+	type plain CommitStatus
+	var raw struct {
+		plain
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*c = CommitStatus(raw.plain)
+	if c.State == "" {
+		c.State = raw.Status
+	}
+	return nil
+}
+
+// ContextStatus represents a single check in a detailed response
+type ContextStatus struct {
+	Context     string `json:"context"`
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// GroupStatus represents the rolled-up state of checks sharing a context prefix
+type GroupStatus struct {
+	State string `json:"state"`
+	Count int    `json:"count"`
+}
+
+// MarshalJSON encodes the group using the configured field naming style
+func (g GroupStatus) MarshalJSON() ([]byte, error) {
+	type plain GroupStatus
+	return marshalWithNaming(plain(g))
+}
+
+// statePrecedence lists canonical states from most to least severe
+var statePrecedence = []string{"error", "failure", "pending", "warning", "success", "skipped", "neutral", "unknown"}
+
+// isCanonicalState reports whether state is one the service maps explicitly
+func isCanonicalState(state string) bool {
+	for _, s := range statePrecedence {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// stateSeverity ranks a state by statePrecedence, lower being more severe.
+// Unrecognized states rank alongside "unknown".
+func stateSeverity(state string) int {
+	for i, s := range statePrecedence {
+		if s == state {
+			return i
+		}
+	}
+	return len(statePrecedence) - 1
+}
+
+// worstState returns the most severe of the given states, or "unknown" when there are none
+func worstState(states ...string) string {
+	worst := "unknown"
+	for _, state := range states {
+		if stateSeverity(state) < stateSeverity(worst) {
+			worst = state
+		}
+	}
+	return worst
+}
+
+// statusTime returns when a status was last reported
+func statusTime(status CommitStatus) time.Time {
+	if status.UpdatedAt.IsZero() {
+		return status.CreatedAt
+	}
+	return status.UpdatedAt
+}
+
+// buildDuration returns the time from the earliest pending status to the
+// latest terminal one. It is zero while any check is still pending or when
+// the statuses lack the timestamps to tell.
+func buildDuration(statuses []CommitStatus) time.Duration {
+	for _, status := range latestByContext(statuses) {
+		if status.State == "pending" {
+			return 0
+		}
+	}
+
+	var started, finished time.Time
+	for _, status := range statuses {
+		reported := statusTime(status)
+		if reported.IsZero() {
+			continue
+		}
+		switch {
+		case status.State == "pending":
+			if started.IsZero() || reported.Before(started) {
+				started = reported
+			}
+		case isTerminalState(status.State):
+			if reported.After(finished) {
+				finished = reported
+			}
+		}
+	}
+
+	if started.IsZero() || !finished.After(started) {
+		return 0
+	}
+	return finished.Sub(started)
+}
+
+// newestStatusTime returns when the most recent of statuses was reported,
+// or the zero time when there are none
+func newestStatusTime(statuses []CommitStatus) time.Time {
+	var newest time.Time
+	for _, status := range statuses {
+		if t := statusTime(status); t.After(newest) {
+			newest = t
+		}
+	}
+	return newest
+}
+
+// isStale reports whether the newest of statuses is older than maxAge at now.
+// Commits without timestamped statuses are never stale.
+func isStale(statuses []CommitStatus, maxAge time.Duration, now time.Time) bool {
+	newest := newestStatusTime(statuses)
+	return !newest.IsZero() && now.Sub(newest) > maxAge
+}
+
+// latestByContext keeps only the most recent status for each context, so
+// stale entries from earlier runs do not affect the result. Ties on
+// timestamp go to the higher ID. Contexts keep their first-seen order.
+func latestByContext(statuses []CommitStatus) []CommitStatus {
+	index := make(map[string]int, len(statuses))
+	latest := make([]CommitStatus, 0, len(statuses))
+	for _, status := range statuses {
+		i, seen := index[status.Context]
+		if !seen {
+			index[status.Context] = len(latest)
+			latest = append(latest, status)
+			continue
+		}
+
+		current := latest[i]
+		newer := statusTime(status).After(statusTime(current)) ||
+			(statusTime(status).Equal(statusTime(current)) && status.ID > current.ID)
+		if newer {
+			latest[i] = status
+		}
+	}
+	return latest
+}
+
+// rollupState reports the combined state of a commit from its latest
+// status per context, falling back to Gitea's combined state when no
+// individual statuses were returned and to unknown when that is empty too.
+// When PRIMARY_CONTEXT is among the statuses, its state is authoritative.
+func rollupState(status *StatusResponse) string {
+	if len(status.Statuses) == 0 {
+		if status.State == "" {
+			return "unknown"
+		}
+		return status.State
+	}
+
+	latest := latestByContext(status.Statuses)
+	if config.PrimaryContext != "" {
+		for _, s := range latest {
+			if s.Context == config.PrimaryContext {
+				return s.State
+			}
+		}
+	}
+
+	states := make([]string, 0, len(latest))
+	for _, s := range latest {
+		states = append(states, s.State)
+	}
+	return worstState(states...)
+}
+
+// contextGroup returns the prefix of a context before its first slash,
+// or "other" for contexts without one
+func contextGroup(context string) string {
+	if prefix, _, found := strings.Cut(context, "/"); found && prefix != "" {
+		return prefix
+	}
+	return "other"
+}
+
+// groupStatuses rolls statuses up by their context prefix
+func groupStatuses(statuses []CommitStatus) map[string]GroupStatus {
+	groups := make(map[string]GroupStatus)
+	for _, status := range statuses {
+		key := contextGroup(status.Context)
+		group := groups[key]
+		if group.Count == 0 {
+			group.State = status.State
+		} else {
+			group.State = worstState(group.State, status.State)
+		}
+		group.Count++
+		groups[key] = group
+	}
+	return groups
+}
+
+// MatrixCell represents a single check in the matrix view
+type MatrixCell struct {
+	Context string `json:"context"`
+	State   string `json:"state"`
+	Symbol  string `json:"symbol"`
+}
+
+// MarshalJSON encodes the matrix cell using the configured field naming style
+func (c MatrixCell) MarshalJSON() ([]byte, error) {
+	type plain MatrixCell
+	return marshalWithNaming(plain(c))
+}
+
+// buildMatrix lists every check with its symbol, sorted by context name
+func buildMatrix(statuses []CommitStatus) []MatrixCell {
+	matrix := make([]MatrixCell, 0, len(statuses))
+	for _, status := range statuses {
+		matrix = append(matrix, MatrixCell{
+			Context: status.Context,
+			State:   status.State,
+			Symbol:  mapStateToSymbol(status.State),
+		})
+	}
+	sort.SliceStable(matrix, func(i, j int) bool {
+		return matrix[i].Context < matrix[j].Context
+	})
+	return matrix
+}
+
+// contextURLs maps each context to its target URL, skipping contexts
+// without one. It returns nil when no context has a URL.
+func contextURLs(statuses []CommitStatus) map[string]string {
+	var urls map[string]string
+	for _, status := range statuses {
+		if status.TargetURL == "" {
+			continue
+		}
+		if urls == nil {
+			urls = make(map[string]string)
+		}
+		urls[status.Context] = status.TargetURL
+	}
+	return urls
+}
+
+// pendingContexts lists the contexts of statuses that are still pending,
+// sorted and without duplicates. It is never nil so clients always get a list.
+func pendingContexts(statuses []ContextStatus) []string {
+	seen := make(map[string]bool)
+	pending := []string{}
+	for _, status := range statuses {
+		if status.State == "pending" && !seen[status.Context] {
+			seen[status.Context] = true
+			pending = append(pending, status.Context)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// applyDetail adds the per-check breakdown to a response, either as the
+// matrix view or as the statuses with their context groups
+func applyDetail(response *BuildStatusResponse, statuses []CommitStatus, format string) {
+	response.ContextURLs = contextURLs(statuses)
+	if format == formatMatrix {
+		response.Matrix = buildMatrix(statuses)
+		return
+	}
+
+	response.Statuses = make([]ContextStatus, 0, len(statuses))
+	for _, status := range statuses {
+		response.Statuses = append(response.Statuses, ContextStatus{
+			Context:     status.Context,
+			State:       status.State,
+			TargetURL:   status.TargetURL,
+			Description: status.Description,
+		})
+	}
+	sortBySeverity(response.Statuses)
+	response.Groups = groupStatuses(statuses)
+}
+
+// sortBySeverity orders checks most severe first, following the worst-state
+// precedence, and by context name within a state, so triage views show the
+// problems at the top
+func sortBySeverity(statuses []ContextStatus) {
+	sort.SliceStable(statuses, func(i, j int) bool {
+		si, sj := stateSeverity(statuses[i].State), stateSeverity(statuses[j].State)
+		if si != sj {
+			return si < sj
+		}
+		return statuses[i].Context < statuses[j].Context
+	})
+}
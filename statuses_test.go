@@ -0,0 +1,665 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommitStatus_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"gitea status key", `{"status": "failure", "context": "ci/test"}`, "failure"},
+		{"state key", `{"state": "pending", "context": "ci/test"}`, "pending"},
+		{"state key wins", `{"state": "success", "status": "failure"}`, "success"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status CommitStatus
+			if err := json.Unmarshal([]byte(tt.body), &status); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if status.State != tt.expected {
+				t.Errorf("Expected state '%s', got '%s'", tt.expected, status.State)
+			}
+		})
+	}
+}
+
+func TestWorstState(t *testing.T) {
+	tests := []struct {
+		states   []string
+		expected string
+	}{
+		{[]string{"success", "failure"}, "failure"},
+		{[]string{"failure", "error"}, "error"},
+		{[]string{"success", "pending", "warning"}, "pending"},
+		{[]string{"success", "warning"}, "warning"},
+		{[]string{"success", "bogus"}, "success"},
+		{nil, "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.states, "_"), func(t *testing.T) {
+			if result := worstState(tt.states...); result != tt.expected {
+				t.Errorf("worstState(%v) = %s, want %s", tt.states, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGroupStatuses(t *testing.T) {
+	statuses := []CommitStatus{
+		{Context: "ci/build", State: "success"},
+		{Context: "ci/test", State: "failure"},
+		{Context: "security/scan", State: "pending"},
+		{Context: "security/deps", State: "success"},
+		{Context: "lint", State: "success"},
+		{Context: "/odd", State: "warning"},
+	}
+
+	groups := groupStatuses(statuses)
+
+	expected := map[string]GroupStatus{
+		"ci":       {State: "failure", Count: 2},
+		"security": {State: "pending", Count: 2},
+		"other":    {State: "warning", Count: 2},
+	}
+	if len(groups) != len(expected) {
+		t.Fatalf("Expected %d groups, got %d: %+v", len(expected), len(groups), groups)
+	}
+	for name, group := range expected {
+		if groups[name] != group {
+			t.Errorf("Expected group '%s' to be %+v, got %+v", name, group, groups[name])
+		}
+	}
+}
+
+func TestStatusHandler_DetailGroups(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		return createHTTPResponse(200, `{
+            "state": "failure",
+            "statuses": [
+                {"status": "success", "context": "ci/build"},
+                {"status": "failure", "context": "deploy/staging"},
+                {"status": "success", "context": "lint"}
+            ],
+            "total_count": 3
+        }`), nil
+	})
+
+	tests := []struct {
+		name       string
+		query      string
+		withDetail bool
+	}{
+		{"without detail", "owner=testowner&repo=testrepo", false},
+		{"with detail", "owner=testowner&repo=testrepo&detail=true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+
+			if !tt.withDetail {
+				if response.Groups != nil || response.Statuses != nil {
+					t.Errorf("Expected no detail fields, got %+v", response)
+				}
+				return
+			}
+
+			if len(response.Statuses) != 3 {
+				t.Errorf("Expected 3 statuses, got %d", len(response.Statuses))
+			}
+			expected := map[string]string{"ci": "success", "deploy": "failure", "other": "success"}
+			for name, state := range expected {
+				if response.Groups[name].State != state {
+					t.Errorf("Expected group '%s' state '%s', got '%s'", name, state, response.Groups[name].State)
+				}
+			}
+		})
+	}
+}
+
+func TestLatestByContext(t *testing.T) {
+	older := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	statuses := []CommitStatus{
+		{ID: 1, State: "failure", Context: "ci/build", UpdatedAt: older},
+		{ID: 2, State: "success", Context: "lint", UpdatedAt: older},
+		{ID: 3, State: "success", Context: "ci/build", UpdatedAt: newer},
+		{ID: 4, State: "pending", Context: "lint", UpdatedAt: older},
+	}
+
+	latest := latestByContext(statuses)
+	if len(latest) != 2 {
+		t.Fatalf("Expected 2 contexts, got %d", len(latest))
+	}
+	if latest[0].ID != 3 {
+		t.Errorf("Expected newest ci/build entry to win, got ID %d", latest[0].ID)
+	}
+	if latest[1].ID != 4 {
+		t.Errorf("Expected higher ID to win a timestamp tie, got ID %d", latest[1].ID)
+	}
+}
+
+func TestStatusHandler_RerunOverridesStaleStatus(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{
+            "state": "failure",
+            "statuses": [
+                {"id": 1, "status": "failure", "context": "ci/build", "updated_at": "2024-01-01T12:00:00Z"},
+                {"id": 2, "status": "success", "context": "ci/build", "updated_at": "2024-01-01T13:00:00Z"},
+                {"id": 3, "status": "success", "context": "lint", "updated_at": "2024-01-01T12:00:00Z"}
+            ],
+            "total_count": 3
+        }`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main&detail=true", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if rr.Code != http.StatusOK || response.State != "success" {
+		t.Errorf("Expected re-run to report success with 200, got %q with %d", response.State, rr.Code)
+	}
+	if len(response.Statuses) != 2 {
+		t.Errorf("Expected stale entry to be dropped, got %+v", response.Statuses)
+	}
+}
+
+func TestBuildMatrix(t *testing.T) {
+	statuses := []CommitStatus{
+		{Context: "security/scan", State: "pending"},
+		{Context: "ci/test", State: "failure"},
+		{Context: "ci/build", State: "success"},
+		{Context: "lint", State: "bogus"},
+	}
+
+	expected := []MatrixCell{
+		{Context: "ci/build", State: "success", Symbol: "✓"},
+		{Context: "ci/test", State: "failure", Symbol: "✗"},
+		{Context: "lint", State: "bogus", Symbol: "?"},
+		{Context: "security/scan", State: "pending", Symbol: "●"},
+	}
+
+	if result := buildMatrix(statuses); !reflect.DeepEqual(result, expected) {
+		t.Errorf("buildMatrix() = %+v, want %+v", result, expected)
+	}
+}
+
+func TestStatusHandler_MatrixFormat(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		return createHTTPResponse(200, `{
+            "state": "failure",
+            "statuses": [
+                {"status": "success", "context": "deploy/staging"},
+                {"status": "failure", "context": "ci/test"}
+            ],
+            "total_count": 2
+        }`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&detail=true&format=matrix", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+
+	expected := []MatrixCell{
+		{Context: "ci/test", State: "failure", Symbol: "✗"},
+		{Context: "deploy/staging", State: "success", Symbol: "✓"},
+	}
+	if !reflect.DeepEqual(response.Matrix, expected) {
+		t.Errorf("Expected matrix %+v, got %+v", expected, response.Matrix)
+	}
+	if response.Statuses != nil || response.Groups != nil {
+		t.Errorf("Expected matrix to replace statuses and groups, got %+v", response)
+	}
+}
+
+func TestStatusHandler_StaleStatuses(t *testing.T) {
+	fresh := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	old := time.Now().Add(-96 * time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name          string
+		updatedAt     string
+		state         string
+		downgrade     bool
+		expectedState string
+		expectedStale bool
+	}{
+		{"fresh success", fresh, "success", true, "success", false},
+		{"old success", old, "success", false, "success", true},
+		{"old success downgraded", old, "success", true, "warning", true},
+		{"old failure kept", old, "failure", true, "failure", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.MaxStatusAge = 72 * time.Hour
+			config.StaleDowngrade = tt.downgrade
+			defer func() { config = originalConfig }()
+
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(200, `{
+                    "state": "`+tt.state+`",
+                    "statuses": [{"status": "`+tt.state+`", "context": "ci/build", "updated_at": "`+tt.updatedAt+`"}],
+                    "total_count": 1
+                }`), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branches=main", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.State != tt.expectedState || response.Stale != tt.expectedStale {
+				t.Errorf("Expected state %q stale %v, got %q stale %v", tt.expectedState, tt.expectedStale, response.State, response.Stale)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_EmptyTopLevelState(t *testing.T) {
+	tests := []struct {
+		name          string
+		combined      string
+		listed        string
+		expectedState string
+	}{
+		{
+			"statuses included",
+			`{"state": "", "statuses": [{"status": "success", "context": "ci/build"}, {"status": "failure", "context": "ci/test"}], "total_count": 2}`,
+			``,
+			"failure",
+		},
+		{
+			"statuses listed separately",
+			`{"state": "", "statuses": [], "total_count": 2}`,
+			`[{"status": "success", "context": "ci/build"}, {"status": "pending", "context": "ci/test"}]`,
+			"pending",
+		},
+		{
+			"nothing to roll up",
+			`{"state": "", "statuses": [], "total_count": 2}`,
+			`[]`,
+			"unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				if strings.HasSuffix(req.URL.Path, "/statuses") {
+					if tt.listed == "" {
+						t.Error("Expected no status listing when statuses were included")
+					}
+					return createHTTPResponse(200, tt.listed), nil
+				}
+				return createHTTPResponse(200, tt.combined), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.State != tt.expectedState {
+				t.Errorf("Expected recomputed state %q, got %q", tt.expectedState, response.State)
+			}
+		})
+	}
+}
+
+func TestContextURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []CommitStatus
+		expected map[string]string
+	}{
+		{
+			"mixed",
+			[]CommitStatus{
+				{Context: "ci/build", TargetURL: "https://ci.example.com/1"},
+				{Context: "ci/test"},
+				{Context: "lint", TargetURL: "https://ci.example.com/2"},
+			},
+			map[string]string{"ci/build": "https://ci.example.com/1", "lint": "https://ci.example.com/2"},
+		},
+		{"no urls", []CommitStatus{{Context: "ci/build"}}, nil},
+		{"no statuses", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := contextURLs(tt.statuses); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("contextURLs() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_DetailContextURLs(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{
+            "state": "failure",
+            "statuses": [
+                {"status": "success", "context": "ci/build", "target_url": "https://ci.example.com/builds/1"},
+                {"status": "failure", "context": "ci/test", "target_url": ""},
+                {"status": "success", "context": "lint"}
+            ],
+            "total_count": 3
+        }`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main&detail=true", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	expected := map[string]string{"ci/build": "https://ci.example.com/builds/1"}
+	if !reflect.DeepEqual(response.ContextURLs, expected) {
+		t.Errorf("Expected context_urls %v, got %v", expected, response.ContextURLs)
+	}
+}
+
+func TestBuildDuration(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	tests := []struct {
+		name     string
+		statuses []CommitStatus
+		expected time.Duration
+	}{
+		{
+			"finished build",
+			[]CommitStatus{
+				{ID: 1, State: "pending", Context: "ci/build", CreatedAt: at(0), UpdatedAt: at(0)},
+				{ID: 2, State: "pending", Context: "ci/test", CreatedAt: at(1), UpdatedAt: at(1)},
+				{ID: 3, State: "success", Context: "ci/build", CreatedAt: at(4), UpdatedAt: at(4)},
+				{ID: 4, State: "failure", Context: "ci/test", CreatedAt: at(7), UpdatedAt: at(7)},
+			},
+			7 * time.Minute,
+		},
+		{
+			"still running",
+			[]CommitStatus{
+				{ID: 1, State: "pending", Context: "ci/build", CreatedAt: at(0)},
+				{ID: 2, State: "pending", Context: "ci/test", CreatedAt: at(1)},
+				{ID: 3, State: "success", Context: "ci/build", CreatedAt: at(4)},
+			},
+			0,
+		},
+		{
+			"no pending status",
+			[]CommitStatus{{ID: 1, State: "success", Context: "ci/build", CreatedAt: at(4)}},
+			0,
+		},
+		{
+			"no timestamps",
+			[]CommitStatus{
+				{ID: 1, State: "pending", Context: "ci/build"},
+				{ID: 2, State: "success", Context: "ci/build"},
+			},
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := buildDuration(tt.statuses); result != tt.expected {
+				t.Errorf("buildDuration() = %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_DetailBuildDuration(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{
+            "state": "success",
+            "statuses": [
+                {"id": 1, "status": "pending", "context": "ci/build", "created_at": "2024-01-01T12:00:00Z", "updated_at": "2024-01-01T12:00:00Z"},
+                {"id": 2, "status": "success", "context": "ci/build", "created_at": "2024-01-01T12:04:30Z", "updated_at": "2024-01-01T12:04:30Z"}
+            ],
+            "total_count": 2
+        }`), nil
+	})
+
+	for _, detail := range []bool{false, true} {
+		req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main&detail="+strconv.FormatBool(detail), nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+
+		var response BuildStatusResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Could not parse response JSON: %v", err)
+		}
+		expected := ""
+		if detail {
+			expected = "4m30s"
+		}
+		if response.BuildDuration != expected {
+			t.Errorf("With detail=%v expected build_duration %q, got %q", detail, expected, response.BuildDuration)
+		}
+	}
+}
+
+func TestPendingContexts(t *testing.T) {
+	statuses := []ContextStatus{
+		{Context: "deploy/staging", State: "pending"},
+		{Context: "ci/build", State: "success"},
+		{Context: "ci/test", State: "pending"},
+		{Context: "ci/test", State: "pending"},
+		{Context: "lint", State: "failure"},
+	}
+
+	if result := pendingContexts(statuses); !reflect.DeepEqual(result, []string{"ci/test", "deploy/staging"}) {
+		t.Errorf("pendingContexts() = %v", result)
+	}
+	if result := pendingContexts(statuses[1:2]); result == nil || len(result) != 0 {
+		t.Errorf("Expected an empty, non-nil list when nothing is pending, got %#v", result)
+	}
+}
+
+func TestStatusHandler_PendingOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses string
+		expected string
+	}{
+		{
+			"mixed states",
+			`[{"status": "success", "context": "ci/build"}, {"status": "pending", "context": "ci/test"}, {"status": "failure", "context": "lint"}, {"status": "pending", "context": "deploy"}]`,
+			`"pending_contexts":["ci/test","deploy"]`,
+		},
+		{
+			"none pending",
+			`[{"status": "success", "context": "ci/build"}]`,
+			`"pending_contexts":[]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(200, `{"state": "pending", "statuses": `+tt.statuses+`, "total_count": 4}`), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main&pending_only=true", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			body := rr.Body.String()
+			if !strings.Contains(body, tt.expected) {
+				t.Errorf("Expected %s in response, got %s", tt.expected, body)
+			}
+			if strings.Contains(body, `"statuses"`) || strings.Contains(body, `"groups"`) {
+				t.Errorf("Expected only the pending contexts, got %s", body)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_NoCIState(t *testing.T) {
+	tests := []struct {
+		name           string
+		noCIState      string
+		body           string
+		expectedState  string
+		expectedSymbol string
+		expectedCode   int
+	}{
+		{"no statuses reported as unknown by default", "", `{"state": "", "statuses": [], "total_count": 0}`, "unknown", "○", http.StatusNoContent},
+		{"no statuses reported as neutral", "neutral", `{"state": "", "statuses": [], "total_count": 0}`, "neutral", "–", http.StatusOK},
+		{"gitea pending without statuses", "neutral", `{"state": "pending", "statuses": [], "total_count": 0}`, "neutral", "–", http.StatusOK},
+		{"statuses present", "neutral", `{"state": "success", "statuses": [{"context": "ci/build", "status": "success"}], "total_count": 1}`, "success", "✓", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			defer func() { config = originalConfig }()
+			config.NoCIState = tt.noCIState
+
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(200, tt.body), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.State != tt.expectedState || response.Symbol != tt.expectedSymbol {
+				t.Errorf("Expected %s %s, got %s %s", tt.expectedState, tt.expectedSymbol, response.State, response.Symbol)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_PrimaryContext(t *testing.T) {
+	tests := []struct {
+		name           string
+		primaryContext string
+		body           string
+		expectedState  string
+	}{
+		{"primary context overrides the rollup", "ci/gate", `{"state": "failure", "statuses": [{"context": "ci/gate", "status": "success"}, {"context": "ci/lint", "status": "failure"}], "total_count": 2}`, "success"},
+		{"primary context absent falls back to the rollup", "ci/gate", `{"state": "failure", "statuses": [{"context": "ci/build", "status": "success"}, {"context": "ci/lint", "status": "failure"}], "total_count": 2}`, "failure"},
+		{"no primary context configured", "", `{"state": "failure", "statuses": [{"context": "ci/gate", "status": "success"}, {"context": "ci/lint", "status": "failure"}], "total_count": 2}`, "failure"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			defer func() { config = originalConfig }()
+			config.PrimaryContext = tt.primaryContext
+
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				return createHTTPResponse(200, tt.body), nil
+			})
+
+			req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			var response BuildStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Could not parse response JSON: %v", err)
+			}
+			if response.State != tt.expectedState {
+				t.Errorf("Expected state %s, got %s", tt.expectedState, response.State)
+			}
+		})
+	}
+}
+
+func TestStatusHandler_DetailSortsBySeverity(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "error", "statuses": [
+			{"context": "ci/lint", "status": "success"},
+			{"context": "ci/docs", "status": "pending"},
+			{"context": "ci/test", "status": "failure"},
+			{"context": "ci/audit", "status": "warning"},
+			{"context": "ci/build", "status": "failure"},
+			{"context": "ci/deploy", "status": "error"},
+			{"context": "ci/bench", "status": "success"}
+		], "total_count": 7}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main&detail=true", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	var response BuildStatusResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+
+	expected := []string{
+		"error ci/deploy",
+		"failure ci/build",
+		"failure ci/test",
+		"pending ci/docs",
+		"warning ci/audit",
+		"success ci/bench",
+		"success ci/lint",
+	}
+	var got []string
+	for _, status := range response.Statuses {
+		got = append(got, status.State+" "+status.Context)
+	}
+	if strings.Join(got, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected statuses ordered\n%v\ngot\n%v", expected, got)
+	}
+}
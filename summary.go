@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"text/template"
+)
+
+// defaultSummaryTemplate renders summaries like "build on main: success ✓ (3 of 3 checks passed)"
+const defaultSummaryTemplate = "build on {{.Branch}}: {{.State}} {{.Symbol}} ({{.Passed}} of {{.Total}} checks passed)"
+
+// SummaryData holds the values available to SUMMARY_TEMPLATE
+type SummaryData struct {
+	Branch  string
+	State   string
+	Symbol  string
+	Total   int
+	Passed  int
+	Failed  int
+	Pending int
+}
+
+// summarize renders the one-line summary of a branch's checks. A template
+// that fails to execute yields an empty summary rather than failing the lookup.
+func summarize(branch, state string, statuses []CommitStatus) string {
+	data := SummaryData{
+		Branch: branch,
+		State:  state,
+		Symbol: mapStateToSymbol(state),
+		Total:  len(statuses),
+	}
+	for _, status := range statuses {
+		switch status.State {
+		case "success":
+			data.Passed++
+		case "failure", "error":
+			data.Failed++
+		case "pending":
+			data.Pending++
+		}
+	}
+
+	tmpl, err := template.New("summary").Parse(config.SummaryTemplate)
+	if err != nil {
+		log.Printf("Error parsing summary template: %v", err)
+		return ""
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		log.Printf("Error rendering summary: %v", err)
+		return ""
+	}
+	return b.String()
+}
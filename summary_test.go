@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		state    string
+		statuses []CommitStatus
+		expected string
+	}{
+		{
+			name:     "all passed",
+			state:    "success",
+			statuses: []CommitStatus{{State: "success"}, {State: "success"}, {State: "success"}},
+			expected: "build on main: success ✓ (3 of 3 checks passed)",
+		},
+		{
+			name:     "one failed",
+			state:    "failure",
+			statuses: []CommitStatus{{State: "success"}, {State: "failure"}},
+			expected: "build on main: failure ✗ (1 of 2 checks passed)",
+		},
+		{
+			name:     "no checks",
+			state:    "unknown",
+			expected: "build on main: unknown ○ (0 of 0 checks passed)",
+		},
+		{
+			name:     "custom template",
+			template: "{{.Branch}} {{.Symbol}} {{.Failed}} failed, {{.Pending}} pending",
+			state:    "failure",
+			statuses: []CommitStatus{{State: "error"}, {State: "pending"}, {State: "success"}},
+			expected: "main ✗ 1 failed, 1 pending",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			if tt.template != "" {
+				config.SummaryTemplate = tt.template
+			}
+			defer func() { config = originalConfig }()
+
+			if got := summarize("main", tt.state, tt.statuses); got != tt.expected {
+				t.Errorf("Expected summary %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_InvalidSummaryTemplate(t *testing.T) {
+	t.Setenv("SUMMARY_TEMPLATE", "{{.Branch")
+	if _, err := loadConfig(); err == nil {
+		t.Error("Expected error for invalid template, got nil")
+	}
+}
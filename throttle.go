@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// throttleSweepSize is how many remembered requests trigger a sweep of the
+// ones whose interval has passed
+const throttleSweepSize = 1024
+
+// repoThrottle remembers when each client last queried each repository so
+// one client cannot poll a repository more often than MIN_REPO_INTERVAL
+type repoThrottle struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// newRepoThrottle creates a throttle with no remembered requests
+func newRepoThrottle() *repoThrottle {
+	return &repoThrottle{last: make(map[string]time.Time)}
+}
+
+var throttle = newRepoThrottle()
+
+// allow records a request from client for owner/repo, returning how long
+// the client must wait instead when its previous request is less than
+// interval ago. Throttled requests do not restart the interval.
+func (t *repoThrottle) allow(client, owner, repo string, interval time.Duration, now time.Time) (time.Duration, bool) {
+	key := client + " " + strings.ToLower(owner+"/"+repo)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.last[key]; ok {
+		if wait := last.Add(interval).Sub(now); wait > 0 {
+			return wait, false
+		}
+	}
+	if len(t.last) >= throttleSweepSize {
+		for k, last := range t.last {
+			if now.Sub(last) >= interval {
+				delete(t.last, k)
+			}
+		}
+	}
+	t.last[key] = now
+	return 0, true
+}
+
+// unixProxy is the TRUSTED_PROXIES entry trusting connections over
+// UNIX_SOCKET, which carry no address
+const unixProxy = "unix"
+
+// parseProxyPrefix parses a TRUSTED_PROXIES entry, a single address being
+// a range of one
+func parseProxyPrefix(entry string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	return netip.ParsePrefix(entry)
+}
+
+// trustedProxy reports whether host, the address a connection came from or
+// one named in X-Forwarded-For, is listed in TRUSTED_PROXIES
+func trustedProxy(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	for _, entry := range config.TrustedProxies {
+		if prefix, err := parseProxyPrefix(entry); err == nil && prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddress identifies the client by the IP address of the connection.
+// X-Forwarded-For is only followed when the connection comes from one of
+// TRUSTED_PROXIES, since clients could otherwise set it to evade the
+// throttle; the client is then the last address not itself a trusted proxy.
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	// Connections over UNIX_SOCKET carry no IP address
+	_, addrErr := netip.ParseAddr(host)
+	viaSocket := addrErr != nil && slices.Contains(config.TrustedProxies, unixProxy)
+	if !viaSocket && !trustedProxy(host) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		if !trustedProxy(hop) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
+
+// clientIdentity identifies the client for the throttle: by the API key it
+// presented when there is one, so clients sharing an address are told
+// apart, and by its address otherwise. Keys are hashed so they are not
+// kept in memory.
+func clientIdentity(r *http.Request) string {
+	if key := requestAPIKey(r); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:])
+	}
+	return clientAddress(r)
+}
+
+// throttleWait enforces MIN_REPO_INTERVAL, returning how long the client
+// must wait before querying owner/repo again, or zero when it may go ahead
+func throttleWait(r *http.Request, owner, repo string) time.Duration {
+	if config.MinRepoInterval <= 0 {
+		return 0
+	}
+	wait, _ := throttle.allow(clientIdentity(r), owner, repo, config.MinRepoInterval, time.Now())
+	return wait
+}
+
+// retryAfterSeconds rounds wait up to the whole seconds a Retry-After header carries
+func retryAfterSeconds(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
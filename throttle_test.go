@@ -0,0 +1,176 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// enableThrottle sets MIN_REPO_INTERVAL with a fresh throttle for the duration of the test
+func enableThrottle(t *testing.T, interval time.Duration) {
+	t.Helper()
+
+	originalConfig := config
+	originalThrottle := throttle
+	config.MinRepoInterval = interval
+	throttle = newRepoThrottle()
+	t.Cleanup(func() {
+		config = originalConfig
+		throttle = originalThrottle
+	})
+}
+
+func TestRepoThrottle_Allow(t *testing.T) {
+	th := newRepoThrottle()
+	now := time.Now()
+
+	if _, ok := th.allow("10.0.0.1", "myorg", "myrepo", 5*time.Second, now); !ok {
+		t.Fatal("Expected the first request to be allowed")
+	}
+	wait, ok := th.allow("10.0.0.1", "MyOrg", "MyRepo", 5*time.Second, now.Add(2*time.Second))
+	if ok || wait != 3*time.Second {
+		t.Errorf("Expected a repeat request to wait 3s, got %v, %v", wait, ok)
+	}
+	if _, ok := th.allow("10.0.0.2", "myorg", "myrepo", 5*time.Second, now.Add(2*time.Second)); !ok {
+		t.Error("Expected another client to be allowed")
+	}
+	if _, ok := th.allow("10.0.0.1", "myorg", "other", 5*time.Second, now.Add(2*time.Second)); !ok {
+		t.Error("Expected another repository to be allowed")
+	}
+	if _, ok := th.allow("10.0.0.1", "myorg", "myrepo", 5*time.Second, now.Add(5*time.Second)); !ok {
+		t.Error("Expected the request to be allowed once the interval has passed")
+	}
+}
+
+func TestStatusHandler_ThrottlesRapidRequests(t *testing.T) {
+	enableThrottle(t, time.Minute)
+
+	statusCalls := 0
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		statusCalls++
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	send := func(remoteAddr, repo string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/status?owner=myorg&branches=main&repo="+repo, nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+		return rr
+	}
+
+	if rr := send("10.0.0.1:1000", "myrepo"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the first request to succeed, got %d", rr.Code)
+	}
+	for i := 0; i < 5; i++ {
+		// A new connection from the same address is the same client
+		rr := send("10.0.0.1:2000", "myrepo")
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("Request %d: expected status 429, got %d", i, rr.Code)
+		}
+		if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "60" {
+			t.Errorf("Request %d: expected Retry-After 60, got %q", i, retryAfter)
+		}
+	}
+	if statusCalls != 1 {
+		t.Errorf("Expected throttled requests not to reach Gitea, got %d status calls", statusCalls)
+	}
+
+	if rr := send("10.0.0.2:1000", "myrepo"); rr.Code != http.StatusOK {
+		t.Errorf("Expected another client to be served, got %d", rr.Code)
+	}
+	if rr := send("10.0.0.1:1000", "otherrepo"); rr.Code != http.StatusOK {
+		t.Errorf("Expected another repository to be served, got %d", rr.Code)
+	}
+}
+
+func TestStatusHandler_ThrottleOffByDefault(t *testing.T) {
+	enableThrottle(t, 0)
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Request %d: expected status 200, got %d", i, rr.Code)
+		}
+	}
+}
+
+func TestClientAddress(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+	config.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.1", unixProxy}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		expected   string
+	}{
+		{"direct client", "203.0.113.7:1000", "", "203.0.113.7"},
+		{"untrusted connection ignores forwarding", "203.0.113.7:1000", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.5:1000", "198.51.100.1", "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.5:1000", "198.51.100.1, 192.168.1.1, 10.1.2.3", "198.51.100.1"},
+		{"spoofed hop before the real client", "10.0.0.5:1000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"trusted proxy without forwarding", "10.0.0.5:1000", "", "10.0.0.5"},
+		{"unix socket", "@", "198.51.100.1", "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/status", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := clientAddress(req); got != tt.expected {
+				t.Errorf("clientAddress() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	config.TrustedProxies = nil
+	req := httptest.NewRequest("GET", "/status", nil)
+	req.RemoteAddr = "@"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := clientAddress(req); got != "@" {
+		t.Errorf("Expected forwarding over an untrusted socket to be ignored, got %q", got)
+	}
+}
+
+func TestStatusHandler_ThrottlesByAPIKey(t *testing.T) {
+	enableThrottle(t, time.Minute)
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	// Every client reaches the service through the same proxy address
+	send := func(key string) int {
+		req := httptest.NewRequest("GET", "/status?owner=myorg&repo=myrepo&branches=main", nil)
+		req.RemoteAddr = "10.0.0.1:1000"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+		return rr.Code
+	}
+
+	if code := send("alice-key"); code != http.StatusOK {
+		t.Fatalf("Expected the first client to be served, got %d", code)
+	}
+	if code := send("bob-key"); code != http.StatusOK {
+		t.Errorf("Expected a client with another key to be served, got %d", code)
+	}
+	if code := send("alice-key"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected a repeat with the same key to be throttled, got %d", code)
+	}
+	if code := send(""); code != http.StatusOK {
+		t.Errorf("Expected a client without a key to be told apart by address, got %d", code)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestTrace records the outcome of a single request for post-hoc debugging
+type RequestTrace struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Owner      string    `json:"owner,omitempty"`
+	Repository string    `json:"repository,omitempty"`
+	State      string    `json:"state,omitempty"`
+	StatusCode int       `json:"status_code"`
+	Duration   string    `json:"duration"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the trace using the configured field naming style
+func (t RequestTrace) MarshalJSON() ([]byte, error) {
+	type plain RequestTrace
+	return marshalWithNaming(plain(t))
+}
+
+// traceRing is a fixed-size buffer keeping the most recent request traces
+type traceRing struct {
+	mu      sync.Mutex
+	entries []RequestTrace
+	next    int
+	full    bool
+}
+
+// newTraceRing creates a ring buffer holding up to size traces
+func newTraceRing(size int) *traceRing {
+	return &traceRing{entries: make([]RequestTrace, size)}
+}
+
+// add records a trace, evicting the oldest one once the buffer is full
+func (t *traceRing) add(trace RequestTrace) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entries[t.next] = trace
+	t.next = (t.next + 1) % len(t.entries)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// snapshot returns the buffered traces, oldest first
+func (t *traceRing) snapshot() []RequestTrace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.full {
+		return append([]RequestTrace{}, t.entries[:t.next]...)
+	}
+	return append(append([]RequestTrace{}, t.entries[t.next:]...), t.entries[:t.next]...)
+}
+
+var traces = newTraceRing(defaultConfig().TraceBufferSize)
+
+type traceContextKey struct{}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Flush passes through to the underlying writer so streaming handlers keep working
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// traceRequests records every request outside /debug/ into the trace buffer
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/debug/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		trace := &RequestTrace{
+			Time:   start,
+			Method: r.Method,
+			Path:   r.URL.Path,
+		}
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), traceContextKey{}, trace)))
+
+		trace.StatusCode = recorder.status
+		trace.Duration = time.Since(start).String()
+		traces.add(*trace)
+	})
+}
+
+// annotateTrace attaches the outcome of a status lookup to the request's trace
+func annotateTrace(r *http.Request, response BuildStatusResponse) {
+	trace, ok := r.Context().Value(traceContextKey{}).(*RequestTrace)
+	if !ok {
+		return
+	}
+
+	trace.Owner = response.Owner
+	trace.Repository = response.Repository
+	trace.State = response.State
+	trace.Error = response.Error
+}
+
+// debugRequestsHandler handles the /debug/requests endpoint
+func debugRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !requireAPIKey(w, r) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, traces.snapshot())
+}
+
+// logBody logs the serialized form of v when DEBUG_LOG_BODIES is enabled.
+// Only request and response payloads are passed here; headers, which carry
+// API keys and tokens, are never logged.
+func logBody(label string, r *http.Request, v any) {
+	if !config.DebugLogBodies {
+		return
+	}
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("DEBUG %s %s %s: could not encode body: %v", r.Method, r.URL.Path, label, err)
+		return
+	}
+	log.Printf("DEBUG %s %s %s: %s", r.Method, r.URL.Path, label, body)
+}
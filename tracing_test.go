@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTraceRing_EvictsOldest(t *testing.T) {
+	ring := newTraceRing(3)
+	for _, path := range []string{"/a", "/b", "/c", "/d"} {
+		ring.add(RequestTrace{Path: path})
+	}
+
+	entries := ring.snapshot()
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	if strings.Join(paths, ",") != "/b,/c,/d" {
+		t.Errorf("Expected /b,/c,/d, got %v", paths)
+	}
+}
+
+func TestTraceRequests_RecordsRequestsInOrder(t *testing.T) {
+	originalTraces := traces
+	traces = newTraceRing(2)
+	defer func() { traces = originalTraces }()
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "/repos/testowner/broken") {
+			return createHTTPResponse(500, `{"message": "Internal Server Error"}`), nil
+		}
+		if !strings.Contains(req.URL.Path, "/commits/") {
+			return createHTTPResponse(200, `{"default_branch": "main"}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	handler := traceRequests(http.HandlerFunc(statusHandler))
+	for _, repo := range []string{"first", "second", "broken"} {
+		req := httptest.NewRequest("GET", "/status?owner=testowner&repo="+repo, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest("GET", "/debug/requests", nil)
+	rr := httptest.NewRecorder()
+	traceRequests(http.HandlerFunc(debugRequestsHandler)).ServeHTTP(rr, req)
+
+	var entries []RequestTrace
+	if err := json.Unmarshal(rr.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+
+	// The oldest request is evicted past capacity and debug requests are not traced
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 traces, got %d: %+v", len(entries), entries)
+	}
+	if entries[0].Repository != "second" || entries[0].State != "success" || entries[0].StatusCode != http.StatusOK {
+		t.Errorf("Unexpected first trace: %+v", entries[0])
+	}
+	if entries[1].Repository != "broken" || entries[1].Error == "" || entries[1].StatusCode != http.StatusInternalServerError {
+		t.Errorf("Unexpected second trace: %+v", entries[1])
+	}
+	if entries[1].Method != "GET" || entries[1].Path != "/status" || entries[1].Duration == "" {
+		t.Errorf("Expected method, path and duration in trace, got %+v", entries[1])
+	}
+}
+
+func TestDebugRequestsHandler_RequiresAPIKey(t *testing.T) {
+	originalConfig := config
+	config.APIKey = "service-key"
+	defer func() { config = originalConfig }()
+
+	req := httptest.NewRequest("GET", "/debug/requests", nil)
+	rr := httptest.NewRecorder()
+	debugRequestsHandler(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLogBody_OnlyWhenEnabled(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	for _, enabled := range []bool{false, true} {
+		originalConfig := config
+		config.DebugLogBodies = enabled
+		logs.Reset()
+
+		req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main", nil)
+		req.Header.Set("X-API-Key", "service-key")
+		statusHandler(httptest.NewRecorder(), req)
+		config = originalConfig
+
+		logged := strings.Contains(logs.String(), `"repository":"testrepo"`)
+		if logged != enabled {
+			t.Errorf("With DEBUG_LOG_BODIES=%v expected body logged=%v, got logs: %s", enabled, enabled, logs.String())
+		}
+		if strings.Contains(logs.String(), "service-key") {
+			t.Errorf("Expected headers not to be logged, got: %s", logs.String())
+		}
+	}
+}
+
+func TestStatusHandler_DebugRaw(t *testing.T) {
+	upstream := `{"state": "success", "sha": "abc123", "statuses": [{"context": "ci/build", "status": "success", "creator": {"login": "bot"}}], "total_count": 1}`
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(200, upstream), nil
+	})
+
+	tests := []struct {
+		name         string
+		enabled      bool
+		query        string
+		expectedCode int
+		expectRaw    bool
+	}{
+		{"enabled and requested", true, "&debug=raw", http.StatusOK, true},
+		{"enabled but not requested", true, "", http.StatusOK, false},
+		{"disabled", false, "&debug=raw", http.StatusForbidden, false},
+		{"disabled and not requested", false, "", http.StatusOK, false},
+		{"unknown mode", true, "&debug=all", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalConfig := config
+			config.DebugRawUpstream = tt.enabled
+			defer func() { config = originalConfig }()
+
+			req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branches=main"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			statusHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			var response struct {
+				State string          `json:"state"`
+				Raw   json.RawMessage `json:"raw"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if !tt.expectRaw {
+				if response.Raw != nil {
+					t.Errorf("Expected no raw payload, got %s", response.Raw)
+				}
+				return
+			}
+			if response.State != "success" {
+				t.Errorf("Expected state 'success', got '%s'", response.State)
+			}
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, []byte(upstream)); err != nil {
+				t.Fatal(err)
+			}
+			if string(response.Raw) != compact.String() {
+				t.Errorf("Expected raw payload %s, got %s", compact.String(), response.Raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// serverVersionTTL is how long the Gitea server version is reused before being fetched again
+const serverVersionTTL = 10 * time.Minute
+
+// versionCache remembers the Gitea server version so that
+// include_server_version does not cost an upstream call per request
+type versionCache struct {
+	mu      sync.Mutex
+	version string
+	expires time.Time
+}
+
+var serverVersion = &versionCache{}
+
+// get returns the cached server version, fetching it when missing or
+// expired. The fetch runs without holding the lock, so requests do not
+// queue behind a slow Gitea; concurrent refreshes may each fetch.
+func (v *versionCache) get(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	version, expires := v.version, v.expires
+	v.mu.Unlock()
+	if version != "" && time.Now().Before(expires) {
+		return version, nil
+	}
+
+	version, err := service.withContext(ctx).GetVersion()
+	if err != nil {
+		return "", err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.version = version
+	v.expires = time.Now().Add(serverVersionTTL)
+	return version, nil
+}
+
+// applyServerVersion adds the Gitea server version to response. The version
+// is a debugging aid, so failing to fetch it is logged rather than failing the lookup.
+func applyServerVersion(ctx context.Context, response *BuildStatusResponse) {
+	version, err := serverVersion.get(ctx)
+	if err != nil {
+		log.Printf("Error fetching Gitea server version: %v", err)
+		return
+	}
+	response.ServerVersion = version
+}
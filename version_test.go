@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatusHandler_IncludeServerVersion(t *testing.T) {
+	originalVersion := serverVersion
+	serverVersion = &versionCache{}
+	defer func() { serverVersion = originalVersion }()
+
+	var versionCalls atomic.Int32
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/version") {
+			versionCalls.Add(1)
+			return createHTTPResponse(200, `{"version": "1.21.4"}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 0}`), nil
+	})
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main&include_server_version=true", nil)
+		rr := httptest.NewRecorder()
+		statusHandler(rr, req)
+
+		var response BuildStatusResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Could not parse response JSON: %v", err)
+		}
+		if response.ServerVersion != "1.21.4" {
+			t.Errorf("Expected server_version '1.21.4', got %q", response.ServerVersion)
+		}
+	}
+
+	if calls := versionCalls.Load(); calls != 1 {
+		t.Errorf("Expected the version to be fetched once, got %d calls", calls)
+	}
+}
+
+func TestStatusHandler_ServerVersionOmittedByDefault(t *testing.T) {
+	originalVersion := serverVersion
+	serverVersion = &versionCache{}
+	defer func() { serverVersion = originalVersion }()
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/version") {
+			t.Error("Expected no version lookup without include_server_version")
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 0}`), nil
+	})
+
+	req := httptest.NewRequest("GET", "/status?owner=testowner&repo=testrepo&branch=main", nil)
+	rr := httptest.NewRecorder()
+	statusHandler(rr, req)
+
+	if strings.Contains(rr.Body.String(), "server_version") {
+		t.Errorf("Expected no server_version field, got %s", rr.Body.String())
+	}
+}
+
+func TestApplyServerVersion_FailureIsNotFatal(t *testing.T) {
+	originalVersion := serverVersion
+	serverVersion = &versionCache{}
+	defer func() { serverVersion = originalVersion }()
+
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(500, `{"message": "boom"}`), nil
+	})
+
+	response := BuildStatusResponse{State: "success"}
+	applyServerVersion(httptest.NewRequest("GET", "/", nil).Context(), &response)
+	if response.ServerVersion != "" || response.Error != "" {
+		t.Errorf("Expected a failed version lookup to leave the response untouched, got %+v", response)
+	}
+}
+
+func TestVersionCache_FetchesWithoutHoldingTheLock(t *testing.T) {
+	originalVersion := serverVersion
+	serverVersion = &versionCache{}
+	defer func() { serverVersion = originalVersion }()
+
+	// Each fetch waits for the other to start, which it only can when the
+	// first does not hold the cache locked
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		arrived.Done()
+		waited := make(chan struct{})
+		go func() {
+			arrived.Wait()
+			close(waited)
+		}()
+		select {
+		case <-waited:
+			return createHTTPResponse(200, `{"version": "1.21.4"}`), nil
+		case <-time.After(time.Second):
+			return createHTTPResponse(504, `{"message": "timed out"}`), nil
+		}
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := serverVersion.get(context.Background())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected concurrent fetches not to wait on each other, got %v", err)
+		}
+	}
+}
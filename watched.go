@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// WatchedRepository is the subset of a subscribed repository listing used for aggregation
+type WatchedRepository struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	DefaultBranch string `json:"default_branch"`
+	Archived      bool   `json:"archived"`
+}
+
+// WatchedResponse represents the /watched response: the aggregated statuses
+// of the repositories watched by the account the service's token belongs to
+type WatchedResponse struct {
+	RepositoryResults
+	Error string `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the watched repository results using the configured field naming style
+func (w WatchedResponse) MarshalJSON() ([]byte, error) {
+	type plain WatchedResponse
+	return marshalWithNaming(plain(w))
+}
+
+// GetWatchedRepositories lists every repository the token's account is subscribed to
+func (g *GiteaService) GetWatchedRepositories() ([]WatchedRepository, error) {
+	endpoint, err := g.apiURL("user", "subscriptions")
+	if err != nil {
+		return nil, err
+	}
+	return getPaged[WatchedRepository](g, endpoint, "list watched repositories")
+}
+
+// resolveWatched fetches the default branch status of every active
+// repository the token's account watches, as /org does for an organization
+func resolveWatched(ctx context.Context) (WatchedResponse, error) {
+	var response WatchedResponse
+
+	repos, err := service.withContext(ctx).GetWatchedRepositories()
+	if err != nil {
+		response.Error = fmt.Sprintf("Failed to list watched repositories: %v", err)
+		return response, err
+	}
+
+	var entries []BatchEntry
+	for _, repo := range repos {
+		if repo.Archived || !repoAllowed(repo.Owner.Login, repo.Name) {
+			continue
+		}
+		entries = append(entries, BatchEntry{Owner: repo.Owner.Login, Repo: repo.Name, Branch: repo.DefaultBranch})
+	}
+
+	response.RepositoryResults = resolveRepositories(ctx, entries)
+	return response, nil
+}
+
+// watchedHandler handles the /watched endpoint. The listing exposes the
+// names of private repositories the token can see, so it requires the API
+// key and is refused when none is configured.
+func watchedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if config.APIKey == "" {
+		writeError(w, http.StatusForbidden, "Set API_KEY to list watched repositories")
+		return
+	}
+	if !requireAPIKey(w, r) {
+		return
+	}
+
+	response, err := resolveWatched(r.Context())
+	if err != nil {
+		response.Error = quietError(response.Error, err)
+		writeJSON(w, errorHTTPCode(w, err), response)
+		return
+	}
+	writeJSON(w, mapStateToHTTPCode(response.State), response)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// watchedListing builds a page of n repositories named repo-<offset+i>,
+// owned by alternating accounts
+func watchedListing(offset, n int) string {
+	repos := make([]string, n)
+	for i := range repos {
+		owner := []string{"alice", "myorg"}[(offset+i)%2]
+		repos[i] = fmt.Sprintf(`{"name": "repo-%d", "owner": {"login": %q}, "default_branch": "main"}`, offset+i, owner)
+	}
+	return "[" + strings.Join(repos, ",") + "]"
+}
+
+// watchedRequest builds a /watched request presenting the API key, which
+// the endpoint requires
+func watchedRequest(t *testing.T) *http.Request {
+	t.Helper()
+
+	originalConfig := config
+	config.APIKey = "s3cret"
+	t.Cleanup(func() { config = originalConfig })
+
+	req := httptest.NewRequest("GET", "/watched", nil)
+	req.Header.Set("X-API-Key", "s3cret")
+	return req
+}
+
+func TestWatchedHandler_AggregatesEveryPage(t *testing.T) {
+	originalConfig := config
+	config.OrgConcurrency = 3
+	config.BatchConcurrency = 10
+	defer func() { config = originalConfig }()
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	var statusPaths []string
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/user/subscriptions") {
+			if req.Header.Get("Authorization") != "token test-token" {
+				t.Errorf("Expected the service token, got %q", req.Header.Get("Authorization"))
+			}
+			return pagedListing(req, watchedListing(0, orgPageSize), watchedListing(orgPageSize, 4)), nil
+		}
+
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		statusPaths = append(statusPaths, req.URL.Path)
+		mu.Unlock()
+		time.Sleep(2 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		if strings.Contains(req.URL.Path, "/repos/myorg/repo-51/") {
+			return createHTTPResponse(200, `{"state": "failure", "statuses": [], "total_count": 1}`), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := watchedRequest(t)
+	rr := httptest.NewRecorder()
+	watchedHandler(rr, req)
+
+	var response WatchedResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if response.State != "failure" || rr.Code != mapStateToHTTPCode("failure") {
+		t.Errorf("Expected the worst state failure, got %q with %d", response.State, rr.Code)
+	}
+	if response.Succeeded != orgPageSize+4 || len(response.Results) != orgPageSize+4 {
+		t.Errorf("Expected every page to be resolved, got %d results", response.Succeeded)
+	}
+	if response.Results[0].Owner != "alice" || response.Results[1].Owner != "myorg" {
+		t.Errorf("Expected each repository under its own owner, got %+v", response.Results[:2])
+	}
+	if maxInFlight > config.OrgConcurrency {
+		t.Errorf("Expected at most %d concurrent lookups, got %d", config.OrgConcurrency, maxInFlight)
+	}
+	for _, path := range statusPaths {
+		if !strings.Contains(path, "/commits/main/") {
+			t.Errorf("Expected the default branch to be looked up, got %s", path)
+		}
+	}
+}
+
+func TestWatchedHandler_SkipsArchived(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/user/subscriptions") {
+			return pagedListing(req, `[
+                {"name": "api", "owner": {"login": "myorg"}, "default_branch": "main"},
+                {"name": "old", "owner": {"login": "myorg"}, "default_branch": "main", "archived": true}
+            ]`), nil
+		}
+		if strings.Contains(req.URL.Path, "/repos/myorg/old/") {
+			t.Error("Expected the archived repository to be skipped")
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := watchedRequest(t)
+	rr := httptest.NewRecorder()
+	watchedHandler(rr, req)
+
+	var response WatchedResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if rr.Code != http.StatusOK || response.State != "success" || len(response.Results) != 1 {
+		t.Errorf("Expected one successful repository, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestWatchedHandler_ListingFails(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		return createHTTPResponse(401, `{"message": "token is required"}`), nil
+	})
+
+	req := watchedRequest(t)
+	rr := httptest.NewRecorder()
+	watchedHandler(rr, req)
+
+	var response WatchedResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if rr.Code < http.StatusBadRequest || !strings.Contains(response.Error, "watched repositories") {
+		t.Errorf("Expected the listing error, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestWatchedHandler_MethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest("POST", "/watched", nil)
+	rr := httptest.NewRecorder()
+	watchedHandler(rr, req)
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rr.Code)
+	}
+}
+
+func TestWatchedHandler_FollowsShortPages(t *testing.T) {
+	setMockService(t, func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/user/subscriptions") {
+			// Gitea clamps limit to its MAX_RESPONSE_ITEMS, here 3
+			return pagedListing(req, watchedListing(0, 3), watchedListing(3, 1)), nil
+		}
+		return createHTTPResponse(200, `{"state": "success", "statuses": [], "total_count": 1}`), nil
+	})
+
+	req := watchedRequest(t)
+	rr := httptest.NewRecorder()
+	watchedHandler(rr, req)
+
+	var response WatchedResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response JSON: %v", err)
+	}
+	if len(response.Results) != 4 {
+		t.Errorf("Expected the repositories of every page, got %d results", len(response.Results))
+	}
+}
+
+func TestWatchedHandler_RequiresAPIKey(t *testing.T) {
+	originalConfig := config
+	defer func() { config = originalConfig }()
+
+	tests := []struct {
+		name         string
+		apiKey       string
+		presented    string
+		expectedCode int
+	}{
+		{"no API key configured", "", "", http.StatusForbidden},
+		{"missing key", "s3cret", "", http.StatusUnauthorized},
+		{"wrong key", "s3cret", "guess", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setMockService(t, func(req *http.Request) (*http.Response, error) {
+				t.Errorf("Expected no upstream call, got %s", req.URL.Path)
+				return createHTTPResponse(200, `[]`), nil
+			})
+			config.APIKey = tt.apiKey
+
+			req := httptest.NewRequest("GET", "/watched", nil)
+			if tt.presented != "" {
+				req.Header.Set("X-API-Key", tt.presented)
+			}
+			rr := httptest.NewRecorder()
+			watchedHandler(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if strings.Contains(rr.Body.String(), "results") {
+				t.Errorf("Expected no repositories to be listed, got %s", rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// maxWebhookBodySize bounds the webhook payloads the service will read
+const maxWebhookBodySize = 1 << 20
+
+// WebhookPayload represents the parts of a Gitea commit status webhook the service uses
+type WebhookPayload struct {
+	SHA         string `json:"sha"`
+	Context     string `json:"context"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	TargetURL   string `json:"target_url"`
+	Branches    []struct {
+		Name string `json:"name"`
+	} `json:"branches"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+}
+
+// StatusEvent describes a status change pushed to event subscribers
+type StatusEvent struct {
+	Owner      string    `json:"owner"`
+	Repository string    `json:"repository"`
+	Branches   []string  `json:"branches,omitempty"`
+	SHA        string    `json:"sha"`
+	Context    string    `json:"context"`
+	State      string    `json:"state"`
+	Symbol     string    `json:"symbol"`
+	TargetURL  string    `json:"target_url,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// MarshalJSON encodes the event using the configured field naming style
+func (e StatusEvent) MarshalJSON() ([]byte, error) {
+	type plain StatusEvent
+	return marshalWithNaming(plain(e))
+}
+
+// validWebhookSignature checks the X-Gitea-Signature header, a hex
+// HMAC-SHA256 of the body keyed with the webhook secret
+func validWebhookSignature(body []byte, signature, secret string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// webhookHandler handles the /webhook endpoint receiving Gitea status events
+func webhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Unsigned webhooks could push fake events to subscribers, clear cached
+	// statuses and fill the history, so none are accepted without a secret
+	if config.WebhookSecret == "" {
+		writeError(w, http.StatusForbidden, "Set WEBHOOK_SECRET to accept webhooks")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+
+	if !validWebhookSignature(body, r.Header.Get("X-Gitea-Signature"), config.WebhookSecret) {
+		writeError(w, http.StatusUnauthorized, "Invalid webhook signature")
+		return
+	}
+
+	// Only commit status events carry build state; acknowledge anything else
+	if event := r.Header.Get("X-Gitea-Event"); event != "" && event != "status" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid webhook payload: %v", err))
+		return
+	}
+
+	owner, repo := payload.Repository.Owner.Login, payload.Repository.Name
+	if owner == "" || repo == "" {
+		writeError(w, http.StatusBadRequest, "Webhook payload is missing the repository")
+		return
+	}
+
+	event := StatusEvent{
+		Owner:      owner,
+		Repository: repo,
+		SHA:        payload.SHA,
+		Context:    payload.Context,
+		State:      payload.State,
+		Symbol:     mapStateToSymbol(payload.State),
+		TargetURL:  payload.TargetURL,
+		Time:       time.Now().UTC(),
+	}
+	for _, branch := range payload.Branches {
+		event.Branches = append(event.Branches, branch.Name)
+	}
+
+	// Cached statuses for the repository are now stale
+	cache.Delete(r.Context(), cacheKey(canonicalName(owner), canonicalName(repo), ""))
+
+	history.record(event)
+	delivered := broker.publish(event)
+	log.Printf("Webhook status event for %s/%s: %s (%d subscribers)", owner, repo, event.State, delivered)
+	w.WriteHeader(http.StatusNoContent)
+}